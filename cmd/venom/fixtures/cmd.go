@@ -0,0 +1,64 @@
+package fixtures
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/venom/executors/dbfixtures"
+)
+
+// Cmd fixtures
+var Cmd = &cobra.Command{
+	Use:   "fixtures",
+	Short: "Manage the database fixtures of the dbfixtures executor",
+}
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Write the rows of database tables in fixtures files: venom fixtures dump --driver postgres --dsn ... --tables a,b",
+	Long: `Write the rows of database tables in fixtures files, that can be loaded by the dbfixtures executor.
+
+Each table is written in a file named after the table, in the output directory.
+All the tables are written without --tables.
+
+Examples:
+  venom fixtures dump --driver postgres --dsn "user=venom password=venom dbname=venom host=localhost sslmode=disable" --tables employee,person
+  venom fixtures dump --driver mysql --dsn "venom:venom@(localhost:3306)/venom" --output-dir fixtures/staging`,
+	Args: cobra.NoArgs,
+	RunE: runDump,
+}
+
+var (
+	driver    string
+	dsn       string
+	tables    []string
+	outputDir string
+)
+
+func init() {
+	dumpCmd.Flags().StringVar(&driver, "driver", "", "Database driver: postgres, mysql or sqlite3")
+	dumpCmd.Flags().StringVar(&dsn, "dsn", "", "Data source name of the database")
+	dumpCmd.Flags().StringSliceVar(&tables, "tables", nil, "Tables to write, all the tables by default")
+	dumpCmd.Flags().StringVar(&outputDir, "output-dir", "fixtures", "Directory of the fixtures files")
+	_ = dumpCmd.MarkFlagRequired("driver")
+	_ = dumpCmd.MarkFlagRequired("dsn")
+	Cmd.AddCommand(dumpCmd)
+}
+
+func runDump(cmd *cobra.Command, args []string) error {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return fmt.Errorf("unable to connect to database: %w", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+	if err := dbfixtures.Dump(db, driver, outputDir, tables); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "fixtures written in %s\n", outputDir)
+	return nil
+}
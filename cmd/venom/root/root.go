@@ -3,6 +3,7 @@ package root
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/ovh/venom/cmd/venom/fixtures"
 	metricsreport "github.com/ovh/venom/cmd/venom/metrics-report"
 	"github.com/ovh/venom/cmd/venom/run"
 	"github.com/ovh/venom/cmd/venom/update"
@@ -25,4 +26,5 @@ func addCommands(cmd *cobra.Command) {
 	cmd.AddCommand(version.Cmd)
 	cmd.AddCommand(update.Cmd)
 	cmd.AddCommand(metricsreport.Cmd)
+	cmd.AddCommand(fixtures.Cmd)
 }
@@ -11,8 +11,14 @@ import (
 	"github.com/ovh/venom"
 )
 
-// Name of executor
-const Name = "amqp"
+const (
+	// Name of executor
+	Name = "amqp"
+	// ContextKey is the key of the background consumers in the testcase context
+	ContextKey = venom.ContextKey("amqpContext")
+
+	defaultTimeoutSeconds = 5
+)
 
 // New returns a new Executor
 func New() venom.Executor {
@@ -23,7 +29,7 @@ func New() venom.Executor {
 type Executor struct {
 	Addr string `json:"addr" yaml:"addr"`
 
-	// ClientType must be "consumer" or "producer"
+	// ClientType must be "consumer", "producer" or "collect"
 	ClientType string `json:"clientType" yaml:"clientType"`
 
 	// Used when ClientType is consumer
@@ -31,6 +37,11 @@ type Executor struct {
 	SourceAddr string `json:"sourceAddr" yaml:"sourceAddr"`
 	// MessageLimit represents the limit of message will be read. After limit, consumer will stop reading
 	MessageLimit uint `json:"messageLimit" yaml:"messageLimit"`
+	// Background is the name of a consumer running until the end of the testcase.
+	// Its messages are returned by the steps with the collect ClientType, once MessageLimit messages are received
+	Background string `json:"background" yaml:"background"`
+	// Timeout to collect the messages of a background consumer, in seconds. Default 5
+	Timeout int `json:"timeout" yaml:"timeout"`
 
 	// Used when ClientType is producer
 	// TargetAddr represents the target address to which outgoing messages should be published
@@ -57,6 +68,14 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 		return nil, err
 	}
 
+	switch {
+	case e.ClientType == "collect":
+		return e.collect(ctx)
+	case e.ClientType == "consumer" && e.Background != "":
+		// the session is closed at the end of the testcase
+		return nil, e.startBackground(ctx)
+	}
+
 	client, session, err := e.createAMQPSession(ctx)
 	if err != nil {
 		return nil, err
@@ -70,7 +89,7 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	case "consumer":
 		return e.consumeMessages(ctx, session)
 	default:
-		return nil, fmt.Errorf("clientType %q must be producer or consumer", e.ClientType)
+		return nil, fmt.Errorf("clientType %q must be producer, consumer or collect", e.ClientType)
 	}
 }
 
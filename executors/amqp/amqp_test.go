@@ -1,6 +1,7 @@
 package amqp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -282,7 +283,7 @@ func TestExecutor_ValidateConsumer(t *testing.T) {
 }
 
 func TestExecutor_ClientTypes(t *testing.T) {
-	validTypes := []string{"producer", "consumer"}
+	validTypes := []string{"producer", "consumer", "collect"}
 	invalidTypes := []string{"publisher", "subscriber", "invalid", ""}
 
 	for _, ct := range validTypes {
@@ -586,3 +587,18 @@ func TestExecutor_MultipleMessagesWithDifferentFormats(t *testing.T) {
 		}
 	}
 }
+
+func TestExecutor_Run_Collect(t *testing.T) {
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+	defer Executor{}.TearDown(ctx) //nolint
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"clientType": "collect"})
+	require.EqualError(t, err, "collecting messages: background is mandatory when clientType is collect")
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"clientType": "collect", "background": "events"})
+	require.EqualError(t, err, `collecting messages: background consumer "events" is not started`)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"clientType": "consumer", "background": "events", "addr": "amqp://localhost:5672"})
+	require.EqualError(t, err, "consuming messages: sourceAddr is manatory when clientType is consumer")
+}
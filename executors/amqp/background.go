@@ -0,0 +1,120 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+)
+
+// consumedMessage is a message buffered by a background consumer
+type consumedMessage struct {
+	msgString string
+	msgJSON   interface{}
+}
+
+// Setup prepares the store of the background consumers of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, background.NewConsumers[consumedMessage]()), nil
+}
+
+// TearDown stops the background consumers left running by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	if consumers := getConsumers(ctx); consumers != nil {
+		consumers.StopAll()
+	}
+	return nil
+}
+
+func getConsumers(ctx context.Context) *background.Consumers[consumedMessage] {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*background.Consumers[consumedMessage])
+}
+
+// startBackground starts a receiver running until the end of the testcase
+func (e Executor) startBackground(ctx context.Context) error {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return errors.New("consuming messages: background consumers are not available outside of a testcase")
+	}
+	if _, err := consumers.Get(e.Background); err == nil {
+		return fmt.Errorf("consuming messages: background consumer %q is already started", e.Background)
+	}
+	if e.SourceAddr == "" {
+		return errors.New("consuming messages: sourceAddr is manatory when clientType is consumer")
+	}
+
+	client, session, err := e.createAMQPSession(ctx)
+	if err != nil {
+		return err
+	}
+	recv, err := session.NewReceiver(ctx, e.SourceAddr, nil)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("consuming messages: %w", err)
+	}
+
+	// the receiver outlives the step
+	bctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopped := make(chan struct{})
+	consumer := background.NewConsumer[consumedMessage](func() {
+		cancel()
+		<-stopped
+		_ = session.Close(context.Background())
+		_ = client.Close()
+	})
+	go func() {
+		defer close(stopped)
+		for {
+			msgString, msgJSON, err := consumeMessage(bctx, recv)
+			if err != nil {
+				if bctx.Err() == nil {
+					consumer.Fail(err)
+				}
+				return
+			}
+			consumer.Push(consumedMessage{msgString: msgString, msgJSON: msgJSON})
+		}
+	}()
+	venom.Debug(ctx, "background consumer %q started on %s", e.Background, e.SourceAddr)
+	return consumers.Add(e.Background, consumer)
+}
+
+// collect returns the messages received by a background consumer, once MessageLimit messages are received
+func (e Executor) collect(ctx context.Context) (interface{}, error) {
+	if e.Background == "" {
+		return nil, errors.New("collecting messages: background is mandatory when clientType is collect")
+	}
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return nil, errors.New("collecting messages: background consumers are not available outside of a testcase")
+	}
+	consumer, err := consumers.Get(e.Background)
+	if err != nil {
+		return nil, fmt.Errorf("collecting messages: %w", err)
+	}
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeoutSeconds
+	}
+	consumed, err := consumer.Collect(ctx, int(e.MessageLimit), time.Duration(timeout)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("collecting messages: %w", err)
+	}
+
+	output := Result{
+		Messages:     make([]string, 0, len(consumed)),
+		MessagesJSON: make([]interface{}, 0, len(consumed)),
+	}
+	for _, c := range consumed {
+		output.Messages = append(output.Messages, c.msgString)
+		output.MessagesJSON = append(output.MessagesJSON, c.msgJSON)
+	}
+	return output, nil
+}
@@ -0,0 +1,125 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/mitchellh/mapstructure"
+)
+
+// getMultiAction represents a bulk get in couchbase
+type getMultiAction struct {
+	baseAction
+
+	IDs []string `json:"ids" yaml:"ids" mapstructure:"ids"`
+}
+
+// doGetMulti retrieves the documents in a single batch, like get without expiry
+func (e *Executor) doGetMulti(ctx context.Context,
+	rawAction any,
+	cluster *gocb.Cluster,
+) (any, error) {
+	var action getMultiAction
+
+	if err := mapstructure.Decode(rawAction, &action); err != nil {
+		return nil, fmt.Errorf("unable to decode getMulti action: %w", err)
+	}
+
+	collection, err := e.getCollection(ctx, cluster,
+		action.Bucket, action.Collection, action.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]gocb.BulkOp, len(action.IDs))
+	for i, id := range action.IDs {
+		ops[i] = &gocb.GetOp{ID: id}
+	}
+
+	if err := collection.Do(ops, &gocb.BulkOpOptions{Context: ctx}); err != nil {
+		return nil, err
+	}
+
+	results := map[string]map[string]any{}
+
+	for _, op := range ops {
+		getOp := op.(*gocb.GetOp)
+
+		var (
+			data  any
+			found = true
+		)
+
+		if errors.Is(getOp.Err, gocb.ErrDocumentNotFound) {
+			found = false
+		} else if getOp.Err != nil {
+			return nil, getOp.Err
+		} else if terr := getOp.Result.Content(&data); terr != nil {
+			return nil, fmt.Errorf("error while transcoding content of entry id=%q: %w", getOp.ID, terr)
+		}
+
+		results[getOp.ID] = map[string]any{
+			"found": found,
+			"data":  data,
+		}
+	}
+
+	return results, nil
+}
+
+// upsertMultiAction represents a bulk upsert in couchbase
+type upsertMultiAction struct {
+	baseAction
+
+	Expiry  *float64       `json:"expiry,omitempty" yaml:"expiry,omitempty" mapstructure:"expiry"`
+	Entries map[string]any `json:"entries"          yaml:"entries"          mapstructure:"entries"`
+}
+
+// doUpsertMulti upserts the entries in a single batch
+func (e *Executor) doUpsertMulti(ctx context.Context,
+	rawAction any,
+	cluster *gocb.Cluster,
+) (any, error) {
+	var action upsertMultiAction
+
+	if err := mapstructure.Decode(rawAction, &action); err != nil {
+		return nil, fmt.Errorf("unable to decode upsertMulti action: %w", err)
+	}
+
+	collection, err := e.getCollection(ctx, cluster,
+		action.Bucket, action.Collection, action.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry := e.getExpiry(action.Expiry)
+
+	ids := slices.Sorted(maps.Keys(action.Entries))
+	ops := make([]gocb.BulkOp, len(ids))
+	for i, id := range ids {
+		ops[i] = &gocb.UpsertOp{ID: id, Value: action.Entries[id], Expiry: expiry}
+	}
+
+	if err := collection.Do(ops, &gocb.BulkOpOptions{Context: ctx}); err != nil {
+		return nil, err
+	}
+
+	results := map[string]map[string]any{}
+
+	for _, op := range ops {
+		upsertOp := op.(*gocb.UpsertOp)
+		if upsertOp.Err != nil {
+			return nil, fmt.Errorf("unable to upsert entry id=%q: %w", upsertOp.ID, upsertOp.Err)
+		}
+
+		results[upsertOp.ID] = map[string]any{
+			"upserted": true,
+		}
+	}
+
+	return results, nil
+}
@@ -124,6 +124,36 @@ func (e *Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, e
 				return nil, err
 			}
 
+		case "getMulti":
+			results[index], err = e.doGetMulti(ctx, action, cluster)
+			if err != nil {
+				return nil, err
+			}
+
+		case "upsertMulti":
+			results[index], err = e.doUpsertMulti(ctx, action, cluster)
+			if err != nil {
+				return nil, err
+			}
+
+		case "lookupIn":
+			results[index], err = e.doLookupIn(ctx, action, cluster)
+			if err != nil {
+				return nil, err
+			}
+
+		case "mutateIn":
+			results[index], err = e.doMutateIn(ctx, action, cluster)
+			if err != nil {
+				return nil, err
+			}
+
+		case "query":
+			results[index], err = e.doQuery(ctx, action, cluster)
+			if err != nil {
+				return nil, err
+			}
+
 		default:
 			return nil, fmt.Errorf("action type %q not supported", actionType)
 		}
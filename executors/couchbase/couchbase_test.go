@@ -0,0 +1,66 @@
+package couchbase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanConsistency(t *testing.T) {
+	consistency, err := scanConsistency("")
+	require.NoError(t, err)
+	assert.Equal(t, gocb.QueryScanConsistencyNotBounded, consistency)
+
+	consistency, err = scanConsistency("request_plus")
+	require.NoError(t, err)
+	assert.Equal(t, gocb.QueryScanConsistencyRequestPlus, consistency)
+
+	_, err = scanConsistency("at_plus")
+	require.EqualError(t, err, `invalid scan consistency "at_plus" (valid values are [not_bounded request_plus])`)
+}
+
+func TestSubdocSpecs(t *testing.T) {
+	lookupIn, err := lookupInSpecs([]subdocSpec{
+		{Type: "get", Path: "name"},
+		{Type: "exists", Path: "country"},
+		{Type: "count", Path: "routes", Xattr: true},
+	})
+	require.NoError(t, err)
+	assert.Len(t, lookupIn, 3)
+
+	_, err = lookupInSpecs([]subdocSpec{{Type: "upsert", Path: "name"}})
+	require.EqualError(t, err, `invalid lookupIn spec type "upsert" (valid values are [get exists count])`)
+
+	mutateIn, err := mutateInSpecs([]subdocSpec{
+		{Type: "upsert", Path: "name", Value: "venom"},
+		{Type: "array_append", Path: "routes", Value: "CDG", CreatePath: true},
+		{Type: "increment", Path: "visits", Delta: 2},
+		{Type: "remove", Path: "iata"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, mutateIn, 4)
+
+	_, err = mutateInSpecs([]subdocSpec{{Type: "get", Path: "name"}})
+	require.Error(t, err)
+
+	semantic, err := storeSemantic("upsert")
+	require.NoError(t, err)
+	assert.Equal(t, gocb.StoreSemanticsUpsert, semantic)
+
+	_, err = storeSemantic("merge")
+	require.Error(t, err)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	metrics := queryMetrics(gocb.QueryMetrics{
+		ElapsedTime:   1500 * time.Millisecond,
+		ExecutionTime: time.Second,
+		ResultCount:   3,
+	})
+	assert.Equal(t, 1.5, metrics["elapsed_time"])
+	assert.Equal(t, 1.0, metrics["execution_time"])
+	assert.Equal(t, uint64(3), metrics["result_count"])
+}
@@ -0,0 +1,131 @@
+package couchbase
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/mitchellh/mapstructure"
+	"github.com/ovh/venom"
+)
+
+// queryAction represents a N1QL/SQL++ query in couchbase
+type queryAction struct {
+	baseAction
+
+	Statement       string         `json:"statement"                  yaml:"statement"                  mapstructure:"statement"`
+	Parameters      []any          `json:"parameters,omitempty"       yaml:"parameters,omitempty"       mapstructure:"parameters"`
+	NamedParameters map[string]any `json:"named_parameters,omitempty" yaml:"named_parameters,omitempty" mapstructure:"named_parameters"`
+	ScanConsistency string         `json:"scan_consistency,omitempty" yaml:"scan_consistency,omitempty" mapstructure:"scan_consistency"`
+	Readonly        bool           `json:"readonly,omitempty"         yaml:"readonly,omitempty"         mapstructure:"readonly"`
+}
+
+func scanConsistency(value string) (gocb.QueryScanConsistency, error) {
+	switch value {
+	case "", "not_bounded":
+		return gocb.QueryScanConsistencyNotBounded, nil
+	case "request_plus":
+		return gocb.QueryScanConsistencyRequestPlus, nil
+	default:
+		return 0, fmt.Errorf("invalid scan consistency %q (valid values are %v)",
+			value, []string{"not_bounded", "request_plus"})
+	}
+}
+
+// doQuery runs the statement on the cluster, or on the scope of the action or of the executor if any,
+// and returns the rows and the metrics of the query
+func (e *Executor) doQuery(ctx context.Context,
+	rawAction any,
+	cluster *gocb.Cluster,
+) (any, error) {
+	var action queryAction
+
+	if err := mapstructure.Decode(rawAction, &action); err != nil {
+		return nil, fmt.Errorf("unable to decode query action: %w", err)
+	}
+
+	if action.Statement == "" {
+		return nil, errors.New("unable to perform query operation: missing 'statement'")
+	}
+
+	consistency, err := scanConsistency(action.ScanConsistency)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &gocb.QueryOptions{
+		PositionalParameters: action.Parameters,
+		NamedParameters:      action.NamedParameters,
+		ScanConsistency:      consistency,
+		Readonly:             action.Readonly,
+		Metrics:              true,
+		Context:              ctx,
+	}
+
+	var rows *gocb.QueryResult
+	if scopeName := cmp.Or(action.Scope, e.Scope); scopeName != "" {
+		var bucket *gocb.Bucket
+
+		bucket, err = e.getBucket(ctx, cluster, action.Bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		scope := bucket.Scope(scopeName)
+
+		venom.Debug(ctx, "querying scope %q of bucket %q: %s", scope.Name(), bucket.Name(), action.Statement)
+
+		rows, err = scope.Query(action.Statement, opts)
+	} else {
+		venom.Debug(ctx, "querying cluster: %s", action.Statement)
+
+		rows, err = cluster.Query(action.Statement, opts)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform query %q: %w", action.Statement, err)
+	}
+
+	defer rows.Close()
+
+	results := []any{}
+
+	for rows.Next() {
+		var row any
+		if err := rows.Row(&row); err != nil {
+			return nil, fmt.Errorf("unable to decode query row: %w", err)
+		}
+
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to perform query %q: %w", action.Statement, err)
+	}
+
+	metadata, err := rows.MetaData()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read query metadata: %w", err)
+	}
+
+	return map[string]any{
+		"rows":    results,
+		"metrics": queryMetrics(metadata.Metrics),
+	}, nil
+}
+
+// queryMetrics returns the metrics of a query, durations in seconds
+func queryMetrics(metrics gocb.QueryMetrics) map[string]any {
+	return map[string]any{
+		"elapsed_time":   metrics.ElapsedTime.Seconds(),
+		"execution_time": metrics.ExecutionTime.Seconds(),
+		"result_count":   metrics.ResultCount,
+		"result_size":    metrics.ResultSize,
+		"mutation_count": metrics.MutationCount,
+		"sort_count":     metrics.SortCount,
+		"error_count":    metrics.ErrorCount,
+		"warning_count":  metrics.WarningCount,
+	}
+}
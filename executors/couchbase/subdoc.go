@@ -0,0 +1,269 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/mitchellh/mapstructure"
+)
+
+// subdocSpec represents an operation on a path of a document
+type subdocSpec struct {
+	Type       string `json:"type"                  yaml:"type"                  mapstructure:"type"`
+	Path       string `json:"path"                  yaml:"path"                  mapstructure:"path"`
+	Value      any    `json:"value,omitempty"       yaml:"value,omitempty"       mapstructure:"value"`
+	Delta      int64  `json:"delta,omitempty"       yaml:"delta,omitempty"       mapstructure:"delta"`
+	CreatePath bool   `json:"create_path,omitempty" yaml:"create_path,omitempty" mapstructure:"create_path"`
+	Xattr      bool   `json:"xattr,omitempty"       yaml:"xattr,omitempty"       mapstructure:"xattr"`
+}
+
+// lookupInAction represents a sub-document lookup in couchbase
+type lookupInAction struct {
+	baseAction
+
+	IDs   []string     `json:"ids"   yaml:"ids"   mapstructure:"ids"`
+	Specs []subdocSpec `json:"specs" yaml:"specs" mapstructure:"specs"`
+}
+
+func lookupInSpecs(specs []subdocSpec) ([]gocb.LookupInSpec, error) {
+	lookupInSpecs := make([]gocb.LookupInSpec, 0, len(specs))
+
+	for _, spec := range specs {
+		switch spec.Type {
+		case "get":
+			lookupInSpecs = append(lookupInSpecs, gocb.GetSpec(spec.Path, &gocb.GetSpecOptions{IsXattr: spec.Xattr}))
+		case "exists":
+			lookupInSpecs = append(lookupInSpecs, gocb.ExistsSpec(spec.Path, &gocb.ExistsSpecOptions{IsXattr: spec.Xattr}))
+		case "count":
+			lookupInSpecs = append(lookupInSpecs, gocb.CountSpec(spec.Path, &gocb.CountSpecOptions{IsXattr: spec.Xattr}))
+		default:
+			return nil, fmt.Errorf("invalid lookupIn spec type %q (valid values are %v)",
+				spec.Type, []string{"get", "exists", "count"})
+		}
+	}
+
+	return lookupInSpecs, nil
+}
+
+func (e *Executor) doLookupIn(ctx context.Context,
+	rawAction any,
+	cluster *gocb.Cluster,
+) (any, error) {
+	var action lookupInAction
+
+	if err := mapstructure.Decode(rawAction, &action); err != nil {
+		return nil, fmt.Errorf("unable to decode lookupIn action: %w", err)
+	}
+
+	specs, err := lookupInSpecs(action.Specs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(specs) == 0 {
+		return nil, errors.New("unable to perform lookupIn operation: missing 'specs'")
+	}
+
+	collection, err := e.getCollection(ctx, cluster,
+		action.Bucket, action.Collection, action.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]map[string]any{}
+
+	for _, id := range action.IDs {
+		docOut, err := collection.LookupIn(id, specs, &gocb.LookupInOptions{Context: ctx})
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			results[id] = map[string]any{
+				"found": false,
+			}
+
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		specResults := make([]map[string]any, len(action.Specs))
+		for i, spec := range action.Specs {
+			specResult := map[string]any{
+				"path":   spec.Path,
+				"exists": docOut.Exists(uint(i)),
+			}
+
+			if spec.Type != "exists" && docOut.Exists(uint(i)) {
+				var value any
+				if err := docOut.ContentAt(uint(i), &value); err != nil {
+					return nil, fmt.Errorf("error while decoding path %q of entry id=%q: %w", spec.Path, id, err)
+				}
+
+				specResult["value"] = value
+			}
+
+			specResults[i] = specResult
+		}
+
+		results[id] = map[string]any{
+			"found":   true,
+			"results": specResults,
+		}
+	}
+
+	return results, nil
+}
+
+// mutateInAction represents a sub-document mutation in couchbase
+type mutateInAction struct {
+	baseAction
+
+	StoreSemantic  string       `json:"store_semantic,omitempty"  yaml:"store_semantic,omitempty"  mapstructure:"store_semantic"`
+	PreserveExpiry bool         `json:"preserve_expiry,omitempty" yaml:"preserve_expiry,omitempty" mapstructure:"preserve_expiry"`
+	Expiry         *float64     `json:"expiry,omitempty"          yaml:"expiry,omitempty"          mapstructure:"expiry"`
+	IDs            []string     `json:"ids"                       yaml:"ids"                       mapstructure:"ids"`
+	Specs          []subdocSpec `json:"specs"                     yaml:"specs"                     mapstructure:"specs"`
+}
+
+func mutateInSpecs(specs []subdocSpec) ([]gocb.MutateInSpec, error) {
+	mutateInSpecs := make([]gocb.MutateInSpec, 0, len(specs))
+
+	for _, spec := range specs {
+		var mutateInSpec gocb.MutateInSpec
+
+		switch spec.Type {
+		case "insert":
+			mutateInSpec = gocb.InsertSpec(spec.Path, spec.Value,
+				&gocb.InsertSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "upsert":
+			mutateInSpec = gocb.UpsertSpec(spec.Path, spec.Value,
+				&gocb.UpsertSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "replace":
+			mutateInSpec = gocb.ReplaceSpec(spec.Path, spec.Value,
+				&gocb.ReplaceSpecOptions{IsXattr: spec.Xattr})
+		case "remove":
+			mutateInSpec = gocb.RemoveSpec(spec.Path,
+				&gocb.RemoveSpecOptions{IsXattr: spec.Xattr})
+		case "array_append":
+			mutateInSpec = gocb.ArrayAppendSpec(spec.Path, spec.Value,
+				&gocb.ArrayAppendSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "array_prepend":
+			mutateInSpec = gocb.ArrayPrependSpec(spec.Path, spec.Value,
+				&gocb.ArrayPrependSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "array_add_unique":
+			mutateInSpec = gocb.ArrayAddUniqueSpec(spec.Path, spec.Value,
+				&gocb.ArrayAddUniqueSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "increment":
+			mutateInSpec = gocb.IncrementSpec(spec.Path, spec.Delta,
+				&gocb.CounterSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		case "decrement":
+			mutateInSpec = gocb.DecrementSpec(spec.Path, spec.Delta,
+				&gocb.CounterSpecOptions{CreatePath: spec.CreatePath, IsXattr: spec.Xattr})
+		default:
+			return nil, fmt.Errorf("invalid mutateIn spec type %q (valid values are %v)",
+				spec.Type, []string{"insert", "upsert", "replace", "remove", "array_append",
+					"array_prepend", "array_add_unique", "increment", "decrement"})
+		}
+
+		mutateInSpecs = append(mutateInSpecs, mutateInSpec)
+	}
+
+	return mutateInSpecs, nil
+}
+
+func storeSemantic(value string) (gocb.StoreSemantics, error) {
+	switch value {
+	case "", "replace":
+		return gocb.StoreSemanticsReplace, nil
+	case "upsert":
+		return gocb.StoreSemanticsUpsert, nil
+	case "insert":
+		return gocb.StoreSemanticsInsert, nil
+	default:
+		return 0, fmt.Errorf("invalid store semantic %q (valid values are %v)",
+			value, []string{"replace", "upsert", "insert"})
+	}
+}
+
+func (e *Executor) doMutateIn(ctx context.Context,
+	rawAction any,
+	cluster *gocb.Cluster,
+) (any, error) {
+	var action mutateInAction
+
+	if err := mapstructure.Decode(rawAction, &action); err != nil {
+		return nil, fmt.Errorf("unable to decode mutateIn action: %w", err)
+	}
+
+	specs, err := mutateInSpecs(action.Specs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(specs) == 0 {
+		return nil, errors.New("unable to perform mutateIn operation: missing 'specs'")
+	}
+
+	semantic, err := storeSemantic(action.StoreSemantic)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := e.getCollection(ctx, cluster,
+		action.Bucket, action.Collection, action.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &gocb.MutateInOptions{
+		StoreSemantic:  semantic,
+		PreserveExpiry: action.PreserveExpiry,
+		Context:        ctx,
+	}
+
+	if expiry, ok := e.tryGetExpiry(action.Expiry); ok {
+		opts.Expiry = expiry
+		opts.PreserveExpiry = false
+	}
+
+	results := map[string]map[string]any{}
+
+	for _, id := range action.IDs {
+		docOut, err := collection.MutateIn(id, specs, opts)
+		if errors.Is(err, gocb.ErrDocumentNotFound) || errors.Is(err, gocb.ErrDocumentExists) {
+			results[id] = map[string]any{
+				"mutated": false,
+			}
+
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// only the counters return a value
+		specResults := make([]map[string]any, len(action.Specs))
+		for i, spec := range action.Specs {
+			specResult := map[string]any{
+				"path": spec.Path,
+			}
+
+			if spec.Type == "increment" || spec.Type == "decrement" {
+				var value int64
+				if err := docOut.ContentAt(uint(i), &value); err != nil {
+					return nil, fmt.Errorf("error while decoding path %q of entry id=%q: %w", spec.Path, id, err)
+				}
+
+				specResult["value"] = value
+			}
+
+			specResults[i] = specResult
+		}
+
+		results[id] = map[string]any{
+			"mutated": true,
+			"results": specResults,
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,262 @@
+package cql
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/tlsconfig"
+)
+
+// Name of the executor.
+const Name = "cql"
+
+const defaultTimeout = 10
+
+// New returns a new executor that can execute CQL statements
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor is a venom executor that executes CQL statements on Cassandra or ScyllaDB
+type Executor struct {
+	// Hosts of the cluster, with an optional port. Default localhost
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Port of the hosts without port. Default 9042
+	Port     int    `json:"port,omitempty" yaml:"port,omitempty"`
+	Keyspace string `json:"keyspace,omitempty" yaml:"keyspace,omitempty"`
+	// Consistency of the statements, e.g. one, quorum or local_quorum. Default quorum
+	Consistency string `json:"consistency,omitempty" yaml:"consistency,omitempty"`
+	// ProtoVersion is the version of the native protocol, discovered by default
+	ProtoVersion int    `json:"proto_version,omitempty" yaml:"proto_version,omitempty" mapstructure:"proto_version"`
+	Username     string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password     string `json:"password,omitempty" yaml:"password,omitempty"`
+
+	// TLS options. The certificates and key are PEM contents, or paths to PEM files
+	TLS             bool   `json:"tls,omitempty" yaml:"tls,omitempty"`
+	TLSClientCert   string `json:"tls_client_cert,omitempty" yaml:"tls_client_cert,omitempty" mapstructure:"tls_client_cert"`
+	TLSClientKey    string `json:"tls_client_key,omitempty" yaml:"tls_client_key,omitempty" mapstructure:"tls_client_key"`
+	TLSRootCA       string `json:"tls_root_ca,omitempty" yaml:"tls_root_ca,omitempty" mapstructure:"tls_root_ca"`
+	IgnoreVerifySSL bool   `json:"ignore_verify_ssl,omitempty" yaml:"ignore_verify_ssl,omitempty" mapstructure:"ignore_verify_ssl"`
+
+	// Timeout of the connection and of the statements, in seconds. Default 10
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	Commands []Command `json:"commands,omitempty" yaml:"commands,omitempty"`
+	// File is a .cql file, its statements separated by semicolons are executed in order
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+// Command is a CQL statement with its bind parameters. A command can be set as a string, without parameters
+type Command struct {
+	Query string        `json:"query" yaml:"query"`
+	Args  []interface{} `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+// Rows represents an array of Row
+type Rows []Row
+
+// Row represents a row returned by a CQL statement.
+type Row map[string]interface{}
+
+// Column represents a column of the rows returned by a CQL statement
+type Column struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is the CQL type of the column, e.g. text or list<int>
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// QueryResult represents the rows returned by a CQL statement.
+type QueryResult struct {
+	Rows    Rows     `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Columns []Column `json:"columns,omitempty" yaml:"columns,omitempty"`
+}
+
+// Result represents a step result.
+type Result struct {
+	Queries []QueryResult `json:"queries,omitempty" yaml:"queries,omitempty"`
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// Run implements the venom.Executor interface for Executor.
+func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	if err := decodeStep(step, &e); err != nil {
+		return nil, err
+	}
+
+	var commands []Command
+	if len(e.Commands) != 0 {
+		commands = e.Commands
+	} else if e.File != "" {
+		file := path.Join(venom.StringVarFromCtx(ctx, "venom.testsuite.workdir"), e.File)
+		venom.Debug(ctx, "loading CQL file from %s\n", file)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, statement := range splitStatements(string(content)) {
+			commands = append(commands, Command{Query: statement})
+		}
+	} else {
+		return nil, errors.New("commands or file is mandatory")
+	}
+
+	session, err := e.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	results := []QueryResult{}
+	for i, c := range commands {
+		venom.Debug(ctx, "Executing command number %d\n", i)
+		r, err := run(ctx, session, c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to exec command number %d", i)
+		}
+		results = append(results, r)
+	}
+	return Result{Queries: results}, nil
+}
+
+// session connects to the cluster
+func (e Executor) session(ctx context.Context) (*gocql.Session, error) {
+	hosts := e.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+	cluster := gocql.NewCluster(hosts...)
+	if e.Port != 0 {
+		cluster.Port = e.Port
+	}
+	cluster.Keyspace = e.Keyspace
+	cluster.ProtoVersion = e.ProtoVersion
+	cluster.Logger = logger{ctx: ctx}
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	cluster.Timeout = time.Duration(timeout) * time.Second
+	cluster.ConnectTimeout = cluster.Timeout
+
+	if e.Consistency != "" {
+		consistency, err := gocql.ParseConsistencyWrapper(e.Consistency)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Consistency = consistency
+	}
+	if e.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{Username: e.Username, Password: e.Password}
+	}
+
+	tlsConfig, err := e.tlsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		cluster.SslOpts = &gocql.SslOptions{Config: tlsConfig, EnableHostVerification: !e.IgnoreVerifySSL}
+	}
+
+	venom.Debug(ctx, "connecting to cluster %v, keyspace %q\n", hosts, e.Keyspace)
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to cluster")
+	}
+	return session, nil
+}
+
+// run executes the statement with its bind parameters, and returns its rows if any
+func run(ctx context.Context, session *gocql.Session, c Command) (QueryResult, error) {
+	args := make([]interface{}, len(c.Args))
+	for i := range c.Args {
+		args[i] = bindValue{value: c.Args[i]}
+	}
+	iter := session.Query(c.Query, args...).WithContext(ctx).Iter()
+
+	infos := iter.Columns()
+	columns := make([]Column, len(infos))
+	for i, info := range infos {
+		columns[i] = Column{Name: info.Name, Type: typeName(info.TypeInfo)}
+	}
+
+	rows := Rows{}
+	for {
+		row := map[string]interface{}{}
+		if !iter.MapScan(row) {
+			break
+		}
+		for name, v := range row {
+			row[name] = normalizeValue(v)
+		}
+		rows = append(rows, row)
+	}
+	if err := iter.Close(); err != nil {
+		return QueryResult{}, err
+	}
+	if len(columns) == 0 {
+		return QueryResult{}, nil
+	}
+	return QueryResult{Rows: rows, Columns: columns}, nil
+}
+
+// tlsConfig returns the TLS configuration of the step, or nil without TLS options
+func (e Executor) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	config, err := tlsconfig.New(ctx, tlsconfig.Options{
+		RootCA:          e.TLSRootCA,
+		ClientCert:      e.TLSClientCert,
+		ClientKey:       e.TLSClientKey,
+		IgnoreVerifySSL: e.IgnoreVerifySSL,
+	})
+	if config == nil && err == nil && e.TLS {
+		config = &tls.Config{}
+	}
+	return config, err
+}
+
+// decodeStep decodes the step, the commands can be set as strings
+func decodeStep(step venom.TestStep, e *Executor) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to == reflect.TypeOf(Command{}) {
+				return Command{Query: data.(string)}, nil
+			}
+			return data, nil
+		},
+		Result: e,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(step)
+}
+
+// logger writes the logs of the driver in the debug logs of the step
+type logger struct {
+	ctx context.Context
+}
+
+func (l logger) Print(v ...interface{}) {
+	venom.Debug(l.ctx, "%s", fmt.Sprint(v...))
+}
+
+func (l logger) Printf(format string, v ...interface{}) {
+	venom.Debug(l.ctx, format, v...)
+}
+
+func (l logger) Println(v ...interface{}) {
+	venom.Debug(l.ctx, "%s", fmt.Sprint(v...))
+}
@@ -0,0 +1,137 @@
+package cql
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/inf.v0"
+
+	"github.com/ovh/venom"
+)
+
+func native(typ gocql.Type) gocql.NativeType {
+	return gocql.NewNativeType(4, typ, "")
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`
+-- the keyspace
+CREATE KEYSPACE IF NOT EXISTS venom WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1};
+
+/* the table; with a comment */
+CREATE TABLE IF NOT EXISTS venom.users (id int PRIMARY KEY, name text);
+INSERT INTO venom.users (id, name) VALUES (1, 'it''s; a name'); // trailing comment
+INSERT INTO "venom"."users" (id, name) VALUES (2, $$a;b$$)
+;;
+`)
+	require.Len(t, statements, 4)
+	assert.Equal(t, "CREATE KEYSPACE IF NOT EXISTS venom WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}", statements[0])
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS venom.users (id int PRIMARY KEY, name text)", statements[1])
+	assert.Equal(t, "INSERT INTO venom.users (id, name) VALUES (1, 'it''s; a name')", statements[2])
+	assert.Equal(t, `INSERT INTO "venom"."users" (id, name) VALUES (2, $$a;b$$)`, statements[3])
+
+	statements = splitStatements(`
+begin unlogged batch
+  INSERT INTO venom.users (id, name) VALUES (3, 'apply batch;');
+  INSERT INTO venom.users (id, name) VALUES (4, 'd'); -- in the batch
+APPLY BATCH;
+DELETE FROM venom.users WHERE id = 1;
+`)
+	require.Len(t, statements, 2)
+	assert.Equal(t, "begin unlogged batch\n  INSERT INTO venom.users (id, name) VALUES (3, 'apply batch;');\n"+
+		"  INSERT INTO venom.users (id, name) VALUES (4, 'd'); \nAPPLY BATCH", statements[0])
+	assert.Equal(t, "DELETE FROM venom.users WHERE id = 1", statements[1])
+}
+
+func TestBindValue(t *testing.T) {
+	tests := []struct {
+		info     gocql.TypeInfo
+		value    interface{}
+		target   interface{}
+		expected interface{}
+	}{
+		{info: native(gocql.TypeInt), value: float64(42), target: new(int), expected: 42},
+		{info: native(gocql.TypeBigInt), value: float64(1 << 40), target: new(int64), expected: int64(1 << 40)},
+		{info: native(gocql.TypeFloat), value: 1.5, target: new(float32), expected: float32(1.5)},
+		{info: native(gocql.TypeDecimal), value: "12.50", target: new(inf.Dec), expected: *inf.NewDec(1250, 2)},
+		{info: native(gocql.TypeTimestamp), value: "2024-05-01T10:30:00Z", target: new(time.Time), expected: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{info: native(gocql.TypeBlob), value: "bytes", target: new([]byte), expected: []byte("bytes")},
+		{info: native(gocql.TypeVarchar), value: "text", target: new(string), expected: "text"},
+		{
+			info:     gocql.CollectionType{NativeType: native(gocql.TypeList), Elem: native(gocql.TypeInt)},
+			value:    []interface{}{float64(1), float64(2)},
+			target:   new([]int),
+			expected: []int{1, 2},
+		},
+		{
+			info:     gocql.CollectionType{NativeType: native(gocql.TypeMap), Key: native(gocql.TypeInt), Elem: native(gocql.TypeVarchar)},
+			value:    map[string]interface{}{"1": "one"},
+			target:   new(map[int]string),
+			expected: map[int]string{1: "one"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(typeName(tt.info), func(t *testing.T) {
+			data, err := gocql.Marshal(tt.info, bindValue{value: tt.value})
+			require.NoError(t, err)
+			require.NoError(t, gocql.Unmarshal(tt.info, data, tt.target))
+			assert.Equal(t, tt.expected, reflect.ValueOf(tt.target).Elem().Interface())
+		})
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	big := new(big.Int).Lsh(big.NewInt(1), 70)
+	assert.Equal(t, int64(3), normalizeValue(int32(3)))
+	assert.Equal(t, int64(3), normalizeValue(int8(3)))
+	assert.Equal(t, float64(1.5), normalizeValue(float32(1.5)))
+	assert.Equal(t, "1180591620717411303424", normalizeValue(big))
+	assert.Equal(t, int64(12), normalizeValue(inf.NewDec(12, 0)))
+	assert.Equal(t, "12.50", normalizeValue(inf.NewDec(1250, 2)))
+	assert.Equal(t, "1180591620717411303424", normalizeValue(new(inf.Dec).SetUnscaledBig(big)))
+	assert.Equal(t, "10.0.0.1", normalizeValue(net.ParseIP("10.0.0.1")))
+	assert.Equal(t, "AP8=", normalizeValue([]byte{0x00, 0xff}))
+	assert.Nil(t, normalizeValue(time.Time{}))
+	assert.Equal(t, map[string]interface{}{"1": []interface{}{"a"}}, normalizeValue(map[int][]string{1: {"a"}}))
+}
+
+func TestTypeName(t *testing.T) {
+	assert.Equal(t, "int", typeName(native(gocql.TypeInt)))
+	assert.Equal(t, "list<int>", typeName(gocql.CollectionType{NativeType: native(gocql.TypeList), Elem: native(gocql.TypeInt)}))
+	assert.Equal(t, "map<varchar, set<uuid>>", typeName(gocql.CollectionType{
+		NativeType: native(gocql.TypeMap),
+		Key:        native(gocql.TypeVarchar),
+		Elem:       gocql.CollectionType{NativeType: native(gocql.TypeSet), Elem: native(gocql.TypeUUID)},
+	}))
+}
+
+func TestExecutor_Run_Mandatory(t *testing.T) {
+	venom.InitTestLogger(t)
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"hosts": []interface{}{"localhost"}})
+	assert.EqualError(t, err, "commands or file is mandatory")
+}
+
+func TestDecodeStep(t *testing.T) {
+	var e Executor
+	require.NoError(t, decodeStep(venom.TestStep{
+		"keyspace":      "venom",
+		"proto_version": 4,
+		"commands": []interface{}{
+			"SELECT * FROM users",
+			map[string]interface{}{"query": "SELECT * FROM users WHERE id = ?", "args": []interface{}{1}},
+		},
+	}, &e))
+	assert.Equal(t, "venom", e.Keyspace)
+	assert.Equal(t, 4, e.ProtoVersion)
+	assert.Equal(t, []Command{
+		{Query: "SELECT * FROM users"},
+		{Query: "SELECT * FROM users WHERE id = ?", Args: []interface{}{1}},
+	}, e.Commands)
+}
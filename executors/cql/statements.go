@@ -0,0 +1,84 @@
+package cql
+
+import "strings"
+
+// splitStatements splits the content of a CQL file into statements, on the semicolons
+// which are not in a string, a quoted identifier, a comment or a BEGIN ... APPLY BATCH statement
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(content) {
+				if content[end] == c {
+					// a doubled quote is an escaped quote
+					if end+1 < len(content) && content[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(content) {
+				end = len(content) - 1
+			}
+			current.WriteString(content[i : end+1])
+			i = end
+		case c == '$' && strings.HasPrefix(content[i:], "$$"):
+			end := strings.Index(content[i+2:], "$$")
+			if end < 0 {
+				current.WriteString(content[i:])
+				i = len(content)
+				continue
+			}
+			current.WriteString(content[i : i+2+end+2])
+			i += 2 + end + 1
+		case strings.HasPrefix(content[i:], "--") || strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				i = len(content)
+				continue
+			}
+			i += end
+			current.WriteByte('\n')
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+				continue
+			}
+			i += 2 + end + 1
+			current.WriteByte(' ')
+		case c == ';' && inBatch(current.String()):
+			current.WriteByte(c)
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// inBatch reports whether the statement is a BEGIN [UNLOGGED | COUNTER] BATCH statement not ended by APPLY BATCH yet
+func inBatch(statement string) bool {
+	words := strings.Fields(strings.ToUpper(statement))
+	if len(words) < 2 || words[0] != "BEGIN" || (words[1] != "BATCH" && (len(words) < 3 || words[2] != "BATCH")) {
+		return false
+	}
+	n := len(words)
+	return words[n-2] != "APPLY" || words[n-1] != "BATCH"
+}
@@ -0,0 +1,188 @@
+package cql
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+// bindValue is a bind parameter of a step, converted to the type of its column when it is marshaled:
+// the YAML numbers are integers for the integer columns, and the strings are parsed for the timestamp and decimal columns
+type bindValue struct {
+	value interface{}
+}
+
+// MarshalCQL implements gocql.Marshaler
+func (b bindValue) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	v, err := convertValue(info, b.value)
+	if err != nil {
+		return nil, err
+	}
+	return gocql.Marshal(info, v)
+}
+
+func convertValue(info gocql.TypeInfo, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch info.Type() {
+	case gocql.TypeTinyInt, gocql.TypeSmallInt, gocql.TypeInt, gocql.TypeBigInt, gocql.TypeCounter, gocql.TypeVarint:
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+	case gocql.TypeFloat:
+		if f, ok := v.(float64); ok {
+			return float32(f), nil
+		}
+	case gocql.TypeDecimal:
+		s := fmt.Sprint(v)
+		if f, ok := v.(float64); ok {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		dec, ok := new(inf.Dec).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("can not parse decimal %q", s)
+		}
+		return dec, nil
+	case gocql.TypeTimestamp:
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse timestamp %q: %w", s, err)
+			}
+			return t, nil
+		}
+	case gocql.TypeBlob:
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	case gocql.TypeList, gocql.TypeSet:
+		if items, ok := v.([]interface{}); ok {
+			// the elements are converted to the element type when gocql marshals them
+			converted := make([]interface{}, len(items))
+			for i, item := range items {
+				converted[i] = bindValue{value: item}
+			}
+			return converted, nil
+		}
+	case gocql.TypeMap:
+		if m, ok := v.(map[string]interface{}); ok {
+			key := info.(gocql.CollectionType).Key
+			converted := make(map[interface{}]interface{}, len(m))
+			for k, item := range m {
+				// the keys of a YAML map are strings
+				var ck interface{} = k
+				if f, err := strconv.ParseFloat(k, 64); err == nil && key.Type() != gocql.TypeVarchar && key.Type() != gocql.TypeText && key.Type() != gocql.TypeAscii {
+					ck = f
+				}
+				kv, err := convertValue(key, ck)
+				if err != nil {
+					return nil, err
+				}
+				converted[kv] = bindValue{value: item}
+			}
+			return converted, nil
+		}
+	}
+	return v, nil
+}
+
+// normalizeValue returns the value of a column as a JSON-friendly type, like the sql executor: the numbers are int64 or float64, the decimals that are not integers are strings,
+// the timestamps are RFC 3339 strings, the uuids and inets are strings, and the blobs are strings, base64 encoded if they are not valid UTF-8
+func normalizeValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, bool, int64, float64, string:
+		return v
+	case []byte:
+		if !utf8.Valid(value) {
+			return base64.StdEncoding.EncodeToString(value)
+		}
+		return string(value)
+	case time.Time:
+		if value.IsZero() {
+			return nil
+		}
+		return value.Format(time.RFC3339Nano)
+	case gocql.UUID:
+		return value.String()
+	case net.IP:
+		return value.String()
+	case *inf.Dec:
+		if value == nil {
+			return nil
+		}
+		// a float64 would round the decimals, they are integers only when they fit exactly
+		s := value.String()
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		return s
+	case *big.Int:
+		if value == nil {
+			return nil
+		}
+		if value.IsInt64() {
+			return value.Int64()
+		}
+		return value.String()
+	case time.Duration:
+		return value.String()
+	case gocql.Duration:
+		return fmt.Sprintf("%dmo%dd%dns", value.Months, value.Days, value.Nanoseconds)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= 1<<63-1 {
+			return int64(u)
+		}
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(normalizeValue(iter.Key().Interface()))] = normalizeValue(iter.Value().Interface())
+		}
+		return m
+	}
+	return v
+}
+
+// typeName returns the CQL name of a type, e.g. list<int>
+func typeName(info gocql.TypeInfo) string {
+	switch t := info.(type) {
+	case gocql.CollectionType:
+		if t.Type() == gocql.TypeMap {
+			return fmt.Sprintf("map<%s, %s>", typeName(t.Key), typeName(t.Elem))
+		}
+		return fmt.Sprintf("%s<%s>", t.Type(), typeName(t.Elem))
+	case gocql.NativeType:
+		if t.Type() == gocql.TypeCustom {
+			return t.Custom()
+		}
+		return t.Type().String()
+	}
+	return fmt.Sprint(info)
+}
@@ -18,6 +18,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/dbpool"
 )
 
 // Name of the executor.
@@ -40,6 +41,11 @@ type Executor struct {
 	Migrations         string   `json:"migrations" yaml:"migrations"`
 	MigrationsTable    string   `json:"migrationsTable" yaml:"migrationsTable"`
 	SkipResetSequences bool     `json:"skipResetSequences" yaml:"skipResetSequences"`
+	// Connection is the name of a connection declared in the sql.connections variable, used instead of Database and DSN
+	Connection string `json:"connection,omitempty" yaml:"connection,omitempty"`
+	// Action is load, the default, or dump to write the rows of the Tables in fixtures files of the Folder
+	Action string   `json:"action,omitempty" yaml:"action,omitempty"`
+	Tables []string `json:"tables,omitempty" yaml:"tables,omitempty"`
 }
 
 // Result represents a step result.
@@ -53,21 +59,51 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, er
 	if err := mapstructure.Decode(step, &e); err != nil {
 		return nil, err
 	}
-	// Connect to the database and ping it.
-	venom.Debug(ctx, "connecting to database %s, %s\n", e.Database, e.DSN)
-
-	db, err := sql.Open(e.Database, e.DSN)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to connect to database")
+	switch e.Action {
+	case "", "load":
+	case "dump":
+		if e.Folder == "" {
+			return nil, fmt.Errorf("folder is mandatory to dump fixtures")
+		}
+	default:
+		return nil, fmt.Errorf("action %q must be load or dump", e.Action)
 	}
-	defer db.Close()
+	var db *sql.DB
+	if e.Connection != "" {
+		pool, connection, err := dbpool.Get(ctx, e.Connection)
+		if err != nil {
+			return nil, err
+		}
+		defer dbpool.LogStats(ctx, e.Connection, pool)
+		db = pool.DB
+		e.Database = connection.Driver
+	} else {
+		// Connect to the database and ping it.
+		venom.Debug(ctx, "connecting to database %s, %s\n", e.Database, e.DSN)
+
+		var err error
+		db, err = sql.Open(e.Database, e.DSN)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to database")
+		}
+		defer db.Close()
 
-	if err = db.Ping(); err != nil {
-		return nil, errors.Wrapf(err, "failed to ping database")
+		if err = db.Ping(); err != nil {
+			return nil, errors.Wrapf(err, "failed to ping database")
+		}
 	}
 
 	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
 
+	if e.Action == "dump" {
+		folder := path.Join(workdir, e.Folder)
+		venom.Debug(ctx, "dumping fixtures in folder %s\n", folder)
+		if err := Dump(db, e.Database, folder, e.Tables); err != nil {
+			return nil, err
+		}
+		return Result{Executor: e}, nil
+	}
+
 	// Load and import the schemas in the database
 	// if the argument is specified.
 	if len(e.Schemas) != 0 {
@@ -78,7 +114,7 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, er
 			if errs != nil {
 				return nil, errs
 			}
-			if _, err = db.Exec(string(sbytes)); err != nil {
+			if _, err := db.Exec(string(sbytes)); err != nil {
 				return nil, errors.Wrapf(err, "failed to exec schema from file %q", s)
 			}
 		}
@@ -101,7 +137,7 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, er
 	}
 
 	// Load fixtures in the databases.
-	if err = loadFixtures(ctx, db, e.Files, e.Folder, getDialect(e.Database, e.SkipResetSequences), workdir); err != nil {
+	if err := loadFixtures(ctx, db, e.Files, e.Folder, getDialect(e.Database, e.SkipResetSequences), workdir); err != nil {
 		return nil, err
 	}
 	r := Result{Executor: e}
@@ -109,6 +145,17 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, er
 	return r, nil
 }
 
+// Setup reads the connections declared in the sql.connections variable of the testsuite
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return dbpool.Setup(ctx, vars)
+}
+
+// TearDown logs the statistics of the connections used by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	dbpool.TearDown(ctx)
+	return nil
+}
+
 // ZeroValueResult return an empty implementation of this executor result
 func (Executor) ZeroValueResult() interface{} {
 	return Result{}
@@ -181,7 +228,7 @@ func getDialect(name string, skipResetSequences bool) func(*fixtures.Loader) err
 		}
 	case "mysql":
 		return fixtures.Dialect("mysql")
-	case "sqlite3":
+	case "sqlite3", "sqlite":
 		return fixtures.Dialect("sqlite3")
 	}
 	return nil
@@ -0,0 +1,33 @@
+package dbfixtures
+
+import (
+	"database/sql"
+	"os"
+
+	fixtures "github.com/go-testfixtures/testfixtures/v3"
+	"github.com/pkg/errors"
+)
+
+// Dump writes the rows of the tables in fixtures files of the folder, that can be loaded by the executor.
+// Each table is written in a file named after the table, all the tables are written without tables.
+func Dump(db *sql.DB, driver, folder string, tables []string) error {
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create folder %q", folder)
+	}
+	options := []func(*fixtures.Dumper) error{
+		fixtures.DumpDatabase(db),
+		fixtures.DumpDialect(driver),
+		fixtures.DumpDirectory(folder),
+	}
+	if len(tables) > 0 {
+		options = append(options, fixtures.DumpTables(tables...))
+	}
+	dumper, err := fixtures.NewDumper(options...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dumper")
+	}
+	if err := dumper.Dump(); err != nil {
+		return errors.Wrapf(err, "failed to dump fixtures in folder %q", folder)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package dbfixtures
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestDump(t *testing.T) {
+	venom.InitTestLogger(t)
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "venom.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE employee (id INTEGER PRIMARY KEY, name TEXT, age INTEGER);
+		CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO employee VALUES (1, 'Jack', 21), (2, 'Jill', NULL);
+		INSERT INTO person VALUES (1, 'Joe');`)
+	require.NoError(t, err)
+
+	folder := filepath.Join(dir, "fixtures")
+	require.NoError(t, Dump(db, "sqlite3", folder, []string{"employee"}))
+	entries, err := os.ReadDir(folder)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "employee.yml", entries[0].Name())
+
+	// the fixtures files are loaded back by the executor
+	_, err = db.Exec("DELETE FROM employee")
+	require.NoError(t, err)
+	require.NoError(t, loadFixtures(context.Background(), db, nil, "fixtures", getDialect("sqlite3", false), dir))
+	var name string
+	var age sql.NullInt64
+	require.NoError(t, db.QueryRow("SELECT name, age FROM employee WHERE id = 2").Scan(&name, &age))
+	assert.Equal(t, "Jill", name)
+	assert.False(t, age.Valid)
+
+	// all the tables are written without tables
+	require.NoError(t, Dump(db, "sqlite3", filepath.Join(dir, "all"), nil))
+	assert.FileExists(t, filepath.Join(dir, "all", "employee.yml"))
+	assert.FileExists(t, filepath.Join(dir, "all", "person.yml"))
+}
+
+func TestExecutor_Run_Dump(t *testing.T) {
+	venom.InitTestLogger(t)
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "venom.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE employee (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO employee VALUES (1, 'Jack');")
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), dir)
+	_, err = Executor{}.Run(ctx, venom.TestStep{"database": "sqlite3", "dsn": dsn, "action": "dump", "folder": "fixtures", "tables": []string{"employee"}})
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dir, "fixtures", "employee.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "- id: 1\n  name: Jack\n", string(content))
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"database": "sqlite3", "dsn": dsn, "action": "dump"})
+	require.EqualError(t, err, "folder is mandatory to dump fixtures")
+	_, err = Executor{}.Run(ctx, venom.TestStep{"database": "sqlite3", "dsn": dsn, "action": "restore"})
+	require.EqualError(t, err, `action "restore" must be load or dump`)
+}
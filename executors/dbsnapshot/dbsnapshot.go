@@ -0,0 +1,198 @@
+package dbsnapshot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// SQL drivers, the same as the sql executor.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/sijms/go-ora"
+	_ "modernc.org/sqlite"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/dbpool"
+)
+
+// Name of the executor.
+const Name = "dbsnapshot"
+
+// ContextKey is the key of the snapshots taken by the testcase
+const ContextKey = venom.ContextKey("dbsnapshots")
+
+// defaultSnapshot is the name of the snapshots taken without name
+const defaultSnapshot = "default"
+
+// New returns a new executor that can take snapshots of database tables and compare them
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor is a venom executor that takes snapshots of database tables,
+// and returns the rows inserted, updated and deleted since a snapshot
+type Executor struct {
+	Driver string `json:"driver" yaml:"driver"`
+	DSN    string `json:"dsn" yaml:"dsn"`
+	// Connection is the name of a connection declared in the sql.connections variable, used instead of Driver and DSN
+	Connection string `json:"connection,omitempty" yaml:"connection,omitempty"`
+	// Action is snapshot or diff
+	Action string `json:"action" yaml:"action"`
+	// Name of the snapshot, to take several snapshots in a testcase
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Tables to capture, the diff compares the tables of the snapshot by default
+	Tables []Table `json:"tables,omitempty" yaml:"tables,omitempty"`
+}
+
+// Table is a captured table. A table can be set as a string, without filter
+type Table struct {
+	Name string `json:"name" yaml:"name"`
+	// Where filters the captured rows
+	Where string `json:"where,omitempty" yaml:"where,omitempty"`
+	// Key are the columns identifying the rows, the primary key of the table by default
+	Key []string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// Row represents a row of a table
+type Row map[string]interface{}
+
+// Update is a row updated since the snapshot
+type Update struct {
+	Key    Row `json:"key" yaml:"key"`
+	Before Row `json:"before" yaml:"before"`
+	After  Row `json:"after" yaml:"after"`
+	// Changed are the updated columns
+	Changed []string `json:"changed" yaml:"changed"`
+}
+
+// Result represents a step result.
+type Result struct {
+	// Rows is the number of captured rows by table
+	Rows map[string]int `json:"rows,omitempty" yaml:"rows,omitempty"`
+	// Inserted, Updated and Deleted are the changed rows by table, and Changes the number of changed rows of all the tables
+	Inserted map[string][]Row    `json:"inserted,omitempty" yaml:"inserted,omitempty"`
+	Updated  map[string][]Update `json:"updated,omitempty" yaml:"updated,omitempty"`
+	Deleted  map[string][]Row    `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+	Changes  int                 `json:"changes" yaml:"changes"`
+}
+
+// snapshots are the snapshots taken by a testcase
+type snapshots struct {
+	mutex sync.Mutex
+	taken map[string]*snapshot
+}
+
+// Run implements the venom.Executor interface for Executor.
+func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	if err := decodeStep(step, &e); err != nil {
+		return nil, err
+	}
+	if e.Action != "snapshot" && e.Action != "diff" {
+		return nil, fmt.Errorf("action %q must be snapshot or diff", e.Action)
+	}
+	if e.Name == "" {
+		e.Name = defaultSnapshot
+	}
+	s, ok := ctx.Value(ContextKey).(*snapshots)
+	if !ok {
+		return nil, fmt.Errorf("no snapshot can be taken outside of a testcase")
+	}
+
+	var before *snapshot
+	if e.Action == "diff" {
+		s.mutex.Lock()
+		before = s.taken[e.Name]
+		s.mutex.Unlock()
+		if before == nil {
+			return nil, fmt.Errorf("snapshot %q was not taken in this testcase", e.Name)
+		}
+		if len(e.Tables) == 0 {
+			e.Tables = before.tables
+		}
+	}
+	if len(e.Tables) == 0 {
+		return nil, fmt.Errorf("tables are mandatory")
+	}
+
+	var db *sqlx.DB
+	driver := e.Driver
+	if e.Connection != "" {
+		pool, connection, err := dbpool.Get(ctx, e.Connection)
+		if err != nil {
+			return nil, err
+		}
+		defer dbpool.LogStats(ctx, e.Connection, pool)
+		db, driver = pool, connection.Driver
+	} else {
+		venom.Debug(ctx, "connecting to database %s, %s\n", e.Driver, e.DSN)
+		var err error
+		db, err = sqlx.Connect(e.Driver, e.DSN)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to database")
+		}
+		defer db.Close()
+	}
+
+	after, err := take(ctx, db, driver, e.Tables)
+	if err != nil {
+		return nil, err
+	}
+	if e.Action == "snapshot" {
+		s.mutex.Lock()
+		s.taken[e.Name] = after
+		s.mutex.Unlock()
+		r := Result{Rows: map[string]int{}}
+		for name, t := range after.captured {
+			r.Rows[name] = len(t.rows)
+		}
+		return r, nil
+	}
+	return diff(before, after)
+}
+
+// decodeStep decodes the step, the tables can be set as strings
+func decodeStep(step venom.TestStep, e *Executor) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to == reflect.TypeOf(Table{}) {
+				return Table{Name: data.(string)}, nil
+			}
+			return data, nil
+		},
+		Result: e,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(step)
+}
+
+// Setup reads the connections declared in the sql.connections variable and prepares the snapshots of the testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	ctx, err := dbpool.Setup(ctx, vars)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, ContextKey, &snapshots{taken: map[string]*snapshot{}}), nil
+}
+
+// TearDown logs the statistics of the connections used by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	dbpool.TearDown(ctx)
+	return nil
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// GetDefaultAssertions return the default assertions of the executor.
+func (e Executor) GetDefaultAssertions() venom.StepAssertions {
+	return venom.StepAssertions{Assertions: []venom.Assertion{}}
+}
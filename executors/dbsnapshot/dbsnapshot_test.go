@@ -0,0 +1,103 @@
+package dbsnapshot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// sqlite returns the DSN of a database with an orders table, and an events table without primary key
+func sqlite(t *testing.T) (string, *sqlx.DB) {
+	dsn := filepath.Join(t.TempDir(), "venom.db")
+	db, err := sqlx.Connect("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.MustExec("CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT, status TEXT)")
+	db.MustExec("CREATE TABLE events (name TEXT, at INTEGER)")
+	db.MustExec("INSERT INTO orders VALUES (1, 'jack', 'NEW'), (2, 'jill', 'NEW'), (3, 'jack', 'PAID')")
+	return dsn, db
+}
+
+func TestExecutor_Run(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn, db := sqlite(t)
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	res, err := Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "snapshot", "tables": []interface{}{"orders"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"orders": 3}, res.(Result).Rows)
+
+	db.MustExec("INSERT INTO orders VALUES (4, 'joe', 'PAID')")
+	db.MustExec("UPDATE orders SET status = 'PAID' WHERE id = 1")
+	db.MustExec("DELETE FROM orders WHERE id = 2")
+
+	res, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "diff"})
+	require.NoError(t, err)
+	r := res.(Result)
+	assert.Equal(t, 3, r.Changes)
+	assert.Equal(t, map[string][]Row{"orders": {{"id": int64(4), "customer": "joe", "status": "PAID"}}}, r.Inserted)
+	assert.Equal(t, map[string][]Update{"orders": {{
+		Key:     Row{"id": int64(1)},
+		Before:  Row{"id": int64(1), "customer": "jack", "status": "NEW"},
+		After:   Row{"id": int64(1), "customer": "jack", "status": "PAID"},
+		Changed: []string{"status"},
+	}}}, r.Updated)
+	assert.Equal(t, map[string][]Row{"orders": {{"id": int64(2), "customer": "jill", "status": "NEW"}}}, r.Deleted)
+
+	// the changes are reachable by the assertions
+	dump := venom.GetExecutorResult(r)
+	assert.Equal(t, "PAID", dump["result.inserted.orders.orders0.status"])
+	assert.Equal(t, "status", dump["result.updated.orders.orders0.changed.changed0"])
+}
+
+func TestExecutor_Run_Filter(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn, db := sqlite(t)
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	tables := []interface{}{
+		map[string]interface{}{"name": "orders", "where": "customer = 'jack'"},
+		map[string]interface{}{"name": "events", "key": []interface{}{"name"}},
+	}
+	res, err := Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "snapshot", "name": "jack", "tables": tables})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"orders": 2, "events": 0}, res.(Result).Rows)
+
+	db.MustExec("INSERT INTO orders VALUES (4, 'joe', 'PAID'), (5, 'jack', 'NEW')")
+	db.MustExec("INSERT INTO events VALUES ('created', 1)")
+
+	res, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "diff", "name": "jack"})
+	require.NoError(t, err)
+	r := res.(Result)
+	assert.Equal(t, 2, r.Changes)
+	assert.Equal(t, []Row{{"id": int64(5), "customer": "jack", "status": "NEW"}}, r.Inserted["orders"])
+	assert.Equal(t, []Row{{"name": "created", "at": int64(1)}}, r.Inserted["events"])
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "diff"})
+	require.EqualError(t, err, `snapshot "default" was not taken in this testcase`)
+}
+
+func TestExecutor_Run_Errors(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn, _ := sqlite(t)
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "snapshot", "tables": []interface{}{"events"}})
+	require.EqualError(t, err, "table events has no primary key, the key must be set")
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "restore", "tables": []interface{}{"orders"}})
+	require.EqualError(t, err, `action "restore" must be snapshot or diff`)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "action": "snapshot", "tables": []interface{}{map[string]interface{}{"name": "orders", "key": []interface{}{"customer"}}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `several rows have the key ["jack"]`)
+}
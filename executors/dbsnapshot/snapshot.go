@@ -0,0 +1,189 @@
+package dbsnapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+// snapshot represents the rows of the captured tables
+type snapshot struct {
+	tables   []Table
+	captured map[string]*capturedTable
+}
+
+// capturedTable represents the rows of a table, by key
+type capturedTable struct {
+	key   []string
+	rows  map[string]Row
+	order []string
+}
+
+// primaryKeyQueries return the columns of the primary key of a table, by driver
+var primaryKeyQueries = map[string]string{
+	"postgres": `SELECT a.attname FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary ORDER BY array_position(i.indkey, a.attnum)`,
+	"mysql": `SELECT column_name FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY' ORDER BY ordinal_position`,
+	"sqlite": `SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`,
+	"oracle": `SELECT cols.column_name FROM all_constraints cons
+		JOIN all_cons_columns cols ON cols.owner = cons.owner AND cols.constraint_name = cons.constraint_name
+		WHERE cons.constraint_type = 'P' AND cons.owner = USER AND cols.table_name = UPPER(:1) ORDER BY cols.position`,
+}
+
+// take captures the rows of the tables
+func take(ctx context.Context, db *sqlx.DB, driver string, tables []Table) (*snapshot, error) {
+	s := &snapshot{captured: map[string]*capturedTable{}}
+	for _, t := range tables {
+		if len(t.Key) == 0 {
+			key, err := primaryKey(ctx, db, driver, t.Name)
+			if err != nil {
+				return nil, err
+			}
+			t.Key = key
+		}
+		venom.Debug(ctx, "capturing table %s by %v\n", t.Name, t.Key)
+		c, err := capture(ctx, db, t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to capture table %s", t.Name)
+		}
+		s.tables = append(s.tables, t)
+		s.captured[t.Name] = c
+	}
+	return s, nil
+}
+
+// primaryKey returns the columns of the primary key of the table
+func primaryKey(ctx context.Context, db *sqlx.DB, driver, table string) ([]string, error) {
+	if driver == "sqlite3" {
+		driver = "sqlite"
+	}
+	query, ok := primaryKeyQueries[driver]
+	if !ok {
+		return nil, fmt.Errorf("unable to find the primary key of table %s with driver %s, the key must be set", table, driver)
+	}
+	var key []string
+	if err := db.SelectContext(ctx, &key, query, table); err != nil {
+		return nil, errors.Wrapf(err, "failed to find the primary key of table %s", table)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key, the key must be set", table)
+	}
+	return key, nil
+}
+
+// capture returns the rows of the table, by key
+func capture(ctx context.Context, db *sqlx.DB, t Table) (*capturedTable, error) {
+	query := "SELECT * FROM " + t.Name
+	if t.Where != "" {
+		query += " WHERE " + t.Where
+	}
+	query += " ORDER BY " + strings.Join(t.Key, ", ")
+	rows, err := db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := &capturedTable{key: t.Key, rows: map[string]Row{}}
+	for rows.Next() {
+		row := Row{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for column, value := range row {
+			// the text values of some drivers are scanned as bytes
+			if b, ok := value.([]byte); ok {
+				row[column] = string(b)
+			}
+		}
+		k, err := c.keyOf(row)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := c.rows[k]; ok {
+			return nil, fmt.Errorf("several rows have the key %s", k)
+		}
+		c.rows[k] = row
+		c.order = append(c.order, k)
+	}
+	return c, rows.Err()
+}
+
+// keyOf returns the key of the row, as the JSON array of the values of the key columns
+func (c *capturedTable) keyOf(row Row) (string, error) {
+	values := make([]interface{}, len(c.key))
+	for i, column := range c.key {
+		v, ok := row[column]
+		if !ok {
+			return "", fmt.Errorf("key column %s is not a column of the table", column)
+		}
+		values[i] = v
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// diff returns the rows inserted, updated and deleted between the snapshots
+func diff(before, after *snapshot) (Result, error) {
+	r := Result{Inserted: map[string][]Row{}, Updated: map[string][]Update{}, Deleted: map[string][]Row{}}
+	for _, t := range after.tables {
+		b, ok := before.captured[t.Name]
+		if !ok {
+			return Result{}, fmt.Errorf("table %s is not captured by the snapshot", t.Name)
+		}
+		a := after.captured[t.Name]
+		inserted, updated, deleted := []Row{}, []Update{}, []Row{}
+		for _, k := range a.order {
+			row := a.rows[k]
+			previous, ok := b.rows[k]
+			if !ok {
+				inserted = append(inserted, row)
+				continue
+			}
+			if changed := changedColumns(previous, row); len(changed) > 0 {
+				key := Row{}
+				for _, column := range a.key {
+					key[column] = row[column]
+				}
+				updated = append(updated, Update{Key: key, Before: previous, After: row, Changed: changed})
+			}
+		}
+		for _, k := range b.order {
+			if _, ok := a.rows[k]; !ok {
+				deleted = append(deleted, b.rows[k])
+			}
+		}
+		r.Inserted[t.Name], r.Updated[t.Name], r.Deleted[t.Name] = inserted, updated, deleted
+		r.Changes += len(inserted) + len(updated) + len(deleted)
+	}
+	return r, nil
+}
+
+// changedColumns returns the sorted columns whose values differ between the rows
+func changedColumns(before, after Row) []string {
+	changed := []string{}
+	for column, value := range after {
+		if previous, ok := before[column]; !ok || !reflect.DeepEqual(previous, value) {
+			changed = append(changed, column)
+		}
+	}
+	for column := range before {
+		if _, ok := after[column]; !ok {
+			changed = append(changed, column)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,323 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+// client sends the requests of an executor to the REST API, common to Elasticsearch and OpenSearch
+type client struct {
+	executor Executor
+	http     *http.Client
+}
+
+// do sends a request, and decodes the JSON response in out if not nil.
+// It returns the status code of the response, and an error for the other status codes than 2xx and the ignored ones,
+// whose responses are decoded like the 2xx ones
+func (c *client) do(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader, out interface{}, ignored ...int) (int, error) {
+	u := strings.TrimSuffix(c.executor.URL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.executor.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.executor.APIKey)
+	} else if c.executor.Username != "" {
+		req.SetBasicAuth(c.executor.Username, c.executor.Password)
+	}
+
+	venom.Debug(ctx, "%s %s", method, u)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	isIgnored := false
+	for _, status := range ignored {
+		isIgnored = isIgnored || resp.StatusCode == status
+	}
+	if resp.StatusCode >= 300 && !isIgnored {
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, errorReason(b))
+	}
+	// the responses of the ignored statuses are decoded too, they may tell why the request did not succeed
+	if out != nil {
+		if err := venom.JSONUnmarshal(b, out); err != nil {
+			return resp.StatusCode, errors.Wrapf(err, "unable to decode response of %s %s", method, path)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// doJSON sends a request with a JSON body
+func (c *client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}, ignored ...int) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+	return c.do(ctx, method, path, query, "application/json", body, out, ignored...)
+}
+
+// errorReason returns the reason of an error response, or the whole response
+func errorReason(body []byte) string {
+	var response struct {
+		Error struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil && response.Error.Reason != "" {
+		return response.Error.Type + ": " + response.Error.Reason
+	}
+	return string(body)
+}
+
+func (c *client) indexPath(suffix string) string {
+	return "/" + url.PathEscape(c.executor.Index) + suffix
+}
+
+// waitForStatus waits for the health of the index, or of the cluster without index, to reach the status
+func (c *client) waitForStatus(ctx context.Context) error {
+	status := c.executor.WaitForStatus
+	if status != "green" && status != "yellow" {
+		return fmt.Errorf("wait_for_status %q must be green or yellow", status)
+	}
+	path := "/_cluster/health"
+	if c.executor.Index != "" {
+		path += c.indexPath("")
+	}
+	query := url.Values{"wait_for_status": {status}, "timeout": {fmt.Sprintf("%ds", c.executor.Timeout)}}
+
+	var health struct {
+		Status   string `json:"status"`
+		TimedOut bool   `json:"timed_out"`
+	}
+	// the health is 408 when the status is not reached
+	code, err := c.doJSON(ctx, http.MethodGet, path, query, nil, &health, http.StatusRequestTimeout)
+	if err != nil {
+		return err
+	}
+	if health.TimedOut || code == http.StatusRequestTimeout {
+		return fmt.Errorf("health status %q is not %s after %ds", health.Status, status, c.executor.Timeout)
+	}
+	return nil
+}
+
+func (c *client) createIndex(ctx context.Context) (Result, error) {
+	var body map[string]interface{}
+	if c.executor.File != "" {
+		var err error
+		body, err = readFile(ctx, c.executor.File)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	var response map[string]interface{}
+	if _, err := c.doJSON(ctx, http.MethodPut, c.indexPath(""), nil, body, &response); err != nil {
+		return Result{}, err
+	}
+	return Result{Acknowledged: response["acknowledged"] == true, Body: response}, nil
+}
+
+func (c *client) deleteIndex(ctx context.Context) (Result, error) {
+	var response map[string]interface{}
+	status, err := c.doJSON(ctx, http.MethodDelete, c.indexPath(""), nil, nil, &response, http.StatusNotFound)
+	if err != nil || status == http.StatusNotFound {
+		return Result{}, err
+	}
+	return Result{Acknowledged: response["acknowledged"] == true, Body: response}, nil
+}
+
+// indexDocuments indexes the documents with a bulk request
+func (c *client) indexDocuments(ctx context.Context) (Result, error) {
+	if len(c.executor.Documents) == 0 {
+		return Result{}, errors.New("documents are mandatory")
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, document := range c.executor.Documents {
+		action := map[string]interface{}{}
+		source := make(map[string]interface{}, len(document))
+		for k, v := range document {
+			if k == "_id" {
+				action["_id"] = documentID(v)
+				continue
+			}
+			source[k] = v
+		}
+		if err := encoder.Encode(map[string]interface{}{"index": action}); err != nil {
+			return Result{}, err
+		}
+		if err := encoder.Encode(source); err != nil {
+			return Result{}, err
+		}
+	}
+
+	query := url.Values{}
+	if c.executor.Refresh {
+		query.Set("refresh", "wait_for")
+	}
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string      `json:"_id"`
+			Error interface{} `json:"error"`
+		} `json:"items"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, c.indexPath("/_bulk"), query, "application/x-ndjson", &body, &response); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{IDs: make([]string, 0, len(response.Items))}
+	for i, item := range response.Items {
+		if item["index"].Error != nil {
+			b, _ := json.Marshal(item["index"].Error)
+			return Result{}, fmt.Errorf("unable to index document %d: %s", i, b)
+		}
+		result.IDs = append(result.IDs, item["index"].ID)
+	}
+	result.Count = int64(len(result.IDs))
+	return result, nil
+}
+
+// documentID returns the id of a document, without exponent for the numbers
+func documentID(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func (c *client) refresh(ctx context.Context) (Result, error) {
+	path := "/_refresh"
+	if c.executor.Index != "" {
+		path = c.indexPath(path)
+	}
+	var response map[string]interface{}
+	if _, err := c.doJSON(ctx, http.MethodPost, path, nil, nil, &response); err != nil {
+		return Result{}, err
+	}
+	return Result{Body: response}, nil
+}
+
+func (c *client) search(ctx context.Context) (Result, error) {
+	if c.executor.Refresh {
+		if _, err := c.refresh(ctx); err != nil {
+			return Result{}, err
+		}
+	}
+
+	body := map[string]interface{}{}
+	if c.executor.Query != nil {
+		body["query"] = c.executor.Query
+	}
+	if c.executor.Aggregations != nil {
+		body["aggregations"] = c.executor.Aggregations
+	}
+	if c.executor.Sort != nil {
+		body["sort"] = c.executor.Sort
+	}
+	if c.executor.Size != nil {
+		body["size"] = *c.executor.Size
+	}
+	if c.executor.From != nil {
+		body["from"] = *c.executor.From
+	}
+	// the total is exact, not capped to 10000
+	query := url.Values{"track_total_hits": {"true"}}
+
+	var response map[string]interface{}
+	if _, err := c.doJSON(ctx, http.MethodPost, c.indexPath("/_search"), query, body, &response); err != nil {
+		return Result{}, err
+	}
+	return searchResult(response)
+}
+
+// searchResult returns the hits, the total and the aggregations of a search response
+func searchResult(response map[string]interface{}) (Result, error) {
+	var decoded struct {
+		Hits struct {
+			// Total is a number before Elasticsearch 7, an object with a value after
+			Total interface{} `json:"total"`
+			Hits  []struct {
+				ID     string      `json:"_id"`
+				Index  string      `json:"_index"`
+				Score  interface{} `json:"_score"`
+				Source interface{} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]interface{} `json:"aggregations"`
+	}
+	b, err := json.Marshal(response)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := venom.JSONUnmarshal(b, &decoded); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Hits: make([]Hit, len(decoded.Hits.Hits)), Aggregations: decoded.Aggregations, Body: response}
+	for i, hit := range decoded.Hits.Hits {
+		score, _ := toNumber(hit.Score).Float64()
+		result.Hits[i] = Hit{ID: hit.ID, Index: hit.Index, Score: score, Source: hit.Source}
+	}
+	total := decoded.Hits.Total
+	if m, ok := total.(map[string]interface{}); ok {
+		total = m["value"]
+	}
+	result.Total, _ = toNumber(total).Int64()
+	return result, nil
+}
+
+func (c *client) count(ctx context.Context) (Result, error) {
+	if c.executor.Refresh {
+		if _, err := c.refresh(ctx); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var body interface{}
+	if c.executor.Query != nil {
+		body = map[string]interface{}{"query": c.executor.Query}
+	}
+	var response struct {
+		Count int64 `json:"count"`
+	}
+	if _, err := c.doJSON(ctx, http.MethodPost, c.indexPath("/_count"), nil, body, &response); err != nil {
+		return Result{}, err
+	}
+	return Result{Count: response.Count}, nil
+}
+
+// toNumber returns the JSON number of a decoded value, or zero
+func toNumber(v interface{}) json.Number {
+	if n, ok := v.(json.Number); ok {
+		return n
+	}
+	return "0"
+}
@@ -0,0 +1,168 @@
+package elasticsearch
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ovh/venom"
+)
+
+// Name of executor
+const Name = "elasticsearch"
+
+const defaultTimeout = 30
+
+// New returns a new Executor
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor represents an action on an Elasticsearch or OpenSearch cluster
+type Executor struct {
+	URL             string `json:"url" yaml:"url"`
+	Username        string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password        string `json:"password,omitempty" yaml:"password,omitempty"`
+	APIKey          string `json:"api_key,omitempty" yaml:"api_key,omitempty" mapstructure:"api_key"`
+	IgnoreVerifySSL bool   `json:"ignore_verify_ssl,omitempty" yaml:"ignore_verify_ssl,omitempty" mapstructure:"ignore_verify_ssl"`
+
+	// Action is createIndex, deleteIndex, index, refresh, search or count
+	Action string `json:"action" yaml:"action"`
+	Index  string `json:"index" yaml:"index"`
+
+	// File is the settings and mappings of the created index, in YAML or JSON
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Documents to index. The _id field of a document is its id
+	Documents []map[string]interface{} `json:"documents,omitempty" yaml:"documents,omitempty"`
+
+	// Query, Aggregations, Sort, Size and From are the body of the search, Query the body of the count
+	Query        map[string]interface{} `json:"query,omitempty" yaml:"query,omitempty"`
+	Aggregations map[string]interface{} `json:"aggregations,omitempty" yaml:"aggregations,omitempty"`
+	Sort         []interface{}          `json:"sort,omitempty" yaml:"sort,omitempty"`
+	Size         *int                   `json:"size,omitempty" yaml:"size,omitempty"`
+	From         *int                   `json:"from,omitempty" yaml:"from,omitempty"`
+
+	// Refresh makes the changes visible to search: index waits for a refresh, search and count refresh the index first
+	Refresh bool `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+	// WaitForStatus waits for the health of the cluster, or of the index, to be green or yellow before the action
+	WaitForStatus string `json:"wait_for_status,omitempty" yaml:"wait_for_status,omitempty" mapstructure:"wait_for_status"`
+	// Timeout of the requests and of the wait for status, in seconds. Default 30
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Hit is a document found by a search
+type Hit struct {
+	ID     string      `json:"id" yaml:"id"`
+	Index  string      `json:"index" yaml:"index"`
+	Score  float64     `json:"score" yaml:"score"`
+	Source interface{} `json:"source" yaml:"source"`
+}
+
+// Result represents a step result
+type Result struct {
+	// Hits are the documents found by search, and Total the number of matching documents
+	Hits         []Hit                  `json:"hits,omitempty" yaml:"hits,omitempty"`
+	Total        int64                  `json:"total" yaml:"total"`
+	Aggregations map[string]interface{} `json:"aggregations,omitempty" yaml:"aggregations,omitempty"`
+	// Count is the number of documents counted or indexed
+	Count int64 `json:"count" yaml:"count"`
+	// IDs are the ids of the indexed documents
+	IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+	// Acknowledged is the acknowledgement of createIndex and deleteIndex, false if the deleted index does not exist
+	Acknowledged bool `json:"acknowledged,omitempty" yaml:"acknowledged,omitempty"`
+	// Body is the response of the last request
+	Body interface{} `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// Run execute TestStep
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	var e Executor
+	if err := mapstructure.Decode(step, &e); err != nil {
+		return nil, err
+	}
+	if e.URL == "" {
+		return nil, errors.New("url is mandatory")
+	}
+	if e.Index == "" && e.Action != "refresh" {
+		return nil, errors.New("index is mandatory")
+	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeout
+	}
+
+	c := &client{
+		executor: e,
+		http: &http.Client{
+			Timeout: time.Duration(e.Timeout) * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: e.IgnoreVerifySSL},
+			},
+		},
+	}
+
+	if e.WaitForStatus != "" && e.Action != "createIndex" {
+		if err := c.waitForStatus(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var result Result
+	var err error
+	switch e.Action {
+	case "createIndex":
+		result, err = c.createIndex(ctx)
+		if err == nil && e.WaitForStatus != "" {
+			err = c.waitForStatus(ctx)
+		}
+	case "deleteIndex":
+		result, err = c.deleteIndex(ctx)
+	case "index":
+		result, err = c.indexDocuments(ctx)
+	case "refresh":
+		result, err = c.refresh(ctx)
+	case "search":
+		result, err = c.search(ctx)
+	case "count":
+		result, err = c.count(ctx)
+	default:
+		return nil, fmt.Errorf("action %q must be createIndex, deleteIndex, index, refresh, search or count", e.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readFile returns the content of a YAML or JSON file of the testsuite
+func readFile(ctx context.Context, file string) (map[string]interface{}, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(venom.StringVarFromCtx(ctx, "venom.testsuite.workdir"), file)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file %q", file)
+	}
+	content := map[string]interface{}{}
+	if strings.TrimSpace(string(b)) == "" {
+		return content, nil
+	}
+	if err := yaml.Unmarshal(b, &content); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode file %q", file)
+	}
+	return content, nil
+}
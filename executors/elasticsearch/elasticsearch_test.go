@@ -0,0 +1,141 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// newServer returns a fake cluster recording the requests
+func newServer(t *testing.T, requests *[]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, r.Method+" "+r.URL.RequestURI()+" "+strings.TrimSpace(string(body)))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/products":
+			_, _ = w.Write([]byte(`{"acknowledged": true, "index": "products"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"type": "index_not_found_exception", "reason": "no such index [missing]"}}`))
+		case r.URL.Path == "/_cluster/health/products":
+			_, _ = w.Write([]byte(`{"status": "green", "timed_out": false}`))
+		case r.URL.Path == "/products/_bulk":
+			_, _ = w.Write([]byte(`{"errors": false, "items": [{"index": {"_id": "1", "status": 201}}, {"index": {"_id": "generated", "status": 201}}]}`))
+		case r.URL.Path == "/products/_refresh":
+			_, _ = w.Write([]byte(`{"_shards": {"total": 1, "successful": 1, "failed": 0}}`))
+		case r.URL.Path == "/products/_search":
+			_, _ = w.Write([]byte(`{"hits": {"total": {"value": 2, "relation": "eq"}, "hits": [
+				{"_index": "products", "_id": "1", "_score": 1.5, "_source": {"name": "venom", "category": "tools"}},
+				{"_index": "products", "_id": "2", "_score": null, "_source": {"name": "cds", "category": "tools"}}]},
+				"aggregations": {"categories": {"buckets": [{"key": "tools", "doc_count": 2}]}}}`))
+		case r.URL.Path == "/products/_count":
+			_, _ = w.Write([]byte(`{"count": 2}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"type": "illegal_argument_exception", "reason": "unexpected request"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExecutor_Run(t *testing.T) {
+	venom.InitTestLogger(t)
+	var requests []string
+	srv := newServer(t, &requests)
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), "../../tests")
+
+	run := func(step venom.TestStep) (Result, error) {
+		step["url"] = srv.URL
+		result, err := Executor{}.Run(ctx, step)
+		if err != nil {
+			return Result{}, err
+		}
+		return result.(Result), nil
+	}
+
+	result, err := run(venom.TestStep{"action": "createIndex", "index": "products", "file": "elasticsearch/products.yml", "wait_for_status": "green"})
+	require.NoError(t, err)
+	assert.True(t, result.Acknowledged)
+	require.Len(t, requests, 2)
+	assert.Contains(t, requests[0], `"number_of_replicas":0`)
+	assert.Equal(t, "GET /_cluster/health/products?timeout=30s&wait_for_status=green ", requests[1])
+
+	result, err = run(venom.TestStep{"action": "deleteIndex", "index": "missing"})
+	require.NoError(t, err)
+	assert.False(t, result.Acknowledged)
+
+	requests = nil
+	result, err = run(venom.TestStep{"action": "index", "index": "products", "refresh": true, "documents": []map[string]interface{}{
+		{"_id": float64(1), "name": "venom"},
+		{"name": "cds"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Count)
+	assert.Equal(t, []string{"1", "generated"}, result.IDs)
+	assert.Equal(t, "POST /products/_bulk?refresh=wait_for "+
+		`{"index":{"_id":"1"}}`+"\n"+`{"name":"venom"}`+"\n"+`{"index":{}}`+"\n"+`{"name":"cds"}`, requests[0])
+
+	requests = nil
+	result, err = run(venom.TestStep{"action": "search", "index": "products", "refresh": true, "size": 10,
+		"query":        map[string]interface{}{"term": map[string]interface{}{"category": "tools"}},
+		"aggregations": map[string]interface{}{"categories": map[string]interface{}{"terms": map[string]interface{}{"field": "category"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "POST /products/_refresh ", requests[0])
+	assert.Equal(t, int64(2), result.Total)
+	require.Len(t, result.Hits, 2)
+	assert.Equal(t, Hit{ID: "1", Index: "products", Score: 1.5, Source: map[string]interface{}{"name": "venom", "category": "tools"}}, result.Hits[0])
+	assert.Equal(t, float64(0), result.Hits[1].Score)
+	buckets := result.Aggregations["categories"].(map[string]interface{})["buckets"].([]interface{})
+	assert.Equal(t, json.Number("2"), buckets[0].(map[string]interface{})["doc_count"])
+
+	result, err = run(venom.TestStep{"action": "count", "index": "products", "query": map[string]interface{}{"match_all": map[string]interface{}{}}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Count)
+
+	_, err = run(venom.TestStep{"action": "search", "index": "unknown"})
+	assert.EqualError(t, err, "POST /unknown/_search: 400 Bad Request: illegal_argument_exception: unexpected request")
+
+	_, err = run(venom.TestStep{"action": "reindex", "index": "products"})
+	assert.EqualError(t, err, `action "reindex" must be createIndex, deleteIndex, index, refresh, search or count`)
+}
+
+func TestExecutor_Run_WaitForStatusTimeout(t *testing.T) {
+	venom.InitTestLogger(t)
+	// the health is 408 when the status is not reached before the timeout
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestTimeout)
+		_, _ = w.Write([]byte(`{"status": "red", "timed_out": true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"url": srv.URL, "action": "count", "index": "products", "wait_for_status": "green", "timeout": 1,
+	})
+	assert.EqualError(t, err, `health status "red" is not green after 1s`)
+}
+
+func TestSearchResult_Total(t *testing.T) {
+	// before Elasticsearch 7, the total is a number
+	result, err := searchResult(map[string]interface{}{"hits": map[string]interface{}{"total": json.Number("3"), "hits": []interface{}{}}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.Total)
+}
+
+func TestDocumentID(t *testing.T) {
+	assert.Equal(t, "1234567", documentID(float64(1234567)))
+	assert.Equal(t, "sku-1", documentID("sku-1"))
+}
@@ -0,0 +1,222 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/ovh/venom"
+)
+
+// Name of executor
+const Name = "graphql"
+
+const defaultTimeoutMs = 5000
+
+// New returns a new Executor
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor represents a Test Exec
+type Executor struct {
+	URL             string                 `json:"url" yaml:"url"`
+	Headers         map[string]string      `json:"headers" yaml:"headers"`
+	IgnoreVerifySSL bool                   `json:"ignore_verify_ssl" yaml:"ignore_verify_ssl" mapstructure:"ignore_verify_ssl"`
+	Query           string                 `json:"query" yaml:"query"`
+	Variables       map[string]interface{} `json:"variables" yaml:"variables"`
+	OperationName   string                 `json:"operationName" yaml:"operationName"`
+
+	// SchemaFile is a SDL document or an introspection result in JSON used to validate the query before sending it
+	SchemaFile string `json:"schemaFile" yaml:"schemaFile"`
+	// Introspect validates the query against the schema returned by the introspection of the server
+	Introspect bool `json:"introspect" yaml:"introspect"`
+
+	// Used by subscriptions, sent over websocket with the graphql-transport-ws protocol
+	SubscriptionURL  string                 `json:"subscriptionUrl" yaml:"subscriptionUrl"`
+	ConnectionParams map[string]interface{} `json:"connectionParams" yaml:"connectionParams"`
+	// MessageLimit represents the number of events to read before returning
+	MessageLimit int `json:"messageLimit" yaml:"messageLimit"`
+	// Timeout of HTTP requests, and of the handshake and the reading of events of a subscription. In Milliseconds. Default 5000
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Response is a GraphQL response, or a subscription event
+type Response struct {
+	Data       interface{}   `json:"data,omitempty" yaml:"data,omitempty"`
+	Errors     []interface{} `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Extensions interface{}   `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+// Result represents a step result
+type Result struct {
+	TimeSeconds float64       `json:"timeseconds,omitempty" yaml:"timeseconds,omitempty"`
+	StatusCode  int           `json:"statuscode,omitempty" yaml:"statuscode,omitempty"`
+	Body        string        `json:"body,omitempty" yaml:"body,omitempty"`
+	Data        interface{}   `json:"data,omitempty" yaml:"data,omitempty"`
+	Errors      []interface{} `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Extensions  interface{}   `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	Events      []Response    `json:"events,omitempty" yaml:"events,omitempty"`
+	Err         string        `json:"err,omitempty" yaml:"err,omitempty"`
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// GetDefaultAssertions return default assertions for this executor
+func (Executor) GetDefaultAssertions() *venom.StepAssertions {
+	return &venom.StepAssertions{Assertions: []venom.Assertion{"result.err ShouldBeEmpty"}}
+}
+
+// Run execute TestStep
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	var e Executor
+	if err := mapstructure.Decode(step, &e); err != nil {
+		return nil, err
+	}
+	if e.URL == "" {
+		return nil, errors.New("url is mandatory")
+	}
+	if e.Query == "" {
+		return nil, errors.New("query is mandatory")
+	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeoutMs
+	}
+
+	doc, err := parseDocument(e.Query)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse query")
+	}
+	op, err := operation(doc, e.OperationName)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout: time.Duration(e.Timeout) * time.Millisecond,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: e.IgnoreVerifySSL},
+		},
+	}
+
+	if e.SchemaFile != "" || e.Introspect {
+		s, err := e.loadSchema(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		if err := validate(s, doc, op, e.Variables); err != nil {
+			return nil, errors.Wrap(err, "invalid query")
+		}
+	}
+
+	start := time.Now()
+	var result Result
+	if op.Operation == ast.Subscription {
+		result = e.subscribe(ctx)
+	} else {
+		result = e.do(ctx, client)
+	}
+	result.TimeSeconds = time.Since(start).Seconds()
+	return result, nil
+}
+
+// do sends the operation over HTTP
+func (e Executor) do(ctx context.Context, client *http.Client) Result {
+	var result Result
+	body, statusCode, err := e.post(ctx, client, request{Query: e.Query, Variables: e.Variables, OperationName: e.OperationName})
+	result.StatusCode = statusCode
+	result.Body = string(body)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	var resp Response
+	if err := venom.JSONUnmarshal(body, &resp); err != nil {
+		result.Err = fmt.Sprintf("unable to read response: %v", err)
+		return result
+	}
+	result.Data, result.Errors, result.Extensions = resp.Data, resp.Errors, resp.Extensions
+	return result
+}
+
+func (e Executor) post(ctx context.Context, client *http.Client, r request) ([]byte, int, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to marshal request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	venom.Debug(ctx, "sending %s to %s", payload, e.URL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, errors.Wrap(err, "unable to read body")
+	}
+	return body, resp.StatusCode, nil
+}
+
+// loadSchema reads the schema file, or introspects the server
+func (e Executor) loadSchema(ctx context.Context, client *http.Client) (*ast.Schema, error) {
+	if e.SchemaFile != "" {
+		path := e.SchemaFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(venom.StringVarFromCtx(ctx, "venom.testsuite.workdir"), path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read schema file %s", path)
+		}
+		s, err := parseSchema(content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse schema file %s", path)
+		}
+		return s, nil
+	}
+
+	body, statusCode, err := e.post(ctx, client, request{Query: introspectionQuery, OperationName: "IntrospectionQuery"})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to introspect schema")
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to introspect schema: status %d: %s", statusCode, body)
+	}
+	s, err := parseIntrospection(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to introspect schema")
+	}
+	return s, nil
+}
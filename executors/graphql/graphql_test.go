@@ -0,0 +1,290 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/ovh/venom"
+)
+
+const testSDL = `
+"""
+The root query
+"""
+type Query {
+  "Fetch a user"
+  user(id: ID!): User
+  users(first: Int = 10): [User!]!
+  search(text: String): [SearchResult]
+}
+
+type Mutation {
+  createUser(input: CreateUserInput!): User @deprecated(reason: "use register")
+}
+
+type Subscription {
+  userCreated: User
+}
+
+interface Node {
+  id: ID!
+}
+
+type User implements Node & Named @key(fields: "id") {
+  id: ID!
+  name: String
+  role: Role
+  friends: [User]
+}
+
+interface Named { name: String }
+
+union SearchResult = | User | Post
+
+type Post implements Node { id: ID! title: String }
+
+enum Role { ADMIN USER }
+
+input CreateUserInput { name: String! }
+
+scalar Date
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+`
+
+func TestParseSDL(t *testing.T) {
+	s, err := parseSDL(testSDL)
+	require.NoError(t, err)
+	assert.Equal(t, "Query", s.Query.Name)
+	assert.Equal(t, "Mutation", s.Mutation.Name)
+	assert.Equal(t, "Subscription", s.Subscription.Name)
+	assert.Equal(t, "[User!]!", s.Types["Query"].Fields.ForName("users").Type.String())
+	assert.Equal(t, "Role", s.Types["User"].Fields.ForName("role").Type.Name())
+	assert.Equal(t, ast.Enum, s.Types["Role"].Kind)
+	assert.Equal(t, ast.Union, s.Types["SearchResult"].Kind)
+	assert.Equal(t, ast.Interface, s.Types["Node"].Kind)
+	assert.Equal(t, ast.Scalar, s.Types["Date"].Kind)
+
+	_, err = parseSDL(`type Query { user: Unknown }`)
+	require.Error(t, err)
+}
+
+func TestParseIntrospection(t *testing.T) {
+	content := `{"data":{"__schema":{"queryType":{"name":"Root"},"mutationType":null,"subscriptionType":null,
+		"types":[{"kind":"OBJECT","name":"Root","fields":[{"name":"ids","args":[{"name":"first","type":{"kind":"SCALAR","name":"Int"},"defaultValue":"10"}],
+		"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}}}},
+		{"name":"search","args":[{"name":"filter","type":{"kind":"NON_NULL","ofType":{"kind":"INPUT_OBJECT","name":"Filter"}}}],"type":{"kind":"UNION","name":"Result"}}]},
+		{"kind":"INPUT_OBJECT","name":"Filter","inputFields":[{"name":"color","type":{"kind":"ENUM","name":"Color"},"defaultValue":"RED"}]},
+		{"kind":"ENUM","name":"Color","enumValues":[{"name":"RED"},{"name":"BLUE"}]},
+		{"kind":"UNION","name":"Result","possibleTypes":[{"kind":"OBJECT","name":"Item"}]},
+		{"kind":"OBJECT","name":"Item","interfaces":[{"kind":"INTERFACE","name":"Node"}],"fields":[{"name":"id","type":{"kind":"SCALAR","name":"ID"}}]},
+		{"kind":"INTERFACE","name":"Node","fields":[{"name":"id","type":{"kind":"SCALAR","name":"ID"}}],"possibleTypes":[{"kind":"OBJECT","name":"Item"}]},
+		{"kind":"SCALAR","name":"String"},{"kind":"OBJECT","name":"__Schema","fields":[]}],
+		"directives":[{"name":"include","locations":["FIELD"],"args":[{"name":"if","type":{"kind":"NON_NULL","ofType":{"kind":"SCALAR","name":"Boolean"}}}]},
+		{"name":"cached","locations":["FIELD","QUERY"],"args":[]}]}}}`
+	s, err := parseSchema([]byte(content))
+	require.NoError(t, err)
+	assert.Equal(t, "Root", s.Query.Name)
+	assert.Nil(t, s.Mutation)
+	assert.Equal(t, "[ID]!", s.Types["Root"].Fields.ForName("ids").Type.String())
+	assert.Equal(t, "Filter!", s.Types["Root"].Fields.ForName("search").Arguments.ForName("filter").Type.String())
+	assert.Equal(t, ast.Union, s.Types["Result"].Kind)
+	require.NotNil(t, s.Directives["cached"])
+
+	doc, err := parseDocument(`{ ids(first: 2) search(filter: {color: BLUE}) @cached { ... on Item { id } } }`)
+	require.NoError(t, err)
+	op, err := operation(doc, "")
+	require.NoError(t, err)
+	require.NoError(t, validate(s, doc, op, nil))
+}
+
+func TestValidate(t *testing.T) {
+	s, err := parseSDL(testSDL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		err           string
+	}{
+		{name: "shorthand", query: `{ users { id name } }`},
+		{name: "alias, arguments and directives", query: `query Get($id: ID!) { me: user(id: $id) @include(if: true) { id, role, __typename } }`, variables: map[string]interface{}{"id": 42}},
+		{name: "fragments", query: `query { users { ...UserFields friends { ... on User { id } } } } fragment UserFields on User { id name }`},
+		{name: "union", query: `{ search(text: "a \"quoted\" string") { __typename ... on Post { title } } }`},
+		{name: "mutation", query: `mutation { createUser(input: {name: "foo"}) { id } }`},
+		{name: "selected operation", query: `query A { users { id } } query B { users { name } }`, operationName: "A"},
+		{name: "unknown field", query: `{ users { id email } }`, err: `Cannot query field "email" on type "User"`},
+		{name: "missing selection", query: `{ users }`, err: `Field "users" of type "[User!]!" must have a selection of subfields`},
+		{name: "selection on leaf", query: `{ users { role { id } } }`, err: `Field "role" must not have a selection since type "Role" has no subfields`},
+		{name: "unknown fragment", query: `{ users { ...Missing } }`, err: `Unknown fragment "Missing"`},
+		{name: "unknown argument", query: `{ users(last: 1) { id } }`, err: `Unknown argument "last" on field "Query.users"`},
+		{name: "missing argument", query: `{ user { id } }`, err: `Field "user" argument "id" of type "ID!" is required`},
+		{name: "wrong argument type", query: `{ users(first: "ten") { id } }`, err: `Int cannot represent non-integer value`},
+		{name: "wrong variable type", query: `query Get($id: String) { user(id: $id) { id } }`, err: `Variable "$id" of type "String" used in position expecting type "ID!"`},
+		{name: "undefined variable", query: `{ user(id: $id) { id } }`, err: `Variable "$id" is not defined`},
+		{name: "missing variable value", query: `query Get($id: ID!) { user(id: $id) { id } }`, err: `must be defined`},
+		{name: "wrong variable value", query: `query Get($id: ID!) { user(id: $id) { id } }`, variables: map[string]interface{}{"id": true}, err: `cannot use bool as ID`},
+		{name: "fragment on wrong type", query: `{ users { ...PostFields } } fragment PostFields on Post { title }`, err: `Fragment "PostFields" cannot be spread here as objects of type "User" can never be of type "Post"`},
+		{name: "several operations", query: `query A { users { id } } query B { users { id } }`, err: "operationName is mandatory"},
+		{name: "syntax error", query: `{ users { id }`, err: "Expected Name, found <EOF>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseDocument(tt.query)
+			if err == nil {
+				var op *ast.OperationDefinition
+				op, err = operation(doc, tt.operationName)
+				if err == nil {
+					err = validate(s, doc, op, tt.variables)
+				}
+			}
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func newGraphQLServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ws.IsWebSocketUpgrade(r) {
+			serveSubscription(w, r)
+			return
+		}
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.Header().Set("Content-Type", "application/json")
+		if req.OperationName == "IntrospectionQuery" {
+			fmt.Fprint(w, `{"data":{"__schema":{"queryType":{"name":"Query"},"types":[
+				{"kind":"OBJECT","name":"Query","fields":[{"name":"hello","args":[{"name":"name","type":{"kind":"SCALAR","name":"String"}}],"type":{"kind":"SCALAR","name":"String"}}]}]}}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"hello":"hello %v"},"errors":[{"message":"partial"}],"extensions":{"cost":%d}}`,
+			req.Variables["name"], len(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func serveSubscription(w http.ResponseWriter, r *http.Request) {
+	upgrader := ws.Upgrader{Subprotocols: []string{subprotocol}}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var msg wsMessage
+	if conn.ReadJSON(&msg) != nil || msg.Type != "connection_init" {
+		return
+	}
+	if conn.WriteJSON(wsMessage{Type: "connection_ack"}) != nil {
+		return
+	}
+	if conn.ReadJSON(&msg) != nil || msg.Type != "subscribe" {
+		return
+	}
+	for i := 0; i < 3; i++ {
+		payload := json.RawMessage(fmt.Sprintf(`{"data":{"counter":%d}}`, i))
+		if conn.WriteJSON(wsMessage{ID: msg.ID, Type: "next", Payload: payload}) != nil {
+			return
+		}
+	}
+	_ = conn.WriteJSON(wsMessage{ID: msg.ID, Type: "complete"})
+	_, _, _ = conn.ReadMessage()
+}
+
+func TestExecutor_Run_Query(t *testing.T) {
+	venom.InitTestLogger(t)
+	srv := newGraphQLServer(t)
+
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"url":        srv.URL,
+		"headers":    map[string]string{"Authorization": "abc"},
+		"query":      `query Hello($name: String) { hello(name: $name) }`,
+		"variables":  map[string]interface{}{"name": "venom"},
+		"introspect": true,
+	})
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Empty(t, result.Err)
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, map[string]interface{}{"hello": "hello venom"}, result.Data)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, map[string]interface{}{"cost": json.Number("3")}, result.Extensions)
+}
+
+func TestExecutor_Run_InvalidQuery(t *testing.T) {
+	venom.InitTestLogger(t)
+	srv := newGraphQLServer(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schema.graphql"), []byte(testSDL), 0o644))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), dir)
+
+	_, err := Executor{}.Run(ctx, venom.TestStep{
+		"url":        srv.URL,
+		"query":      `{ hello }`,
+		"schemaFile": "schema.graphql",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `Cannot query field "hello" on type "Query"`)
+}
+
+func TestExecutor_Run_Subscription(t *testing.T) {
+	venom.InitTestLogger(t)
+	srv := newGraphQLServer(t)
+
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"url":          srv.URL,
+		"query":        `subscription { counter }`,
+		"messageLimit": 2,
+	})
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Empty(t, result.Err)
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, map[string]interface{}{"counter": json.Number("1")}, result.Events[1].Data)
+
+	// without messageLimit, events are read until the subscription completes
+	res, err = Executor{}.Run(context.Background(), venom.TestStep{
+		"url":   srv.URL,
+		"query": `subscription { counter }`,
+	})
+	require.NoError(t, err)
+	assert.Len(t, res.(Result).Events, 3)
+}
+
+func TestExecutor_Run_Timeout(t *testing.T) {
+	venom.InitTestLogger(t)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"url":     srv.URL,
+		"query":   `{ hello }`,
+		"timeout": 100,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "Client.Timeout exceeded")
+}
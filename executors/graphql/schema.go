@@ -0,0 +1,261 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// introspectionQuery fetches the types, arguments and directives needed to validate an operation
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind
+  name
+  fields(includeDeprecated: true) { name args { ...InputValue } type { ...TypeRef } }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue {
+  name
+  type { ...TypeRef }
+  defaultValue
+}
+fragment TypeRef on __Type {
+  kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } }
+}`
+
+// prelude holds the built-in scalars, directives and introspection types, which are not redefined from an introspection result
+var prelude = gqlparser.MustLoadSchema()
+
+// parseSchema reads a schema either from an introspection result in JSON or from a SDL document
+func parseSchema(content []byte) (*ast.Schema, error) {
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseIntrospection(trimmed)
+	}
+	return parseSDL(string(content))
+}
+
+// parseSDL loads and validates a schema definition document
+func parseSDL(content string) (*ast.Schema, error) {
+	s, err := gqlparser.LoadSchema(&ast.Source{Name: "schema", Input: content})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+// String returns the type reference in SDL notation, like [User!]!
+func (r introspectionTypeRef) String() string {
+	switch {
+	case r.Kind == "NON_NULL" && r.OfType != nil:
+		return r.OfType.String() + "!"
+	case r.Kind == "LIST" && r.OfType != nil:
+		return "[" + r.OfType.String() + "]"
+	default:
+		return r.Name
+	}
+}
+
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionTypeRef `json:"queryType"`
+	MutationType     *introspectionTypeRef `json:"mutationType"`
+	SubscriptionType *introspectionTypeRef `json:"subscriptionType"`
+	Types            []struct {
+		Kind   string `json:"kind"`
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string                    `json:"name"`
+			Args []introspectionInputValue `json:"args"`
+			Type introspectionTypeRef      `json:"type"`
+		} `json:"fields"`
+		InputFields   []introspectionInputValue `json:"inputFields"`
+		Interfaces    []introspectionTypeRef    `json:"interfaces"`
+		EnumValues    []struct{ Name string }   `json:"enumValues"`
+		PossibleTypes []introspectionTypeRef    `json:"possibleTypes"`
+	} `json:"types"`
+	Directives []struct {
+		Name      string                    `json:"name"`
+		Locations []string                  `json:"locations"`
+		Args      []introspectionInputValue `json:"args"`
+	} `json:"directives"`
+}
+
+// parseIntrospection reads the result of the introspection query, with or without its data envelope.
+// The result is written back as a SDL document, so that it is loaded and validated like a schema file.
+func parseIntrospection(content []byte) (*ast.Schema, error) {
+	var doc struct {
+		Schema *introspectionSchema `json:"__schema"`
+		Data   struct {
+			Schema *introspectionSchema `json:"__schema"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, errors.Wrap(err, "unable to read introspection result")
+	}
+	is := doc.Schema
+	if is == nil {
+		is = doc.Data.Schema
+	}
+	if is == nil {
+		return nil, errors.New("introspection result has no __schema")
+	}
+
+	var sdl strings.Builder
+	sdl.WriteString("schema {\n")
+	for _, root := range []struct {
+		operation string
+		ref       *introspectionTypeRef
+	}{{"query", is.QueryType}, {"mutation", is.MutationType}, {"subscription", is.SubscriptionType}} {
+		if root.ref != nil && root.ref.Name != "" {
+			fmt.Fprintf(&sdl, "  %s: %s\n", root.operation, root.ref.Name)
+		}
+	}
+	sdl.WriteString("}\n")
+
+	for _, t := range is.Types {
+		if prelude.Types[t.Name] != nil {
+			continue
+		}
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&sdl, "scalar %s\n", t.Name)
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			fmt.Fprintf(&sdl, "%s %s", keyword, t.Name)
+			for i, itf := range t.Interfaces {
+				if i == 0 {
+					sdl.WriteString(" implements ")
+				} else {
+					sdl.WriteString(" & ")
+				}
+				sdl.WriteString(itf.Name)
+			}
+			sdl.WriteString(" {\n")
+			for _, f := range t.Fields {
+				fmt.Fprintf(&sdl, "  %s%s: %s\n", f.Name, writeArguments(f.Args), f.Type)
+			}
+			sdl.WriteString("}\n")
+		case "UNION":
+			names := make([]string, 0, len(t.PossibleTypes))
+			for _, p := range t.PossibleTypes {
+				names = append(names, p.Name)
+			}
+			fmt.Fprintf(&sdl, "union %s = %s\n", t.Name, strings.Join(names, " | "))
+		case "ENUM":
+			fmt.Fprintf(&sdl, "enum %s {\n", t.Name)
+			for _, v := range t.EnumValues {
+				fmt.Fprintf(&sdl, "  %s\n", v.Name)
+			}
+			sdl.WriteString("}\n")
+		case "INPUT_OBJECT":
+			fmt.Fprintf(&sdl, "input %s {\n", t.Name)
+			for _, f := range t.InputFields {
+				fmt.Fprintf(&sdl, "  %s\n", writeInputValue(f))
+			}
+			sdl.WriteString("}\n")
+		default:
+			return nil, fmt.Errorf("unknown kind %q for type %q in introspection result", t.Kind, t.Name)
+		}
+	}
+
+	for _, d := range is.Directives {
+		if prelude.Directives[d.Name] != nil {
+			continue
+		}
+		fmt.Fprintf(&sdl, "directive @%s%s on %s\n", d.Name, writeArguments(d.Args), strings.Join(d.Locations, " | "))
+	}
+
+	s, err := gqlparser.LoadSchema(&ast.Source{Name: "introspection", Input: sdl.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid schema in introspection result")
+	}
+	return s, nil
+}
+
+func writeArguments(args []introspectionInputValue) string {
+	if len(args) == 0 {
+		return ""
+	}
+	values := make([]string, 0, len(args))
+	for _, a := range args {
+		values = append(values, writeInputValue(a))
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+func writeInputValue(v introspectionInputValue) string {
+	s := v.Name + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		s += " = " + *v.DefaultValue
+	}
+	return s
+}
+
+// parseDocument parses an executable GraphQL document, without validating it against a schema
+func parseDocument(content string) (*ast.QueryDocument, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Name: "query", Input: content})
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Operations) == 0 {
+		return nil, errors.New("query has no operation")
+	}
+	return doc, nil
+}
+
+// operation returns the operation to execute, by its name if the document contains several ones
+func operation(doc *ast.QueryDocument, name string) (*ast.OperationDefinition, error) {
+	if name == "" && len(doc.Operations) > 1 {
+		return nil, errors.New("operationName is mandatory when the query contains several operations")
+	}
+	op := doc.Operations.ForName(name)
+	if op == nil {
+		return nil, fmt.Errorf("operation %q not found in query", name)
+	}
+	return op, nil
+}
+
+// validate checks the document against the schema with the rules of the GraphQL specification:
+// fields, arguments, fragments and their type conditions, variable definitions and usages.
+// The variables sent with the operation are checked against their declared types.
+func validate(s *ast.Schema, doc *ast.QueryDocument, op *ast.OperationDefinition, variables map[string]interface{}) error {
+	if errs := validator.Validate(s, doc); len(errs) > 0 {
+		return errs
+	}
+	if _, err := validator.VariableValues(s, op, variables); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package graphql
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+// subprotocol is the protocol implemented by the graphql-ws library
+const subprotocol = "graphql-transport-ws"
+
+const subscriptionID = "1"
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscribe runs the subscription over websocket and collects its events
+// until messageLimit is reached, the server completes the subscription or the timeout expires
+func (e Executor) subscribe(ctx context.Context) Result {
+	var result Result
+
+	url := e.SubscriptionURL
+	if url == "" {
+		url = e.URL
+		if strings.HasPrefix(url, "http") {
+			url = "ws" + strings.TrimPrefix(url, "http")
+		}
+	}
+	header := http.Header{}
+	for k, v := range e.Headers {
+		header.Set(k, v)
+	}
+	dialer := ws.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: time.Duration(e.Timeout) * time.Millisecond,
+		Subprotocols:     []string{subprotocol},
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: e.IgnoreVerifySSL},
+	}
+
+	venom.Debug(ctx, "subscribing on %s", url)
+	conn, resp, err := dialer.DialContext(ctx, url, header)
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		result.Err = fmt.Sprintf("unable to connect to %s: %v", url, err)
+		return result
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(e.Timeout) * time.Millisecond)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	if err := e.init(conn); err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	result.Events = []Response{}
+	for e.MessageLimit <= 0 || len(result.Events) < e.MessageLimit {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+				if e.MessageLimit > 0 {
+					result.Err = fmt.Sprintf("timeout after receiving %d event(s)", len(result.Events))
+				}
+				return result
+			}
+			result.Err = fmt.Sprintf("unable to read message: %v", err)
+			return result
+		}
+		venom.Debug(ctx, "message received: %s %s", msg.Type, msg.Payload)
+
+		switch msg.Type {
+		case "ping":
+			if err := conn.WriteJSON(wsMessage{Type: "pong"}); err != nil {
+				result.Err = err.Error()
+				return result
+			}
+		case "next":
+			var event Response
+			if err := venom.JSONUnmarshal(msg.Payload, &event); err != nil {
+				result.Err = fmt.Sprintf("unable to read event: %v", err)
+				return result
+			}
+			result.Events = append(result.Events, event)
+		case "error":
+			if err := venom.JSONUnmarshal(msg.Payload, &result.Errors); err != nil {
+				result.Err = fmt.Sprintf("unable to read errors: %v", err)
+			}
+			return result
+		case "complete":
+			return result
+		}
+	}
+
+	_ = conn.WriteJSON(wsMessage{ID: subscriptionID, Type: "complete"})
+	_ = conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	return result
+}
+
+// init initializes the connection and starts the subscription
+func (e Executor) init(conn *ws.Conn) error {
+	params, err := json.Marshal(e.ConnectionParams)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal connectionParams")
+	}
+	if e.ConnectionParams == nil {
+		params = nil
+	}
+	if err := conn.WriteJSON(wsMessage{Type: "connection_init", Payload: params}); err != nil {
+		return errors.Wrap(err, "unable to init connection")
+	}
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return errors.Wrap(err, "connection not acknowledged")
+		}
+		if msg.Type == "connection_ack" {
+			break
+		}
+		if msg.Type == "ping" {
+			if err := conn.WriteJSON(wsMessage{Type: "pong"}); err != nil {
+				return err
+			}
+		}
+	}
+
+	payload, err := json.Marshal(request{Query: e.Query, Variables: e.Variables, OperationName: e.OperationName})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal request")
+	}
+	if err := conn.WriteJSON(wsMessage{ID: subscriptionID, Type: "subscribe", Payload: payload}); err != nil {
+		return errors.Wrap(err, "unable to subscribe")
+	}
+	return nil
+}
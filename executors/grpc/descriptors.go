@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+
+	"github.com/ovh/venom"
+)
+
+// descriptorSources caches the descriptors compiled from local files, per test suite file, until the end of the test suite
+var descriptorSources = struct {
+	sync.Mutex
+	sources map[string]map[string]grpcurl.DescriptorSource
+}{sources: map[string]map[string]grpcurl.DescriptorSource{}}
+
+// localDescriptorSource returns the descriptors of the proto_files or of the protoset files.
+// Relative paths are resolved from the directory of the test suite.
+func (e Executor) localDescriptorSource(ctx context.Context) (grpcurl.DescriptorSource, error) {
+	if len(e.Protoset) > 0 && len(e.ProtoFiles) > 0 {
+		return nil, fmt.Errorf("protoset and proto_files cannot be used together")
+	}
+	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(workdir, path)
+	}
+
+	importPaths := make([]string, 0, len(e.ImportPaths))
+	for _, p := range e.ImportPaths {
+		importPaths = append(importPaths, abs(p))
+	}
+	if len(importPaths) == 0 {
+		importPaths = []string{workdir}
+	}
+	protosets := make([]string, 0, len(e.Protoset))
+	for _, p := range e.Protoset {
+		protosets = append(protosets, abs(p))
+	}
+
+	suite := venom.StringVarFromCtx(ctx, "venom.testsuite.filepath")
+	key := strings.Join([]string{
+		strings.Join(importPaths, ","),
+		strings.Join(e.ProtoFiles, ","),
+		strings.Join(protosets, ","),
+	}, "|")
+
+	descriptorSources.Lock()
+	defer descriptorSources.Unlock()
+	suiteSources, ok := descriptorSources.sources[suite]
+	if !ok {
+		suiteSources = map[string]grpcurl.DescriptorSource{}
+		// outside of a test suite, the descriptors are not cached
+		if venom.OnTestSuiteEnd(ctx, func() { releaseDescriptorSources(suite) }) {
+			descriptorSources.sources[suite] = suiteSources
+		}
+	}
+	if source, ok := suiteSources[key]; ok {
+		return source, nil
+	}
+
+	var source grpcurl.DescriptorSource
+	var err error
+	if len(protosets) > 0 {
+		source, err = grpcurl.DescriptorSourceFromProtoSets(protosets...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load protoset files: %w", err)
+		}
+	} else {
+		source, err = compileProtoFiles(ctx, importPaths, e.ProtoFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+	venom.Debug(ctx, "descriptors loaded from local files %s", key)
+	suiteSources[key] = source
+	return source, nil
+}
+
+// releaseDescriptorSources removes the descriptors cached for the test suite
+func releaseDescriptorSources(suite string) {
+	descriptorSources.Lock()
+	defer descriptorSources.Unlock()
+	delete(descriptorSources.sources, suite)
+}
+
+// compileProtoFiles compiles the proto files, their names are relative to one of the import paths
+func compileProtoFiles(ctx context.Context, importPaths, protoFiles []string) (grpcurl.DescriptorSource, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	files, err := compiler.Compile(ctx, protoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile proto files: %w", err)
+	}
+	fds := make([]*desc.FileDescriptor, 0, len(files))
+	for _, f := range files {
+		fd, err := desc.WrapFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load %s: %w", f.Path(), err)
+		}
+		fds = append(fds, fd)
+	}
+	return grpcurl.DescriptorSourceFromFileDescriptors(fds...)
+}
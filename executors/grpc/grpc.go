@@ -8,9 +8,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fullstorydev/grpcurl"
@@ -19,6 +22,7 @@ import (
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/mitchellh/mapstructure"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -36,36 +40,63 @@ func New() venom.Executor {
 
 // Executor represents a Test Exec
 type Executor struct {
-	URL                  string                 `json:"url" yaml:"url"`
-	Service              string                 `json:"service" yaml:"service"`
-	Method               string                 `json:"method" yaml:"method"`
-	JSONDefaultFields    bool                   `json:"default_fields" yaml:"default_fields"`
-	IncludeTextSeparator bool                   `json:"include_text_separator" yaml:"include_text_separator"`
-	Data                 map[string]interface{} `json:"data" yaml:"data"`
-	Headers              map[string]string      `json:"headers" yaml:"headers"`
-	ConnectTimeout       *int64                 `json:"connect_timeout" yaml:"connect_timeout"`
-	TLSClientCert        string                 `json:"tls_client_cert" yaml:"tls_client_cert" mapstructure:"tls_client_cert"`
-	TLSClientKey         string                 `json:"tls_client_key" yaml:"tls_client_key" mapstructure:"tls_client_key"`
-	TLSRootCA            string                 `json:"tls_root_ca" yaml:"tls_root_ca" mapstructure:"tls_root_ca"`
-	IgnoreVerifySSL      bool                   `json:"ignore_verify_ssl" yaml:"ignore_verify_ssl" mapstructure:"ignore_verify_ssl"`
+	URL                  string            `json:"url" yaml:"url"`
+	Service              string            `json:"service" yaml:"service"`
+	Method               string            `json:"method" yaml:"method"`
+	JSONDefaultFields    bool              `json:"default_fields" yaml:"default_fields"`
+	IncludeTextSeparator bool              `json:"include_text_separator" yaml:"include_text_separator"`
+	Data                 interface{}       `json:"data" yaml:"data"`
+	Headers              map[string]string `json:"headers" yaml:"headers"`
+	ConnectTimeout       *int64            `json:"connect_timeout" yaml:"connect_timeout"`
+	TLSClientCert        string            `json:"tls_client_cert" yaml:"tls_client_cert" mapstructure:"tls_client_cert"`
+	TLSClientKey         string            `json:"tls_client_key" yaml:"tls_client_key" mapstructure:"tls_client_key"`
+	TLSRootCA            string            `json:"tls_root_ca" yaml:"tls_root_ca" mapstructure:"tls_root_ca"`
+	IgnoreVerifySSL      bool              `json:"ignore_verify_ssl" yaml:"ignore_verify_ssl" mapstructure:"ignore_verify_ssl"`
+	MessageLimit         int               `json:"message_limit" yaml:"message_limit" mapstructure:"message_limit"`
+	StreamTimeout        int64             `json:"stream_timeout" yaml:"stream_timeout" mapstructure:"stream_timeout"`
+	Interleave           bool              `json:"interleave" yaml:"interleave"`
+	ProtoFiles           []string          `json:"proto_files" yaml:"proto_files" mapstructure:"proto_files"`
+	ImportPaths          []string          `json:"import_paths" yaml:"import_paths" mapstructure:"import_paths"`
+	Protoset             []string          `json:"protoset" yaml:"protoset"`
 }
 
 // Result represents a step result
 type Result struct {
-	Systemout     string        `json:"systemout,omitempty" yaml:"systemout,omitempty"`
-	SystemoutJSON interface{}   `json:"systemoutjson,omitempty" yaml:"systemoutjson,omitempty"`
-	Systemerr     string        `json:"systemerr,omitempty" yaml:"systemerr,omitempty"`
-	SystemerrJSON interface{}   `json:"systemerrjson,omitempty" yaml:"systemerrjson,omitempty"`
-	Err           string        `json:"err,omitempty" yaml:"err,omitempty"`
-	Code          string        `json:"code,omitempty" yaml:"code,omitempty"`
-	Details       []interface{} `json:"details,omitempty" yaml:"details,omitempty"`
-	TimeSeconds   float64       `json:"timeseconds,omitempty" yaml:"timeseconds,omitempty"`
+	Systemout     string            `json:"systemout,omitempty" yaml:"systemout,omitempty"`
+	SystemoutJSON interface{}       `json:"systemoutjson,omitempty" yaml:"systemoutjson,omitempty"`
+	Systemerr     string            `json:"systemerr,omitempty" yaml:"systemerr,omitempty"`
+	SystemerrJSON interface{}       `json:"systemerrjson,omitempty" yaml:"systemerrjson,omitempty"`
+	Err           string            `json:"err,omitempty" yaml:"err,omitempty"`
+	Code          string            `json:"code,omitempty" yaml:"code,omitempty"`
+	Details       []interface{}     `json:"details,omitempty" yaml:"details,omitempty"`
+	TimeSeconds   float64           `json:"timeseconds,omitempty" yaml:"timeseconds,omitempty"`
+	Responses     []Response        `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Trailers      map[string]string `json:"trailers,omitempty" yaml:"trailers,omitempty"`
+}
+
+// Response represents a response message, in the order it has been received
+type Response struct {
+	Message     string      `json:"message" yaml:"message"`
+	MessageJSON interface{} `json:"messagejson,omitempty" yaml:"messagejson,omitempty"`
+	Timestamp   string      `json:"timestamp" yaml:"timestamp"`
+	TimeSeconds float64     `json:"timeseconds" yaml:"timeseconds"`
 }
 
 type customHandler struct {
 	formatter grpcurl.Formatter
 	target    *Result
 	err       error
+
+	start time.Time
+	// limit is the number of responses after which the stream is stopped by calling stop
+	limit   int
+	stop    context.CancelFunc
+	stopped bool
+
+	mu sync.Mutex
+	// received is closed and replaced each time a response is received
+	received chan struct{}
 }
 
 // OnResolveMethod is called with a descriptor of the method that is being invoked.
@@ -75,20 +106,61 @@ func (*customHandler) OnResolveMethod(m *desc.MethodDescriptor) {}
 func (*customHandler) OnSendHeaders(metadata.MD) {}
 
 // OnReceiveHeaders is called when response headers have been received.
-func (*customHandler) OnReceiveHeaders(m metadata.MD) {}
+func (c *customHandler) OnReceiveHeaders(m metadata.MD) {
+	c.target.Headers = flattenMetadata(m)
+}
 
 // OnReceiveResponse is called for each response message received.
 func (c *customHandler) OnReceiveResponse(msg proto.Message) {
+	now := time.Now()
 	res, err := c.formatter(msg)
 	if err != nil || c.err != nil {
 		c.err = err
 		return
 	}
 	c.target.Systemout = res
+
+	response := Response{
+		Message:     res,
+		Timestamp:   now.Format(time.RFC3339Nano),
+		TimeSeconds: now.Sub(c.start).Seconds(),
+	}
+	var resJSON interface{}
+	if err := venom.JSONUnmarshal([]byte(res), &resJSON); err == nil {
+		response.MessageJSON = resJSON
+	}
+
+	c.mu.Lock()
+	c.target.Responses = append(c.target.Responses, response)
+	close(c.received)
+	c.received = make(chan struct{})
+	if c.limit > 0 && len(c.target.Responses) >= c.limit && !c.stopped {
+		c.stopped = true
+		c.stop()
+	}
+	c.mu.Unlock()
+}
+
+// waitResponses waits until n responses have been received, or ctx is done
+func (c *customHandler) waitResponses(ctx context.Context, n int) bool {
+	for {
+		c.mu.Lock()
+		count, received := len(c.target.Responses), c.received
+		c.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-received:
+		case <-ctx.Done():
+			return false
+		}
+	}
 }
 
 // OnReceiveTrailers is called when response trailers and final RPC status have been received.
 func (c *customHandler) OnReceiveTrailers(stat *status.Status, met metadata.MD) {
+	c.target.Trailers = flattenMetadata(met)
 	if err := stat.Err(); err != nil {
 		c.target.Systemerr = err.Error()
 
@@ -108,6 +180,18 @@ func (c *customHandler) OnReceiveTrailers(stat *status.Status, met metadata.MD)
 	c.target.Code = strconv.Itoa(int(uint32(stat.Code())))
 }
 
+// flattenMetadata joins the values of each key with a comma
+func flattenMetadata(m metadata.MD) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = strings.Join(v, ",")
+	}
+	return res
+}
+
 // ZeroValueResult return an empty implementation of this executor result
 func (Executor) ZeroValueResult() interface{} {
 	return Result{}
@@ -132,10 +216,22 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 		headers = append(headers, fmt.Sprintf("%s: %s", k, v))
 	}
 
-	// prepare data
-	data, err := json.Marshal(e.Data)
-	if err != nil {
-		return nil, fmt.Errorf("runGrpcurl: Cannot marshal request data: %s", err)
+	// prepare data, a list of messages is sent as a stream
+	messages, ok := e.Data.([]interface{})
+	if !ok {
+		messages = []interface{}{e.Data}
+	}
+	var data []byte
+	for _, m := range messages {
+		if m == nil {
+			continue
+		}
+		btes, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("runGrpcurl: Cannot marshal request data: %s", err)
+		}
+		data = append(data, btes...)
+		data = append(data, '\n')
 	}
 
 	result := Result{}
@@ -236,12 +332,21 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	var refClient *grpcreflect.Client
 	md := grpcurl.MetadataFromHeaders(headers)
 	refCtx := metadata.NewOutgoingContext(ctx, md)
-	cc, err = dial()
+	cc, err := dial()
 	if err != nil {
 		return Result{Err: err.Error()}, fmt.Errorf("grpc dial error: %w", err)
 	}
-	refClient = grpcreflect.NewClientAuto(refCtx, cc)
-	descSource = grpcurl.DescriptorSourceFromServer(ctx, refClient)
+	// use the local descriptors if any, the server reflection otherwise
+	if len(e.ProtoFiles) > 0 || len(e.Protoset) > 0 {
+		descSource, err = e.localDescriptorSource(ctx)
+		if err != nil {
+			_ = cc.Close()
+			return Result{Err: err.Error()}, err
+		}
+	} else {
+		refClient = grpcreflect.NewClientAuto(refCtx, cc)
+		descSource = grpcurl.DescriptorSourceFromServer(ctx, refClient)
+	}
 
 	// arrange for the RPCs to be cleanly shutdown
 	defer func() {
@@ -268,19 +373,52 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 		return nil, fmt.Errorf("failed to construct request parser and formatter %s", err)
 	}
 
+	// the stream is stopped once message_limit responses are received, or after stream_timeout
+	var invokeCtx context.Context
+	var stop context.CancelFunc
+	if e.StreamTimeout > 0 {
+		invokeCtx, stop = context.WithTimeout(ctx, time.Duration(e.StreamTimeout)*time.Millisecond)
+	} else {
+		invokeCtx, stop = context.WithCancel(ctx)
+	}
+	defer stop()
+
 	// prepare custom handler to handle response
 	handle := customHandler{
-		formatter,
-		&result,
-		nil,
+		formatter: formatter,
+		target:    &result,
+		start:     start,
+		limit:     e.MessageLimit,
+		stop:      stop,
+		received:  make(chan struct{}),
+	}
+
+	// with interleave, each request message waits for the response to the previous one
+	var sent int
+	next := func(m proto.Message) error {
+		if e.Interleave && sent > 0 && !handle.waitResponses(invokeCtx, sent) {
+			return io.EOF
+		}
+		if err := rf.Next(m); err != nil {
+			return err
+		}
+		sent++
+		return nil
 	}
 
 	// invoke the gRPC
-	err = grpcurl.InvokeRPC(ctx, descSource, cc, e.Service+"/"+e.Method, headers, &handle, rf.Next)
+	err = grpcurl.InvokeRPC(invokeCtx, descSource, cc, e.Service+"/"+e.Method, headers, &handle, next)
 	if err != nil {
 		return nil, fmt.Errorf("grpcurl.InvokeRPC() failed.\nUrl: %q\nService: %q\nMethod: %q\nData:%v: %v", e.URL, e.Service, e.Method, e.Data, err)
 	}
 
+	// a stream stopped by message_limit or stream_timeout is not an error
+	if handle.stopped || (e.StreamTimeout > 0 && invokeCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil) {
+		result.Systemerr = ""
+		result.Details = nil
+		result.Code = strconv.Itoa(int(codes.OK))
+	}
+
 	elapsed := time.Since(start)
 	result.TimeSeconds = elapsed.Seconds()
 
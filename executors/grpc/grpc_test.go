@@ -0,0 +1,238 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/ovh/venom"
+)
+
+func newServer(t *testing.T) (string, *health.Server) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	reflection.Register(srv)
+	go srv.Serve(l) // nolint
+	t.Cleanup(srv.Stop)
+	return l.Addr().String(), hs
+}
+
+func TestExecutor_Run_Unary(t *testing.T) {
+	venom.InitTestLogger(t)
+	url, _ := newServer(t)
+
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"url":     url,
+		"service": "grpc.health.v1.Health",
+		"method":  "Check",
+		"data":    map[string]interface{}{"service": ""},
+	})
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Equal(t, "0", result.Code)
+	require.Len(t, result.Responses, 1)
+	assert.Equal(t, map[string]interface{}{"status": "SERVING"}, result.SystemoutJSON)
+	assert.Equal(t, map[string]interface{}{"status": "SERVING"}, result.Responses[0].MessageJSON)
+	assert.NotEmpty(t, result.Responses[0].Timestamp)
+}
+
+func TestExecutor_Run_ServerStream(t *testing.T) {
+	venom.InitTestLogger(t)
+	url, hs := newServer(t)
+	hs.SetServingStatus("payments", healthpb.HealthCheckResponse_SERVING)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		hs.SetServingStatus("payments", healthpb.HealthCheckResponse_NOT_SERVING)
+	}()
+
+	step := venom.TestStep{
+		"url":           url,
+		"service":       "grpc.health.v1.Health",
+		"method":        "Watch",
+		"data":          map[string]interface{}{"service": "payments"},
+		"message_limit": 2,
+	}
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Equal(t, "0", result.Code)
+	assert.Empty(t, result.Systemerr)
+	require.Len(t, result.Responses, 2)
+	assert.Equal(t, map[string]interface{}{"status": "SERVING"}, result.Responses[0].MessageJSON)
+	assert.Equal(t, map[string]interface{}{"status": "NOT_SERVING"}, result.Responses[1].MessageJSON)
+	assert.True(t, result.Responses[0].TimeSeconds <= result.Responses[1].TimeSeconds)
+
+	// without a limit, the stream is read until the timeout
+	delete(step, "message_limit")
+	step["stream_timeout"] = 1000
+	res, err = Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result = res.(Result)
+	assert.Equal(t, "0", result.Code)
+	require.Len(t, result.Responses, 1)
+	assert.Equal(t, map[string]interface{}{"status": "NOT_SERVING"}, result.SystemoutJSON)
+}
+
+func TestExecutor_Run_BidiStream(t *testing.T) {
+	venom.InitTestLogger(t)
+	url, _ := newServer(t)
+
+	for _, interleave := range []bool{false, true} {
+		res, err := Executor{}.Run(context.Background(), venom.TestStep{
+			"url":     url,
+			"service": "grpc.reflection.v1.ServerReflection",
+			"method":  "ServerReflectionInfo",
+			"data": []interface{}{
+				map[string]interface{}{"listServices": ""},
+				map[string]interface{}{"fileContainingSymbol": "grpc.health.v1.Health"},
+				map[string]interface{}{"listServices": ""},
+			},
+			"interleave": interleave,
+		})
+		require.NoError(t, err)
+		result := res.(Result)
+		assert.Equal(t, "0", result.Code)
+		require.Len(t, result.Responses, 3)
+		assert.Contains(t, result.Responses[0].Message, "listServicesResponse")
+		assert.Contains(t, result.Responses[1].Message, "fileDescriptorResponse")
+		assert.Contains(t, result.Responses[2].Message, "listServicesResponse")
+	}
+}
+
+const healthProto = `syntax = "proto3";
+
+package grpc.health.v1;
+
+message HealthCheckRequest {
+  string service = 1;
+}
+
+message HealthCheckResponse {
+  enum ServingStatus {
+    UNKNOWN = 0;
+    SERVING = 1;
+    NOT_SERVING = 2;
+  }
+  ServingStatus status = 1;
+}
+
+service Health {
+  rpc Check(HealthCheckRequest) returns (HealthCheckResponse);
+}
+`
+
+func TestExecutor_Run_LocalDescriptors(t *testing.T) {
+	venom.InitTestLogger(t)
+
+	// a server without reflection, setting response headers and trailers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "42"))
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("x-cost", "1", "x-cost", "2"))
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(l) // nolint
+	t.Cleanup(srv.Stop)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "protos", "health"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "protos", "health", "health.proto"), []byte(healthProto), 0o644))
+	fdp := protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto)
+	btes, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fdp}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "health.protoset"), btes, 0o644))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), dir)
+
+	step := venom.TestStep{
+		"url":     l.Addr().String(),
+		"service": "grpc.health.v1.Health",
+		"method":  "Check",
+		"data":    map[string]interface{}{"service": ""},
+	}
+
+	_, err = Executor{}.Run(ctx, step)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server does not support the reflection API")
+
+	for name, attrs := range map[string]venom.TestStep{
+		"proto_files": {"proto_files": []string{"health/health.proto"}, "import_paths": []string{"protos"}},
+		"protoset":    {"protoset": []string{"health.protoset"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := venom.TestStep{}
+			for k, v := range step {
+				s[k] = v
+			}
+			for k, v := range attrs {
+				s[k] = v
+			}
+			res, err := Executor{}.Run(ctx, s)
+			require.NoError(t, err)
+			result := res.(Result)
+			assert.Equal(t, "0", result.Code)
+			assert.Equal(t, map[string]interface{}{"status": "SERVING"}, result.SystemoutJSON)
+			assert.Equal(t, "42", result.Headers["x-request-id"])
+			assert.Equal(t, "1,2", result.Trailers["x-cost"])
+		})
+	}
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"url": l.Addr().String(), "proto_files": []string{"unknown.proto"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to compile proto files")
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"url": l.Addr().String(), "proto_files": []string{"health/health.proto"}, "protoset": []string{"health.protoset"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protoset and proto_files cannot be used together")
+}
+
+func TestExecutor_LocalDescriptorSource_Cache(t *testing.T) {
+	venom.InitTestLogger(t)
+	dir := t.TempDir()
+	fdp := protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto)
+	btes, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fdp}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "health.protoset"), btes, 0o644))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), dir)
+	ctx = context.WithValue(ctx, venom.ContextKey("var.venom.testsuite.filepath"), filepath.Join(dir, "grpc.yml"))
+	e := Executor{Protoset: []string{"health.protoset"}}
+
+	// outside of a testsuite, the descriptors are loaded for each step
+	source, err := e.localDescriptorSource(ctx)
+	require.NoError(t, err)
+	again, err := e.localDescriptorSource(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, source, again)
+	assert.Empty(t, descriptorSources.sources)
+
+	// in a testsuite, they are shared by its steps
+	suiteCtx, endSuite := venom.WithTestSuiteEnd(ctx)
+	source, err = e.localDescriptorSource(suiteCtx)
+	require.NoError(t, err)
+	again, err = e.localDescriptorSource(suiteCtx)
+	require.NoError(t, err)
+	assert.Same(t, source, again)
+	assert.Len(t, descriptorSources.sources, 1)
+
+	// and released at its end
+	endSuite()
+	assert.Empty(t, descriptorSources.sources)
+}
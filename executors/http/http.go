@@ -3,7 +3,9 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,10 +21,11 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mitchellh/mapstructure"
-	"github.com/ovh/cds/sdk/interpolate"
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/interpolate"
 	"github.com/ovh/venom/reporting"
 	libopenapi "github.com/pb33f/libopenapi"
 	validator "github.com/pb33f/libopenapi-validator"
@@ -71,6 +74,7 @@ type Executor struct {
 	TLSClientCert     string            `json:"tls_client_cert" yaml:"tls_client_cert" mapstructure:"tls_client_cert"`
 	TLSClientKey      string            `json:"tls_client_key" yaml:"tls_client_key" mapstructure:"tls_client_key"`
 	TLSRootCA         string            `json:"tls_root_ca" yaml:"tls_root_ca" mapstructure:"tls_root_ca"`
+	OutputFile        string            `json:"output_file" yaml:"output_file" mapstructure:"output_file"`
 }
 
 // Result represents a step result. Json and yaml descriptor are used for json output
@@ -80,11 +84,21 @@ type Result struct {
 	Request     HTTPRequest `json:"request,omitempty" yaml:"request,omitempty"`
 	Body        string      `json:"body,omitempty" yaml:"body,omitempty"`
 	BodyJSON    interface{} `json:"bodyjson,omitempty" yaml:"bodyjson,omitempty"`
+	BodySize    int64       `json:"bodysize,omitempty" yaml:"bodysize,omitempty"`
+	File        *File       `json:"file,omitempty" yaml:"file,omitempty"`
 	Headers     Headers     `json:"headers,omitempty" yaml:"headers,omitempty"`
 	Err         string      `json:"err,omitempty" yaml:"err,omitempty"`
 	Systemout   string      `json:"systemout,omitempty" yaml:"systemout,omitempty"`
 }
 
+// File describes the response body written to output_file
+type File struct {
+	Path        string `json:"path,omitempty" yaml:"path,omitempty"`
+	Size        int64  `json:"size" yaml:"size"`
+	SHA256      string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	ContentType string `json:"contenttype,omitempty" yaml:"contenttype,omitempty"`
+}
+
 type HTTPRequest struct {
 	Method   string      `json:"method,omitempty"`
 	URL      string      `json:"url,omitempty"`
@@ -149,6 +163,11 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 		venom.Debug(ctx, "MultipartForm detected, removed 'Content-Type' header")
 	}
 
+	// output_file is checked before sending the request, the file is only written under the workdir
+	if e.OutputFile != "" && !filepath.IsLocal(e.OutputFile) {
+		return nil, fmt.Errorf("output_file %s must be a relative path inside the testsuite directory", e.OutputFile)
+	}
+
 	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
 
 	req, err := e.getRequest(ctx, workdir)
@@ -297,12 +316,23 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	if resp.Body != nil {
 		defer resp.Body.Close()
 
-		if !e.SkipBody && isBodySupported(resp) {
+		if e.OutputFile != "" {
+			result.File, err = writeOutputFile(resp, e.OutputFile, workdir)
+			if err != nil {
+				return nil, err
+			}
+			result.BodySize = result.File.Size
+		} else if !e.SkipBody && isBodySupported(resp) {
 			var err error
 			bb, err = io.ReadAll(resp.Body)
 			if err != nil {
 				return nil, err
 			}
+			result.BodySize = int64(len(bb))
+			// binary bodies are not inlined in the results, and so in the vars and the reports
+			if isBinary(bb) {
+				bb = nil
+			}
 			result.Body = string(bb)
 
 			if isBodyJSONSupported(resp) {
@@ -315,6 +345,11 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 			}
 
 			result.Systemout = buildResultInfo(&result, resp)
+		} else if !e.SkipBody {
+			result.BodySize, err = io.Copy(io.Discard, resp.Body)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -333,7 +368,7 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	// if PreserveBodyFile == true, the body is not interpolated.
 	// So, no need to keep it in request here (to re-inject it in vars)
 	// this will avoid to be interpolated after in vars too.
-	if e.PreserveBodyFile || !isContentTypeSupported(requestContentType) {
+	if e.PreserveBodyFile || !isContentTypeSupported(requestContentType) || isBinary([]byte(result.Request.Body)) {
 		result.Request.Body = ""
 	}
 
@@ -481,6 +516,63 @@ func (e Executor) getRequest(ctx context.Context, workdir string) (*http.Request
 	return req, err
 }
 
+// writeOutputFile streams the response body to the file, relative to the workdir
+func writeOutputFile(resp *http.Response, outputFile, workdir string) (*File, error) {
+	path := filepath.Join(workdir, outputFile)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create directory of output_file %s: %v", path, err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create output_file %s: %v", path, err)
+	}
+
+	// the first bytes are kept to detect the content type
+	head := &headBuffer{max: 512}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h, head), resp.Body)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to write output_file %s: %v", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write output_file %s: %v", path, err)
+	}
+
+	contentType := parseContentType(resp.Header.Get("Content-Type"))
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = parseContentType(http.DetectContentType(head.Bytes()))
+	}
+	return &File{
+		Path:        path,
+		Size:        size,
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+		ContentType: contentType,
+	}, nil
+}
+
+// headBuffer keeps the first max bytes written to it
+type headBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// isBinary returns true if the body is not valid UTF-8 text
+func isBinary(body []byte) bool {
+	return bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body)
+}
+
 // writeFile writes the content of the file to an io.Writer
 func writeFile(part io.Writer, filename string) error {
 	file, err := os.Open(filename)
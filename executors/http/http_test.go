@@ -2,12 +2,15 @@ package http
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 
@@ -128,6 +131,7 @@ func TestInterpolation_without_match_Of_String(t *testing.T) {
 }
 
 func TestCookieRedirect(t *testing.T) {
+	venom.InitTestLogger(t)
 	callCount := atomic.Int32{}
 	ctx := context.Background()
 
@@ -177,3 +181,76 @@ func TestCookieRedirect(t *testing.T) {
 
 	require.Equal(t, int32(1), callCount.Load())
 }
+
+func TestOutputFile(t *testing.T) {
+	venom.InitTestLogger(t)
+	content := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(srv.Close)
+
+	workdir := t.TempDir()
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), workdir)
+
+	e := &Executor{}
+	res, err := e.Run(ctx, venom.TestStep{
+		"url":         srv.URL,
+		"output_file": "downloads/image.png",
+	})
+	require.NoError(t, err)
+
+	result, ok := res.(Result)
+	require.True(t, ok)
+	require.Empty(t, result.Body)
+	require.NotNil(t, result.File)
+	require.Equal(t, filepath.Join(workdir, "downloads", "image.png"), result.File.Path)
+	require.Equal(t, int64(len(content)), result.File.Size)
+	sum := sha256.Sum256(content)
+	require.Equal(t, hex.EncodeToString(sum[:]), result.File.SHA256)
+	require.Equal(t, "image/png", result.File.ContentType)
+
+	written, err := os.ReadFile(result.File.Path)
+	require.NoError(t, err)
+	require.Equal(t, content, written)
+}
+
+func TestOutputFileOutsideWorkdir(t *testing.T) {
+	venom.InitTestLogger(t)
+	var callCount atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), t.TempDir())
+	for _, outputFile := range []string{"../image.png", "downloads/../../image.png", filepath.Join(t.TempDir(), "image.png")} {
+		e := &Executor{}
+		_, err := e.Run(ctx, venom.TestStep{
+			"url":         srv.URL,
+			"output_file": outputFile,
+		})
+		require.Error(t, err, outputFile)
+		require.Contains(t, err.Error(), "must be a relative path inside the testsuite directory")
+	}
+	require.Equal(t, int32(0), callCount.Load())
+}
+
+func TestBinaryBody(t *testing.T) {
+	venom.InitTestLogger(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte{0xff, 0x00, 0xfe})
+	}))
+	t.Cleanup(srv.Close)
+
+	e := &Executor{}
+	res, err := e.Run(context.Background(), venom.TestStep{"url": srv.URL})
+	require.NoError(t, err)
+
+	result, ok := res.(Result)
+	require.True(t, ok)
+	require.Empty(t, result.Body)
+	require.Equal(t, int64(3), result.BodySize)
+}
@@ -0,0 +1,124 @@
+// Package background buffers the messages received by consumers running in the background,
+// so that they are started by a step and collected by the next ones of the testcase.
+package background
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Consumer buffers the messages received by a consumer running in the background
+type Consumer[T any] struct {
+	mutex    sync.Mutex
+	messages []T
+	err      error
+	// received is closed and replaced each time a message is received or the consumer fails
+	received chan struct{}
+	stop     func()
+	once     sync.Once
+}
+
+// NewConsumer returns a consumer, stop is called once to stop it and must wait for it to be stopped
+func NewConsumer[T any](stop func()) *Consumer[T] {
+	return &Consumer[T]{received: make(chan struct{}), stop: stop}
+}
+
+// Push buffers a received message
+func (c *Consumer[T]) Push(message T) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.messages = append(c.messages, message)
+	close(c.received)
+	c.received = make(chan struct{})
+}
+
+// Fail records the error which stopped the consumer, it is returned by the next collect
+func (c *Consumer[T]) Fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	close(c.received)
+	c.received = make(chan struct{})
+}
+
+// Collect waits until at least limit messages are buffered, or timeout.
+// It returns and removes all the buffered messages.
+func (c *Consumer[T]) Collect(ctx context.Context, limit int, timeout time.Duration) ([]T, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		c.mutex.Lock()
+		if len(c.messages) >= limit || c.err != nil {
+			messages, err := c.messages, c.err
+			c.messages = nil
+			c.mutex.Unlock()
+			return messages, err
+		}
+		received := c.received
+		c.mutex.Unlock()
+
+		select {
+		case <-received:
+		case <-timer.C:
+			c.mutex.Lock()
+			messages := c.messages
+			c.messages = nil
+			c.mutex.Unlock()
+			return messages, fmt.Errorf("%d messages received after %v, expected %d", len(messages), timeout, limit)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Stop stops the consumer
+func (c *Consumer[T]) Stop() {
+	c.once.Do(c.stop)
+}
+
+// Consumers are the consumers running in the background for a testcase, by name
+type Consumers[T any] struct {
+	mutex     sync.Mutex
+	consumers map[string]*Consumer[T]
+}
+
+// NewConsumers returns an empty set of consumers
+func NewConsumers[T any]() *Consumers[T] {
+	return &Consumers[T]{consumers: map[string]*Consumer[T]{}}
+}
+
+// Add adds a started consumer
+func (c *Consumers[T]) Add(name string, consumer *Consumer[T]) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.consumers[name]; ok {
+		return fmt.Errorf("background consumer %q is already started", name)
+	}
+	c.consumers[name] = consumer
+	return nil
+}
+
+// Get returns a started consumer
+func (c *Consumers[T]) Get(name string) (*Consumer[T], error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	consumer, ok := c.consumers[name]
+	if !ok {
+		return nil, fmt.Errorf("background consumer %q is not started", name)
+	}
+	return consumer, nil
+}
+
+// StopAll stops all the consumers
+func (c *Consumers[T]) StopAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for name, consumer := range c.consumers {
+		consumer.Stop()
+		delete(c.consumers, name)
+	}
+}
@@ -0,0 +1,60 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_Collect(t *testing.T) {
+	stopped := 0
+	c := NewConsumer[string](func() { stopped++ })
+
+	c.Push("a")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.Push("b")
+	}()
+	messages, err := c.Collect(context.Background(), 2, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, messages)
+
+	// the collected messages are removed from the buffer
+	messages, err = c.Collect(context.Background(), 0, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	c.Push("c")
+	messages, err = c.Collect(context.Background(), 2, 20*time.Millisecond)
+	require.Error(t, err)
+	assert.Equal(t, "1 messages received after 20ms, expected 2", err.Error())
+	assert.Equal(t, []string{"c"}, messages)
+
+	c.Fail(errors.New("connection lost"))
+	_, err = c.Collect(context.Background(), 1, time.Second)
+	require.EqualError(t, err, "connection lost")
+
+	c.Stop()
+	c.Stop()
+	assert.Equal(t, 1, stopped)
+}
+
+func TestConsumers(t *testing.T) {
+	stopped := 0
+	consumers := NewConsumers[string]()
+	require.NoError(t, consumers.Add("orders", NewConsumer[string](func() { stopped++ })))
+	require.EqualError(t, consumers.Add("orders", NewConsumer[string](nil)), `background consumer "orders" is already started`)
+
+	c, err := consumers.Get("orders")
+	require.NoError(t, err)
+	assert.NotNil(t, c)
+
+	consumers.StopAll()
+	assert.Equal(t, 1, stopped)
+	_, err = consumers.Get("orders")
+	require.EqualError(t, err, `background consumer "orders" is not started`)
+}
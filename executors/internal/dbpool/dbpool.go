@@ -0,0 +1,200 @@
+// Package dbpool shares named database connection pools between the sql and dbfixtures steps.
+// The connections are declared once in the sql.connections variable of the testsuite:
+//
+//	vars:
+//	  sql.connections:
+//	    main:
+//	      driver: postgres
+//	      dsn: "user=venom password=venom dbname=venom host=localhost sslmode=disable"
+//	      maxOpenConns: 5
+package dbpool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/ovh/venom"
+)
+
+// VarName is the testsuite variable declaring the connections
+const VarName = "sql.connections"
+
+// ContextKey is the key of the connections in the testcase context
+const ContextKey = venom.ContextKey("sqlConnections")
+
+// Connection is the declaration of a named connection
+type Connection struct {
+	Driver       string `json:"driver" yaml:"driver"`
+	DSN          string `json:"dsn" yaml:"dsn"`
+	MaxOpenConns int    `json:"maxOpenConns,omitempty" yaml:"maxOpenConns,omitempty"`
+	MaxIdleConns int    `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	// ConnMaxLifetime and ConnMaxIdleTime of the pooled connections, in seconds
+	ConnMaxLifetime int `json:"connMaxLifetime,omitempty" yaml:"connMaxLifetime,omitempty"`
+	ConnMaxIdleTime int `json:"connMaxIdleTime,omitempty" yaml:"connMaxIdleTime,omitempty"`
+}
+
+// The pools of each testsuite, by its file, are shared by its testcases and closed at its end
+var (
+	poolsMutex sync.Mutex
+	pools      = map[string]map[Connection]*sqlx.DB{}
+)
+
+// Connections are the connections declared for a testcase
+type Connections struct {
+	declared map[string]Connection
+	mutex    sync.Mutex
+	used     map[string]*sqlx.DB
+	// suite is the testsuite of the pools to close on TearDown, when the connections are used outside of a testsuite
+	suite *string
+}
+
+// Setup reads the connections declared in the variables and adds them to the context
+func Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	declared, err := parse(vars[VarName])
+	if err != nil {
+		return ctx, fmt.Errorf("invalid %s variable: %w", VarName, err)
+	}
+	if len(declared) == 0 {
+		return ctx, nil
+	}
+	// the connections are shared by the executors of the testcase
+	if FromContext(ctx) != nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, ContextKey, &Connections{declared: declared, used: map[string]*sqlx.DB{}}), nil
+}
+
+// TearDown logs the statistics of the pools used by the testcase
+func TearDown(ctx context.Context) {
+	c := FromContext(ctx)
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	for name, db := range c.used {
+		LogStats(ctx, name, db)
+	}
+	// the statistics are logged once for the executors sharing the connections
+	c.used = map[string]*sqlx.DB{}
+	suite := c.suite
+	c.suite = nil
+	c.mutex.Unlock()
+
+	if suite != nil {
+		closePools(ctx, *suite)
+	}
+}
+
+// FromContext returns the connections of the testcase, or nil without declared connections
+func FromContext(ctx context.Context) *Connections {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*Connections)
+}
+
+// Get returns the pool of the named connection, with its declaration
+func Get(ctx context.Context, name string) (*sqlx.DB, Connection, error) {
+	c := FromContext(ctx)
+	if c == nil {
+		return nil, Connection{}, fmt.Errorf("connection %q is not declared in the %s variable", name, VarName)
+	}
+	connection, ok := c.declared[name]
+	if !ok {
+		return nil, Connection{}, fmt.Errorf("connection %q is not declared in the %s variable", name, VarName)
+	}
+
+	suite := venom.StringVarFromCtx(ctx, "venom.testsuite.filepath")
+	poolsMutex.Lock()
+	defer poolsMutex.Unlock()
+	suitePools, ok := pools[suite]
+	if !ok {
+		suitePools = map[Connection]*sqlx.DB{}
+		pools[suite] = suitePools
+		if !venom.OnTestSuiteEnd(ctx, func() { closePools(ctx, suite) }) {
+			// outside of a testsuite, the pools live as long as the testcase
+			c.mutex.Lock()
+			c.suite = &suite
+			c.mutex.Unlock()
+		}
+	}
+	db, ok := suitePools[connection]
+	if !ok {
+		venom.Debug(ctx, "opening connection %s to database %s\n", name, connection.Driver)
+		var err error
+		db, err = sqlx.Connect(connection.Driver, connection.DSN)
+		if err != nil {
+			return nil, Connection{}, fmt.Errorf("failed to connect to database of connection %q: %w", name, err)
+		}
+		if connection.MaxOpenConns > 0 {
+			db.SetMaxOpenConns(connection.MaxOpenConns)
+		}
+		if connection.MaxIdleConns > 0 {
+			db.SetMaxIdleConns(connection.MaxIdleConns)
+		}
+		db.SetConnMaxLifetime(time.Duration(connection.ConnMaxLifetime) * time.Second)
+		db.SetConnMaxIdleTime(time.Duration(connection.ConnMaxIdleTime) * time.Second)
+		suitePools[connection] = db
+	}
+
+	c.mutex.Lock()
+	c.used[name] = db
+	c.mutex.Unlock()
+	return db, connection, nil
+}
+
+// closePools closes the pools of the testsuite
+func closePools(ctx context.Context, suite string) {
+	poolsMutex.Lock()
+	defer poolsMutex.Unlock()
+	for connection, db := range pools[suite] {
+		if err := db.Close(); err != nil {
+			venom.Warn(ctx, "unable to close connection to database %s: %v", connection.Driver, err)
+		}
+	}
+	delete(pools, suite)
+}
+
+// LogStats logs the statistics of the pool in the verbose output
+func LogStats(ctx context.Context, name string, db *sqlx.DB) {
+	s := db.Stats()
+	venom.Info(ctx, "connection %s: %d open (%d in use, %d idle), %d waits for %v, %d closed idle, %d closed lifetime",
+		name, s.OpenConnections, s.InUse, s.Idle, s.WaitCount, s.WaitDuration, s.MaxIdleClosed+s.MaxIdleTimeClosed, s.MaxLifetimeClosed)
+}
+
+// parse returns the declared connections, the variable is a map or its JSON string once interpolated
+func parse(v interface{}) (map[string]Connection, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok {
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, err
+		}
+	}
+	declared := map[string]Connection{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, Result: &declared})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(v); err != nil {
+		return nil, err
+	}
+	for name, connection := range declared {
+		if connection.Driver == "" || connection.DSN == "" {
+			return nil, fmt.Errorf("driver and dsn are mandatory for connection %q", name)
+		}
+	}
+	return declared, nil
+}
@@ -0,0 +1,84 @@
+package dbpool
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/ovh/venom"
+)
+
+func TestSetup(t *testing.T) {
+	ctx, err := Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+	assert.Nil(t, FromContext(ctx))
+
+	// once interpolated, the variable is a JSON string
+	ctx, err = Setup(context.Background(), venom.H{VarName: `{"main": {"driver": "postgres", "dsn": "host=localhost", "maxOpenConns": "5"}}`})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Connection{"main": {Driver: "postgres", DSN: "host=localhost", MaxOpenConns: 5}}, FromContext(ctx).declared)
+
+	// the connections of the first executor are shared with the next ones
+	c := FromContext(ctx)
+	ctx, err = Setup(ctx, venom.H{VarName: map[string]interface{}{"main": map[string]interface{}{"driver": "postgres", "dsn": "host=localhost"}}})
+	require.NoError(t, err)
+	assert.Same(t, c, FromContext(ctx))
+
+	_, err = Setup(context.Background(), venom.H{VarName: map[string]interface{}{"main": map[string]interface{}{"driver": "postgres"}}})
+	require.EqualError(t, err, `invalid sql.connections variable: driver and dsn are mandatory for connection "main"`)
+}
+
+func TestGet(t *testing.T) {
+	venom.InitTestLogger(t)
+	vars := venom.H{VarName: map[string]interface{}{
+		"main": map[string]interface{}{"driver": "sqlite", "dsn": filepath.Join(t.TempDir(), "venom.db"), "maxOpenConns": 2},
+	}}
+
+	_, _, err := Get(context.Background(), "main")
+	require.EqualError(t, err, `connection "main" is not declared in the sql.connections variable`)
+
+	suiteCtx, endSuite := venom.WithTestSuiteEnd(context.Background())
+	ctx, err := Setup(suiteCtx, vars)
+	require.NoError(t, err)
+	db, connection, err := Get(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", connection.Driver)
+	assert.Equal(t, 2, db.Stats().MaxOpenConnections)
+	_, _, err = Get(ctx, "other")
+	require.EqualError(t, err, `connection "other" is not declared in the sql.connections variable`)
+	TearDown(ctx)
+
+	// the pool is shared by the next testcases of the testsuite
+	ctx, err = Setup(suiteCtx, vars)
+	require.NoError(t, err)
+	db2, _, err := Get(ctx, "main")
+	require.NoError(t, err)
+	assert.Same(t, db, db2)
+	TearDown(ctx)
+	require.NoError(t, db.Ping())
+
+	// and closed at its end
+	endSuite()
+	require.Error(t, db.Ping())
+
+	// another testsuite opens its own pool
+	otherSuiteCtx, endOtherSuite := venom.WithTestSuiteEnd(context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.filepath"), "other.yml"))
+	defer endOtherSuite()
+	ctx, err = Setup(otherSuiteCtx, vars)
+	require.NoError(t, err)
+	db3, _, err := Get(ctx, "main")
+	require.NoError(t, err)
+	assert.NotSame(t, db, db3)
+
+	// outside of a testsuite, the pool is closed with the testcase
+	ctx, err = Setup(context.Background(), vars)
+	require.NoError(t, err)
+	db4, _, err := Get(ctx, "main")
+	require.NoError(t, err)
+	TearDown(ctx)
+	require.Error(t, db4.Ping())
+}
@@ -0,0 +1,27 @@
+// Package jsondecode decodes the JSON payloads of the messages and values returned by the executors.
+package jsondecode
+
+import (
+	"context"
+
+	"github.com/ovh/venom"
+)
+
+// Value returns the value decoded from JSON, or nil if it is not JSON
+func Value(ctx context.Context, b []byte) interface{} {
+	var v interface{}
+	if err := venom.JSONUnmarshal(b, &v); err != nil {
+		venom.Debug(ctx, "unable to decode value as json: %v", err)
+		return nil
+	}
+	return v
+}
+
+// Message decodes a JSON array or object payload, other payloads are decoded as an empty object
+func Message(ctx context.Context, b []byte) interface{} {
+	switch v := Value(ctx, b).(type) {
+	case []interface{}, map[string]interface{}:
+		return v
+	}
+	return map[string]interface{}{}
+}
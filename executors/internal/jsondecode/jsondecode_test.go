@@ -0,0 +1,28 @@
+package jsondecode
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/venom"
+)
+
+func TestValue(t *testing.T) {
+	venom.InitTestLogger(t)
+	ctx := context.Background()
+	assert.Equal(t, map[string]interface{}{"port": json.Number("8080")}, Value(ctx, []byte(`{"port": 8080}`)))
+	assert.Equal(t, json.Number("42"), Value(ctx, []byte("42")))
+	assert.Nil(t, Value(ctx, []byte("enabled")))
+}
+
+func TestMessage(t *testing.T) {
+	venom.InitTestLogger(t)
+	ctx := context.Background()
+	assert.Equal(t, []interface{}{json.Number("1"), "a"}, Message(ctx, []byte(`[1, "a"]`)))
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, Message(ctx, []byte(`{"id": 1}`)))
+	assert.Equal(t, map[string]interface{}{}, Message(ctx, []byte("42")))
+	assert.Equal(t, map[string]interface{}{}, Message(ctx, []byte("hello")))
+}
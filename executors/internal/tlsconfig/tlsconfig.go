@@ -0,0 +1,79 @@
+// Package tlsconfig builds the TLS configuration of the executors from the tls_root_ca, tls_client_cert,
+// tls_client_key and ignore_verify_ssl options of their steps.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ovh/venom"
+)
+
+// Options are the TLS options of a step.
+// The certificates and key are PEM contents, or paths to PEM files relative to the testsuite.
+type Options struct {
+	RootCA          string
+	ClientCert      string
+	ClientKey       string
+	IgnoreVerifySSL bool
+}
+
+// IsZero reports whether no TLS option is set
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// New returns the TLS configuration of the options, or nil without TLS options
+func New(ctx context.Context, o Options) (*tls.Config, error) {
+	if o.IsZero() {
+		return nil, nil
+	}
+	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
+	config := &tls.Config{InsecureSkipVerify: o.IgnoreVerifySSL}
+
+	if o.RootCA != "" {
+		rootCA, err := readPEM(workdir, o.RootCA)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLSRootCA: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if ok := config.RootCAs.AppendCertsFromPEM(rootCA); !ok {
+			return nil, errors.New("failed to add root CA's certificate")
+		}
+	}
+
+	if o.ClientCert != "" || o.ClientKey != "" {
+		clientCert, err := readPEM(workdir, o.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLSClientCert: %w", err)
+		}
+		clientKey, err := readPEM(workdir, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read TLSClientKey: %w", err)
+		}
+		cert, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse x509 mTLS certificate or key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// readPEM returns the value itself if it is PEM content, or the content of the file at this path
+func readPEM(workdir, value string) ([]byte, error) {
+	if value == "" || strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workdir, path)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,71 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// selfSigned returns a self-signed certificate and its key, PEM encoded
+func selfSigned(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestNew(t *testing.T) {
+	cert, key := selfSigned(t)
+	workdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "ca.pem"), cert, 0o600))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), workdir)
+
+	config, err := New(ctx, Options{})
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	// the root CA is read from the file relative to the testsuite, the client certificate and key are PEM contents
+	config, err = New(ctx, Options{RootCA: "ca.pem", ClientCert: string(cert), ClientKey: string(key)})
+	require.NoError(t, err)
+	require.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.False(t, config.InsecureSkipVerify)
+
+	config, err = New(ctx, Options{IgnoreVerifySSL: true})
+	require.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+
+	// a value without PEM content is a path, a missing file is reported as such
+	_, err = New(ctx, Options{RootCA: "missing.pem"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read TLSRootCA: open "+filepath.Join(workdir, "missing.pem"))
+	_, err = New(ctx, Options{RootCA: "-----BEGIN CERTIFICATE-----\ninvalid\n-----END CERTIFICATE-----"})
+	require.EqualError(t, err, "failed to add root CA's certificate")
+	_, err = New(ctx, Options{ClientCert: string(cert)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse x509 mTLS certificate or key")
+}
@@ -0,0 +1,310 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/IBM/sarama"
+)
+
+// Admin actions, used when ClientType is admin
+const (
+	actionCreateTopics   = "createTopics"
+	actionDeleteTopics   = "deleteTopics"
+	actionDescribeTopics = "describeTopics"
+	actionListGroups     = "listGroups"
+	actionDescribeGroups = "describeGroups"
+	actionResetOffsets   = "resetOffsets"
+	actionAlterConfigs   = "alterConfigs"
+)
+
+type (
+	// TopicDescription represents a topic described by the admin client
+	TopicDescription struct {
+		Name              string            `json:"name" yaml:"name"`
+		Partitions        int               `json:"partitions" yaml:"partitions"`
+		ReplicationFactor int               `json:"replicationfactor" yaml:"replicationFactor"`
+		Configs           map[string]string `json:"configs,omitempty" yaml:"configs,omitempty"`
+	}
+
+	// GroupDescription represents a consumer group described by the admin client
+	GroupDescription struct {
+		Name         string `json:"name" yaml:"name"`
+		State        string `json:"state,omitempty" yaml:"state,omitempty"`
+		ProtocolType string `json:"protocoltype,omitempty" yaml:"protocolType,omitempty"`
+		Members      int    `json:"members" yaml:"members"`
+		// Lag is the sum of the lags of the partitions the group has committed an offset for
+		Lag     int64         `json:"lag" yaml:"lag"`
+		Offsets []GroupOffset `json:"offsets,omitempty" yaml:"offsets,omitempty"`
+	}
+
+	// GroupOffset represents the offset committed by a consumer group for a partition
+	GroupOffset struct {
+		Topic         string `json:"topic" yaml:"topic"`
+		Partition     int32  `json:"partition" yaml:"partition"`
+		Offset        int64  `json:"offset" yaml:"offset"`
+		HighWaterMark int64  `json:"highwatermark" yaml:"highWaterMark"`
+		Lag           int64  `json:"lag" yaml:"lag"`
+	}
+)
+
+// admin runs the admin action of the step
+func (e Executor) admin(result *Result) error {
+	config, err := e.getKafkaConfig()
+	if err != nil {
+		return err
+	}
+	client, err := sarama.NewClient(e.Addrs, config)
+	if err != nil {
+		return fmt.Errorf("error instantiate client err: %w", err)
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("error instantiate admin err: %w", err)
+	}
+	// closes the client too
+	defer func() { _ = admin.Close() }()
+
+	switch e.Action {
+	case actionCreateTopics:
+		return e.createTopics(admin)
+	case actionDeleteTopics:
+		for _, topic := range e.Topics {
+			if err := admin.DeleteTopic(topic); err != nil {
+				return fmt.Errorf("unable to delete topic %s: %w", topic, err)
+			}
+		}
+		return nil
+	case actionDescribeTopics:
+		result.Topics, err = e.describeTopics(admin)
+		return err
+	case actionListGroups:
+		groups, err := admin.ListConsumerGroups()
+		if err != nil {
+			return fmt.Errorf("unable to list consumer groups: %w", err)
+		}
+		result.Groups = make([]GroupDescription, 0, len(groups))
+		for group, protocolType := range groups {
+			result.Groups = append(result.Groups, GroupDescription{Name: group, ProtocolType: protocolType})
+		}
+		sort.Slice(result.Groups, func(i, j int) bool { return result.Groups[i].Name < result.Groups[j].Name })
+		return nil
+	case actionDescribeGroups:
+		result.Groups, err = e.describeGroups(client, admin, e.groups())
+		return err
+	case actionResetOffsets:
+		if e.GroupID == "" {
+			return fmt.Errorf("groupID is mandatory to reset offsets")
+		}
+		if err := e.resetOffsets(client); err != nil {
+			return err
+		}
+		result.Groups, err = e.describeGroups(client, admin, []string{e.GroupID})
+		return err
+	case actionAlterConfigs:
+		entries := make(map[string]sarama.IncrementalAlterConfigsEntry, len(e.Configs))
+		for k := range e.Configs {
+			v := e.Configs[k]
+			entries[k] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &v}
+		}
+		for _, topic := range e.Topics {
+			if err := admin.IncrementalAlterConfig(sarama.TopicResource, topic, entries, false); err != nil {
+				return fmt.Errorf("unable to alter configs of topic %s: %w", topic, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("action must be one of %s, %s, %s, %s, %s, %s or %s", actionCreateTopics, actionDeleteTopics,
+			actionDescribeTopics, actionListGroups, actionDescribeGroups, actionResetOffsets, actionAlterConfigs)
+	}
+}
+
+func (e Executor) createTopics(admin sarama.ClusterAdmin) error {
+	detail := &sarama.TopicDetail{
+		NumPartitions:     e.NumPartitions,
+		ReplicationFactor: e.ReplicationFactor,
+		ConfigEntries:     make(map[string]*string, len(e.Configs)),
+	}
+	if detail.NumPartitions == 0 {
+		detail.NumPartitions = 1
+	}
+	if detail.ReplicationFactor == 0 {
+		detail.ReplicationFactor = 1
+	}
+	for k := range e.Configs {
+		v := e.Configs[k]
+		detail.ConfigEntries[k] = &v
+	}
+	for _, topic := range e.Topics {
+		if err := admin.CreateTopic(topic, detail, false); err != nil {
+			return fmt.Errorf("unable to create topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// describeTopics describes the topics of the step, or all the topics
+func (e Executor) describeTopics(admin sarama.ClusterAdmin) ([]TopicDescription, error) {
+	topics := e.Topics
+	if len(topics) == 0 {
+		details, err := admin.ListTopics()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list topics: %w", err)
+		}
+		for topic := range details {
+			topics = append(topics, topic)
+		}
+		sort.Strings(topics)
+	}
+
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe topics: %w", err)
+	}
+	descriptions := make([]TopicDescription, 0, len(metadata))
+	for _, m := range metadata {
+		if m.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("unable to describe topic %s: %w", m.Name, m.Err)
+		}
+		description := TopicDescription{Name: m.Name, Partitions: len(m.Partitions), Configs: map[string]string{}}
+		if len(m.Partitions) > 0 {
+			description.ReplicationFactor = len(m.Partitions[0].Replicas)
+		}
+		entries, err := admin.DescribeConfig(sarama.ConfigResource{Type: sarama.TopicResource, Name: m.Name})
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe configs of topic %s: %w", m.Name, err)
+		}
+		for _, entry := range entries {
+			description.Configs[entry.Name] = entry.Value
+		}
+		descriptions = append(descriptions, description)
+	}
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	return descriptions, nil
+}
+
+// groups returns the consumer groups of the step
+func (e Executor) groups() []string {
+	if len(e.Groups) > 0 {
+		return e.Groups
+	}
+	if e.GroupID != "" {
+		return []string{e.GroupID}
+	}
+	return nil
+}
+
+// describeGroups describes the consumer groups, with the lag of their committed offsets
+func (e Executor) describeGroups(client sarama.Client, admin sarama.ClusterAdmin, groups []string) ([]GroupDescription, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("groups or groupID is mandatory to describe consumer groups")
+	}
+	described, err := admin.DescribeConsumerGroups(groups)
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe consumer groups: %w", err)
+	}
+
+	descriptions := make([]GroupDescription, 0, len(described))
+	for _, g := range described {
+		if g.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("unable to describe consumer group %s: %w", g.GroupId, g.Err)
+		}
+		description := GroupDescription{Name: g.GroupId, State: g.State, ProtocolType: g.ProtocolType, Members: len(g.Members)}
+
+		offsets, err := admin.ListConsumerGroupOffsets(g.GroupId, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list offsets of consumer group %s: %w", g.GroupId, err)
+		}
+		for topic, partitions := range offsets.Blocks {
+			for partition, block := range partitions {
+				if block.Err != sarama.ErrNoError {
+					return nil, fmt.Errorf("unable to list offset of consumer group %s for partition %d of topic %s: %w", g.GroupId, partition, topic, block.Err)
+				}
+				if block.Offset < 0 {
+					continue
+				}
+				hwm, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get the newest offset of partition %d of topic %s: %w", partition, topic, err)
+				}
+				offset := GroupOffset{Topic: topic, Partition: partition, Offset: block.Offset, HighWaterMark: hwm, Lag: hwm - block.Offset}
+				description.Offsets = append(description.Offsets, offset)
+				description.Lag += offset.Lag
+			}
+		}
+		sort.Slice(description.Offsets, func(i, j int) bool {
+			a, b := description.Offsets[i], description.Offsets[j]
+			return a.Topic < b.Topic || (a.Topic == b.Topic && a.Partition < b.Partition)
+		})
+		descriptions = append(descriptions, description)
+	}
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	return descriptions, nil
+}
+
+// resetOffsets commits the offsets of the consumer group, to the offset, the timestamp or the initial offset of the step.
+// The offsets are committed with an explicit request rather than an offset manager, which only moves committed offsets
+// backward: they can be moved forward, and set for partitions the group has no committed offset for.
+func (e Executor) resetOffsets(client sarama.Client) error {
+	req := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           e.GroupID,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		// the retention of the broker
+		RetentionTime: -1,
+	}
+	// the commit timestamp, set by the broker, is only part of version 1
+	commitTimestamp := int64(0)
+	if !client.Config().Version.IsAtLeast(sarama.V0_9_0_0) {
+		req.Version = 1
+		commitTimestamp = sarama.ReceiveTime
+	}
+
+	var partitionsCount int
+	for _, topic := range e.Topics {
+		partitions := e.Partitions
+		if len(partitions) == 0 {
+			var err error
+			partitions, err = client.Partitions(topic)
+			if err != nil {
+				return fmt.Errorf("unable to get the partitions of topic %s: %w", topic, err)
+			}
+		}
+		for _, partition := range partitions {
+			offset, err := e.startOffset(client, topic, partition)
+			if err != nil {
+				return err
+			}
+			if offset < 0 {
+				// oldest or newest offset
+				offset, err = client.GetOffset(topic, partition, offset)
+				if err != nil {
+					return fmt.Errorf("unable to get the offset of partition %d of topic %s: %w", partition, topic, err)
+				}
+			}
+			req.AddBlock(topic, partition, offset, commitTimestamp, "")
+			partitionsCount++
+		}
+	}
+	if partitionsCount == 0 {
+		return nil
+	}
+
+	coordinator, err := client.Coordinator(e.GroupID)
+	if err != nil {
+		return fmt.Errorf("unable to find the coordinator of consumer group %s: %w", e.GroupID, err)
+	}
+	resp, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return fmt.Errorf("unable to reset offsets of consumer group %s: %w", e.GroupID, err)
+	}
+	for topic, partitions := range resp.Errors {
+		for partition, kerr := range partitions {
+			if kerr != sarama.ErrNoError {
+				return fmt.Errorf("unable to reset offset of partition %d of topic %s: %w", partition, topic, kerr)
+			}
+		}
+	}
+	return nil
+}
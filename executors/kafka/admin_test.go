@@ -0,0 +1,191 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestExecutor_Run_Admin(t *testing.T) {
+	venom.InitTestLogger(t)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"CreateTopicsRequest":    sarama.NewMockCreateTopicsResponse(t),
+		"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(t),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "venom", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("venom", &sarama.GroupDescription{GroupId: "venom", State: "Empty", ProtocolType: "consumer"}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("venom", "orders", 0, 8, "", sarama.ErrNoError).
+			SetOffset("venom", "orders", 1, 10, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetNewest, 10).
+			SetOffset("orders", 1, sarama.OffsetNewest, 10),
+		"ListGroupsRequest": sarama.NewMockListGroupsResponse(t).AddGroup("venom", "consumer"),
+	})
+
+	step := venom.TestStep{
+		"clientType":    "admin",
+		"addrs":         []string{broker.Addr()},
+		"action":        "createTopics",
+		"topics":        []string{"orders"},
+		"numPartitions": 2,
+		"configs":       map[string]string{"retention.ms": "5000"},
+	}
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	require.Empty(t, res.(Result).Err)
+
+	step["action"] = "describeTopics"
+	res, err = Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result := res.(Result)
+	require.Empty(t, result.Err)
+	require.Len(t, result.Topics, 1)
+	assert.Equal(t, "orders", result.Topics[0].Name)
+	assert.Equal(t, 2, result.Topics[0].Partitions)
+	assert.Equal(t, "5000", result.Topics[0].Configs["retention.ms"])
+
+	res, err = Executor{}.Run(context.Background(), venom.TestStep{
+		"clientType": "admin",
+		"addrs":      []string{broker.Addr()},
+		"action":     "describeGroups",
+		"groupID":    "venom",
+	})
+	require.NoError(t, err)
+	result = res.(Result)
+	require.Empty(t, result.Err)
+	require.Len(t, result.Groups, 1)
+	group := result.Groups[0]
+	assert.Equal(t, "venom", group.Name)
+	assert.Equal(t, "Empty", group.State)
+	assert.Equal(t, int64(2), group.Lag)
+	assert.Equal(t, []GroupOffset{
+		{Topic: "orders", Partition: 0, Offset: 8, HighWaterMark: 10, Lag: 2},
+		{Topic: "orders", Partition: 1, Offset: 10, HighWaterMark: 10, Lag: 0},
+	}, group.Offsets)
+
+	res, err = Executor{}.Run(context.Background(), venom.TestStep{
+		"clientType": "admin",
+		"addrs":      []string{broker.Addr()},
+		"action":     "listGroups",
+		// the mock broker only encodes the first versions of the response
+		"kafkaVersion": "2.0.0",
+	})
+	require.NoError(t, err)
+	require.Empty(t, res.(Result).Err)
+	assert.Equal(t, []GroupDescription{{Name: "venom", ProtocolType: "consumer"}}, res.(Result).Groups)
+
+	res, err = Executor{}.Run(context.Background(), venom.TestStep{"clientType": "admin", "addrs": []string{broker.Addr()}, "action": "unknown"})
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "action must be one of")
+}
+
+func TestExecutor_Run_ResetOffsets(t *testing.T) {
+	venom.InitTestLogger(t)
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	handlers := map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "venom", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("venom", &sarama.GroupDescription{GroupId: "venom", State: "Empty", ProtocolType: "consumer"}),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("venom", "orders", 0, 2, "", sarama.ErrNoError).
+			SetOffset("venom", "orders", 1, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders", 0, sarama.OffsetNewest, 10).
+			SetOffset("orders", 0, since.UnixMilli(), 6).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0).
+			SetOffset("orders", 1, sarama.OffsetNewest, 10).
+			SetOffset("orders", 1, since.UnixMilli(), 7),
+	}
+	broker.SetHandlerByMap(handlers)
+
+	// committed returns the offsets of the last commit request received by the broker
+	committed := func() map[int32]int64 {
+		var req *sarama.OffsetCommitRequest
+		for _, rr := range broker.History() {
+			if r, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+				req = r
+			}
+		}
+		require.NotNil(t, req)
+		assert.Equal(t, "venom", req.ConsumerGroup)
+		offsets := map[int32]int64{}
+		for _, partition := range []int32{0, 1} {
+			if offset, _, err := req.Offset("orders", partition); err == nil {
+				offsets[partition] = offset
+			}
+		}
+		return offsets
+	}
+
+	// the group has committed offset 2 for partition 0 and no offset for partition 1:
+	// both are moved forward, to the newest offset, to an offset and to a timestamp
+	for name, tt := range map[string]struct {
+		attrs    venom.TestStep
+		expected map[int32]int64
+	}{
+		"newest":    {attrs: venom.TestStep{"initialOffset": "newest"}, expected: map[int32]int64{0: 10, 1: 10}},
+		"oldest":    {attrs: venom.TestStep{"initialOffset": "oldest"}, expected: map[int32]int64{0: 0, 1: 0}},
+		"offset":    {attrs: venom.TestStep{"offset": 5, "partitions": []int32{1}}, expected: map[int32]int64{1: 5}},
+		"timestamp": {attrs: venom.TestStep{"timestamp": since.Format(time.RFC3339)}, expected: map[int32]int64{0: 6, 1: 7}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			step := venom.TestStep{
+				"clientType": "admin",
+				"addrs":      []string{broker.Addr()},
+				"action":     "resetOffsets",
+				"groupID":    "venom",
+				"topics":     []string{"orders"},
+			}
+			for k, v := range tt.attrs {
+				step[k] = v
+			}
+			res, err := Executor{}.Run(context.Background(), step)
+			require.NoError(t, err)
+			require.Empty(t, res.(Result).Err)
+			assert.Equal(t, tt.expected, committed())
+		})
+	}
+
+	handlers["OffsetCommitRequest"] = sarama.NewMockOffsetCommitResponse(t).SetError("venom", "orders", 0, sarama.ErrUnknownMemberId)
+	broker.SetHandlerByMap(handlers)
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"clientType": "admin",
+		"addrs":      []string{broker.Addr()},
+		"action":     "resetOffsets",
+		"groupID":    "venom",
+		"topics":     []string{"orders"},
+		"partitions": []int32{0},
+		"offset":     1,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "unable to reset offset of partition 0 of topic orders")
+}
@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+)
+
+// ContextKey is the key of the background consumers in the testcase context
+const ContextKey = venom.ContextKey("kafkaContext")
+
+// consumedMessage is a message buffered by a background consumer
+type consumedMessage struct {
+	msg     Message
+	msgJSON interface{}
+}
+
+// Setup prepares the store of the background consumers of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, background.NewConsumers[consumedMessage]()), nil
+}
+
+// TearDown stops the background consumers left running by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	if consumers := getConsumers(ctx); consumers != nil {
+		consumers.StopAll()
+	}
+	return nil
+}
+
+func getConsumers(ctx context.Context) *background.Consumers[consumedMessage] {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*background.Consumers[consumedMessage])
+}
+
+// startBackground starts a consumer running until the end of the testcase,
+// it returns once the consumer is ready to receive the messages
+func (e Executor) startBackground(ctx context.Context) error {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return fmt.Errorf("background consumers are not available outside of a testcase")
+	}
+	if _, err := consumers.Get(e.Background); err == nil {
+		return fmt.Errorf("background consumer %q is already started", e.Background)
+	}
+	config, h, err := e.newConsumer()
+	if err != nil {
+		return err
+	}
+
+	// the consumer outlives the step
+	bctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopped := make(chan struct{})
+	consumer := background.NewConsumer[consumedMessage](func() {
+		cancel()
+		<-stopped
+	})
+	h.background = consumer
+	ready := make(chan struct{})
+	var once sync.Once
+	h.ready = func() { once.Do(func() { close(ready) }) }
+
+	var runErr error
+	go func() {
+		defer close(stopped)
+		if e.GroupID == "" {
+			runErr = e.consumePartitions(bctx, config, h)
+		} else {
+			runErr = e.consumeGroup(bctx, config, h)
+		}
+		if bctx.Err() != nil {
+			runErr = nil
+			return
+		}
+		if runErr != nil {
+			consumer.Fail(runErr)
+		}
+	}()
+
+	select {
+	case <-ready:
+	case <-stopped:
+		if runErr == nil {
+			runErr = fmt.Errorf("background consumer %q stopped", e.Background)
+		}
+		return runErr
+	case <-time.After(time.Duration(e.Timeout) * time.Second):
+		consumer.Stop()
+		return fmt.Errorf("background consumer %q not ready after %ds", e.Background, e.Timeout)
+	}
+	venom.Debug(ctx, "background consumer %q started", e.Background)
+	return consumers.Add(e.Background, consumer)
+}
+
+// consumeGroup consumes the topics with the consumer group until the context is cancelled
+func (e Executor) consumeGroup(ctx context.Context, config *sarama.Config, h *handler) error {
+	consumerGroup, err := sarama.NewConsumerGroup(e.Addrs, e.GroupID, config)
+	if err != nil {
+		return fmt.Errorf("error instantiate consumer err: %w", err)
+	}
+	defer func() { _ = consumerGroup.Close() }()
+
+	go func() {
+		for err := range consumerGroup.Errors() {
+			if ctx.Err() == nil {
+				venom.Error(ctx, "error on consume:%s", err)
+			}
+		}
+	}()
+
+	// Consume returns on each rebalance of the group
+	for ctx.Err() == nil {
+		if err := consumerGroup.Consume(ctx, e.Topics, h); err != nil {
+			return fmt.Errorf("error on consume: %w", err)
+		}
+	}
+	return nil
+}
+
+// collect returns the messages buffered by a background consumer,
+// once messageLimit messages are received, or after waitFor seconds
+func (e Executor) collect(ctx context.Context) ([]Message, []interface{}, error) {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return nil, nil, fmt.Errorf("background consumers are not available outside of a testcase")
+	}
+	consumer, err := consumers.Get(e.Background)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if e.WaitFor > 0 {
+		select {
+		case <-time.After(time.Duration(e.WaitFor) * time.Second):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	consumed, err := consumer.Collect(ctx, e.MessageLimit, time.Duration(e.Timeout)*time.Second)
+	messages := make([]Message, 0, len(consumed))
+	messagesJSON := make([]interface{}, 0, len(consumed))
+	for _, c := range consumed {
+		messages = append(messages, c.msg)
+		messagesJSON = append(messagesJSON, c.msgJSON)
+	}
+	return messages, messagesJSON, err
+}
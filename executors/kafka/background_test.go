@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestExecutor_Run_Background(t *testing.T) {
+	venom.InitTestLogger(t)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders", 0, sarama.OffsetNewest, 2),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 2).
+			SetMessageWithKey("orders", 0, 0, sarama.StringEncoder("order-1"), sarama.StringEncoder(`{"id":1}`)).
+			SetMessageWithKey("orders", 0, 1, sarama.StringEncoder("order-2"), sarama.StringEncoder(`{"id":2}`)).
+			SetHighWaterMark("orders", 0, 2),
+	})
+
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	start := venom.TestStep{
+		"clientType": "consumer",
+		"addrs":      []string{broker.Addr()},
+		"topics":     []string{"orders"},
+		"offset":     0,
+		"background": "orders",
+	}
+	res, err := Executor{}.Run(ctx, start)
+	require.NoError(t, err)
+	require.Empty(t, res.(Result).Err)
+
+	res, err = Executor{}.Run(ctx, start)
+	require.NoError(t, err)
+	assert.Equal(t, `background consumer "orders" is already started`, res.(Result).Err)
+
+	collect := venom.TestStep{"clientType": "collect", "background": "orders", "messageLimit": 2}
+	res, err = Executor{}.Run(ctx, collect)
+	require.NoError(t, err)
+	result := res.(Result)
+	require.Empty(t, result.Err)
+	require.Len(t, result.Messages, 2)
+	assert.Equal(t, "order-1", result.Messages[0].Key)
+	assert.Equal(t, "order-2", result.Messages[1].Key)
+	require.Len(t, result.MessagesJSON, 2)
+
+	// the collected messages are not returned again
+	collect["messageLimit"] = 1
+	collect["timeout"] = 1
+	res, err = Executor{}.Run(ctx, collect)
+	require.NoError(t, err)
+	assert.Equal(t, "0 messages received after 1s, expected 1", res.(Result).Err)
+
+	res, err = Executor{}.Run(ctx, venom.TestStep{"clientType": "collect", "background": "payments"})
+	require.NoError(t, err)
+	assert.Equal(t, `background consumer "payments" is not started`, res.(Result).Err)
+
+	require.NoError(t, Executor{}.TearDown(ctx))
+	res, err = Executor{}.Run(ctx, collect)
+	require.NoError(t, err)
+	assert.Equal(t, `background consumer "orders" is not started`, res.(Result).Err)
+}
+
+type testSession struct {
+	sarama.ConsumerGroupSession
+	claims map[string][]int32
+}
+
+func (s testSession) Claims() map[string][]int32 { return s.claims }
+func (s testSession) Context() context.Context   { return context.Background() }
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestHandler_Ready(t *testing.T) {
+	var ready int
+	h := &handler{ready: func() { ready++ }}
+	session := testSession{claims: map[string][]int32{"orders": {0, 1}, "payments": {0}}}
+	require.NoError(t, h.Setup(session))
+	assert.Equal(t, 0, ready)
+
+	// the consumer is ready once ConsumeClaim is called for every claim, their offsets are resolved
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 0, ready)
+		claim := testClaim{messages: make(chan *sarama.ConsumerMessage)}
+		close(claim.messages)
+		require.NoError(t, h.ConsumeClaim(session, claim))
+	}
+	assert.Equal(t, 1, ready)
+
+	// without claim, nothing will be consumed before a rebalance
+	require.NoError(t, h.Setup(testSession{}))
+	assert.Equal(t, 2, ready)
+}
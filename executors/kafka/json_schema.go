@@ -0,0 +1,36 @@
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+const jsonSchemaURL = "schema.json"
+
+// ValidateJSONSchema will check that the JSON value is valid against the JSON schema
+func ValidateJSONSchema(value []byte, schema string) error {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("failed to read JSON schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(jsonSchemaURL, doc); err != nil {
+		return fmt.Errorf("failed to add JSON schema: %w", err)
+	}
+	sch, err := compiler.Compile(jsonSchemaURL)
+	if err != nil {
+		return fmt.Errorf("failed to compile JSON schema: %w", err)
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(value))
+	if err != nil {
+		return fmt.Errorf("failed to read value %s as JSON: %w", value, err)
+	}
+	if err := sch.Validate(instance); err != nil {
+		return fmt.Errorf("value %s is not valid against JSON schema: %w", value, err)
+	}
+	return nil
+}
@@ -3,19 +3,23 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/mitchellh/mapstructure"
 
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
 )
 
 const (
@@ -40,13 +44,26 @@ type (
 		Value          string            `json:"value,omitempty" yaml:"value,omitempty"`
 		ValueFile      string            `json:"valueFile,omitempty" yaml:"valueFile,omitempty"`
 		AvroSchemaFile string            `json:"avroSchemaFile,omitempty" yaml:"avroSchemaFile,omitempty"`
+		// ProtobufSchemaFile and JSONSchemaFile are registered in the Schema Registry
+		ProtobufSchemaFile string `json:"protobufSchemaFile,omitempty" yaml:"protobufSchemaFile,omitempty"`
+		JSONSchemaFile     string `json:"jsonSchemaFile,omitempty" yaml:"jsonSchemaFile,omitempty"`
+		// ProtobufMessage is the name of the message type in the Protobuf schema, default is the first message
+		ProtobufMessage string `json:"protobufMessage,omitempty" yaml:"protobufMessage,omitempty"`
+		// Partition, Offset and Timestamp are set on the consumed messages
+		Partition int32  `json:"partition" yaml:"partition"`
+		Offset    int64  `json:"offset" yaml:"offset"`
+		Timestamp string `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
 	}
 
 	// MessageJSON represents the object sended or received from kafka
 	MessageJSON struct {
-		Topic string
-		Key   interface{}
-		Value interface{}
+		Topic     string
+		Key       interface{}
+		Value     interface{}
+		Headers   map[string]string
+		Partition int32
+		Offset    int64
+		Timestamp string
 	}
 
 	// Executor represents a Test Exec
@@ -55,6 +72,8 @@ type (
 		// Registry schema address
 		SchemaRegistryAddr string `json:"schema_registry_addr,omitempty" yaml:"schemaRegistryAddr,omitempty"`
 		WithAVRO           bool   `json:"with_avro,omitempty" yaml:"withAVRO,omitempty"`
+		WithProtobuf       bool   `json:"with_protobuf,omitempty" yaml:"withProtobuf,omitempty"`
+		WithJSONSchema     bool   `json:"with_json_schema,omitempty" yaml:"withJSONSchema,omitempty"`
 		WithTLS            bool   `json:"with_tls,omitempty" yaml:"withTLS,omitempty"`
 		WithSASL           bool   `json:"with_sasl,omitempty" yaml:"withSASL,omitempty"`
 		WithSASLHandshaked bool   `json:"with_sasl_handshaked,omitempty" yaml:"withSASLHandshaked,omitempty"`
@@ -64,12 +83,30 @@ type (
 		// TLS Config
 		InsecureTLS bool `json:"insecure_tls,omitempty" yaml:"insecure_tls,omitempty"`
 
-		// ClientType must be "consumer" or "producer"
+		// ClientType must be "consumer", "producer", "collect" or "admin"
 		ClientType string `json:"client_type,omitempty" yaml:"clientType,omitempty"`
 
+		// Used when ClientType is admin
+		// Action is one of createTopics, deleteTopics, describeTopics, listGroups, describeGroups, resetOffsets or alterConfigs
+		Action string `json:"action,omitempty" yaml:"action,omitempty"`
+		// NumPartitions and ReplicationFactor of the created topics. Default 1
+		NumPartitions     int32 `json:"num_partitions,omitempty" yaml:"numPartitions,omitempty"`
+		ReplicationFactor int16 `json:"replication_factor,omitempty" yaml:"replicationFactor,omitempty"`
+		// Configs of the created or altered topics
+		Configs map[string]string `json:"configs,omitempty" yaml:"configs,omitempty"`
+		// Groups are the described consumer groups, default is GroupID
+		Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+
 		// Used when ClientType is consumer
+		// GroupID is the consumer group. Without it, the partitions are consumed directly
 		GroupID string   `json:"group_id,omitempty" yaml:"groupID,omitempty"`
 		Topics  []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+		// Partitions to consume without a consumer group. Default all the partitions of the topics
+		Partitions []int32 `json:"partitions,omitempty" yaml:"partitions,omitempty"`
+		// Offset to start consuming from without a consumer group, in each partition
+		Offset *int64 `json:"offset,omitempty" yaml:"offset,omitempty"`
+		// Timestamp to start consuming from without a consumer group, RFC3339 formatted
+		Timestamp string `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
 		// Represents the timeout for reading messages. In Seconds. Default 5
 		Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 		// WaitFor represents the time for reading messages without marking the test as failure.
@@ -80,9 +117,16 @@ type (
 		InitialOffset string `json:"initial_offset,omitempty" yaml:"initialOffset,omitempty"`
 		// MarkOffset allows to mark offset when consuming message
 		MarkOffset bool `json:"mark_offset,omitempty" yaml:"markOffset,omitempty"`
+		// Background is the name of a consumer running until the end of the testcase.
+		// Its messages are returned by the steps with the collect ClientType, until MessageLimit messages are received
+		Background string `json:"background,omitempty" yaml:"background,omitempty"`
 
 		// KeyFilter determines the key to filter from
 		KeyFilter string `json:"key_filter,omitempty" yaml:"keyFilter,omitempty"`
+		// HeaderFilter determines the header values to filter from
+		HeaderFilter map[string]string `json:"header_filter,omitempty" yaml:"headerFilter,omitempty"`
+		// ValueFilter determines the values to filter from, by path in the JSON value. eg: order.items.0.id
+		ValueFilter map[string]interface{} `json:"value_filter,omitempty" yaml:"valueFilter,omitempty"`
 
 		// Only one of JSON or Avro are currently supported
 		ConsumerEncoding string `json:"consumer_encoding,omitempty" yaml:"consumerEncoding,omitempty"`
@@ -106,6 +150,9 @@ type (
 		Messages     []Message     `json:"messages,omitempty" yaml:"messages,omitempty"`
 		MessagesJSON []interface{} `json:"messagesjson,omitempty" yaml:"messagesJSON,omitempty"`
 		Err          string        `json:"err" yaml:"error"`
+		// Topics and Groups described by the admin client, sorted by name
+		Topics []TopicDescription `json:"topics,omitempty" yaml:"topics,omitempty"`
+		Groups []GroupDescription `json:"groups,omitempty" yaml:"groups,omitempty"`
 	}
 	consumeFunc = func(message *sarama.ConsumerMessage) (Message, interface{}, error)
 )
@@ -123,13 +170,19 @@ func (Executor) GetDefaultAssertions() *venom.StepAssertions {
 // Run execute TestStep of type exec
 func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
 	var e Executor
-	if err := mapstructure.Decode(step, &e); err != nil {
+	if err := decodeStep(step, &e); err != nil {
 		return nil, err
 	}
 	start := time.Now()
 
 	result := Result{}
-	if e.WithAVRO && len(e.SchemaRegistryAddr) != 0 {
+	if (e.WithAVRO && e.WithProtobuf) || (e.WithAVRO && e.WithJSONSchema) || (e.WithProtobuf && e.WithJSONSchema) {
+		return nil, fmt.Errorf("only one of withAVRO, withProtobuf and withJSONSchema can be set")
+	}
+	if e.WithAVRO || e.WithProtobuf || e.WithJSONSchema {
+		if len(e.SchemaRegistryAddr) == 0 {
+			return nil, fmt.Errorf("schemaRegistryAddr is mandatory with withAVRO, withProtobuf or withJSONSchema")
+		}
 		var err error
 		e.schemaReg, err = NewSchemaRegistry(e.SchemaRegistryAddr)
 		if err != nil {
@@ -153,12 +206,29 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 		}
 	case "consumer":
 		var err error
-		result.Messages, result.MessagesJSON, err = e.consumeMessages(ctx)
+		if e.Background != "" {
+			err = e.startBackground(ctx)
+		} else {
+			result.Messages, result.MessagesJSON, err = e.consumeMessages(ctx)
+		}
+		if err != nil {
+			result.Err = err.Error()
+		}
+	case "collect":
+		if e.Background == "" {
+			return nil, fmt.Errorf("background is mandatory to collect messages")
+		}
+		var err error
+		result.Messages, result.MessagesJSON, err = e.collect(ctx)
 		if err != nil {
 			result.Err = err.Error()
 		}
+	case "admin":
+		if err := e.admin(&result); err != nil {
+			result.Err = err.Error()
+		}
 	default:
-		return nil, fmt.Errorf("type must be a consumer or a producer")
+		return nil, fmt.Errorf("type must be a consumer, a producer, a collect or an admin")
 	}
 
 	elapsed := time.Since(start)
@@ -167,6 +237,27 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	return result, nil
 }
 
+// decodeStep decodes the step, the message values can be set as YAML: they are converted to JSON
+func decodeStep(step venom.TestStep, e *Executor) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if to.Kind() != reflect.String || (from.Kind() != reflect.Map && from.Kind() != reflect.Slice) {
+				return data, nil
+			}
+			btes, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			return string(btes), nil
+		},
+		Result: e,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(step)
+}
+
 func (e Executor) produceMessages(workdir string) error {
 	if len(e.Messages) == 0 && e.MessagesFile == "" {
 		return fmt.Errorf("Either one of `messages` or `messagesFile` field must be set")
@@ -229,6 +320,12 @@ func (e Executor) getMessageValue(m *Message, workdir string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("can't get value: %w", err)
 	}
+	if e.WithProtobuf {
+		return e.getTypedMessageValue(m, value, workdir, schemaTypeProtobuf, m.ProtobufSchemaFile)
+	}
+	if e.WithJSONSchema {
+		return e.getTypedMessageValue(m, value, workdir, schemaTypeJSON, m.JSONSchemaFile)
+	}
 	if !e.WithAVRO {
 		// This is test without AVRO - value is all we need to have
 		return value, nil
@@ -275,6 +372,47 @@ func (e Executor) getMessageValue(m *Message, workdir string) ([]byte, error) {
 	return encodedAvroMsg, nil
 }
 
+// getTypedMessageValue encodes the value with a Protobuf or JSON schema, in the Schema Registry wire format
+func (e Executor) getTypedMessageValue(m *Message, value []byte, workdir, schemaType, schemaFile string) ([]byte, error) {
+	var (
+		schemaID int
+		schema   string
+		err      error
+	)
+	subject := fmt.Sprintf("%s-value", m.Topic) // Using topic name strategy
+	if schemaFile = strings.TrimSpace(schemaFile); schemaFile != "" {
+		schemaPath := path.Join(workdir, schemaFile)
+		schemaBlob, err := os.ReadFile(schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("can't read from %s: %w", schemaPath, err)
+		}
+		schema = string(schemaBlob)
+		schemaID, err = e.schemaReg.RegisterNewTypedSchema(subject, schema, schemaType)
+		if err != nil {
+			return nil, fmt.Errorf("can't register new schema in SchemaRegistry: %s", err)
+		}
+	} else {
+		var latestType string
+		schemaID, schema, latestType, err = e.schemaReg.GetLatestTypedSchema(subject)
+		if err != nil {
+			return nil, fmt.Errorf("can't get latest schema for subject %s: %w", subject, err)
+		}
+		if latestType != schemaType {
+			return nil, fmt.Errorf("latest schema for subject %s is %s, not %s", subject, latestType, schemaType)
+		}
+	}
+
+	if schemaType == schemaTypeProtobuf {
+		value, err = Convert2Protobuf(value, schema, m.ProtobufMessage)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := ValidateJSONSchema(value, schema); err != nil {
+		return nil, err
+	}
+	return CreateMessage(value, schemaID)
+}
+
 func (e Executor) getRAWMessageValue(m *Message, workdir string) ([]byte, error) {
 	// We have 2 fields Value and ValueFile from where we can get value, we prefer Value
 	if len(m.Value) != 0 {
@@ -291,23 +429,10 @@ func (e Executor) getRAWMessageValue(m *Message, workdir string) ([]byte, error)
 }
 
 func (e Executor) consumeMessages(ctx context.Context) ([]Message, []interface{}, error) {
-	if len(e.Topics) == 0 {
-		return nil, nil, fmt.Errorf("You must provide topics")
-	}
-
-	config, err := e.getKafkaConfig()
+	config, h, err := e.newConsumer()
 	if err != nil {
 		return nil, nil, err
 	}
-	if strings.TrimSpace(e.InitialOffset) == "oldest" {
-		config.Consumer.Offsets.Initial = sarama.OffsetOldest
-	}
-
-	consumerGroup, err := sarama.NewConsumerGroup(e.Addrs, e.GroupID, config)
-	if err != nil {
-		return nil, nil, fmt.Errorf("error instantiate consumer err: %w", err)
-	}
-	defer func() { _ = consumerGroup.Close() }()
 
 	timeout := time.Duration(e.Timeout) * time.Second
 	if e.WaitFor > 0 {
@@ -316,6 +441,19 @@ func (e Executor) consumeMessages(ctx context.Context) ([]Message, []interface{}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if e.GroupID == "" {
+		if err := e.consumePartitions(ctx, config, h); err != nil {
+			return nil, nil, err
+		}
+		return h.messages, h.messagesJSON, nil
+	}
+
+	consumerGroup, err := sarama.NewConsumerGroup(e.Addrs, e.GroupID, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error instantiate consumer err: %w", err)
+	}
+	defer func() { _ = consumerGroup.Close() }()
+
 	// Track errors
 	go func() {
 		for err := range consumerGroup.Errors() {
@@ -327,17 +465,6 @@ func (e Executor) consumeMessages(ctx context.Context) ([]Message, []interface{}
 		}
 	}()
 
-	h := &handler{
-		withAVRO:     e.WithAVRO,
-		messages:     []Message{},
-		messagesJSON: []interface{}{},
-		markOffset:   e.MarkOffset,
-		messageLimit: e.MessageLimit,
-		schemaReg:    e.schemaReg,
-		keyFilter:    e.KeyFilter,
-		done:         make(chan struct{}),
-	}
-
 	cherr := make(chan error)
 	go func() {
 		cherr <- consumerGroup.Consume(ctx, e.Topics, h)
@@ -365,6 +492,43 @@ func (e Executor) consumeMessages(ctx context.Context) ([]Message, []interface{}
 	return h.messages, h.messagesJSON, nil
 }
 
+// newConsumer checks the consumer attributes of the step, and returns the consumer config and handler
+func (e Executor) newConsumer() (*sarama.Config, *handler, error) {
+	if len(e.Topics) == 0 {
+		return nil, nil, fmt.Errorf("You must provide topics")
+	}
+
+	positioned := len(e.Partitions) > 0 || e.Offset != nil || e.Timestamp != ""
+	if positioned && e.GroupID != "" {
+		return nil, nil, fmt.Errorf("partitions, offset and timestamp can't be used with a consumer group")
+	}
+
+	config, err := e.getKafkaConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.TrimSpace(e.InitialOffset) == "oldest" {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+
+	h := &handler{
+		withAVRO:     e.WithAVRO,
+		withSchema:   e.WithProtobuf || e.WithJSONSchema,
+		messages:     []Message{},
+		messagesJSON: []interface{}{},
+		markOffset:   e.MarkOffset,
+		messageLimit: e.MessageLimit,
+		schemaReg:    e.schemaReg,
+		filter: messageFilter{
+			key:     e.KeyFilter,
+			headers: e.HeaderFilter,
+			values:  e.ValueFilter,
+		},
+		done: make(chan struct{}),
+	}
+	return config, h, nil
+}
+
 func (e Executor) getKafkaConfig() (*sarama.Config, error) {
 	config := sarama.NewConfig()
 	config.Net.TLS.Enable = e.WithTLS
@@ -392,19 +556,34 @@ func (e Executor) getKafkaConfig() (*sarama.Config, error) {
 // handler represents a Sarama consumer group consumer
 type handler struct {
 	withAVRO     bool
+	withSchema   bool
 	messages     []Message
 	messagesJSON []interface{}
 	markOffset   bool
 	messageLimit int
 	schemaReg    SchemaRegistry
-	keyFilter    string
+	filter       messageFilter
 	mutex        sync.Mutex
 	done         chan struct{}
 	once         sync.Once
+	// background buffers the messages instead, when the consumer runs in the background
+	background *background.Consumer[consumedMessage]
+	// ready is called once the consumer is positioned, when it runs in the background
+	ready func()
+	// pendingClaims counts the claims of the session whose starting offset is not resolved yet
+	pendingClaims atomic.Int32
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
 func (h *handler) Setup(s sarama.ConsumerGroupSession) error {
+	var claims int32
+	for _, partitions := range s.Claims() {
+		claims += int32(len(partitions))
+	}
+	h.pendingClaims.Store(claims)
+	if claims == 0 && h.ready != nil {
+		h.ready()
+	}
 	return nil
 }
 
@@ -417,6 +596,12 @@ func (h *handler) Cleanup(sarama.ConsumerGroupSession) error {
 func (h *handler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := session.Context()
 
+	// the starting offset of the claim is resolved before ConsumeClaim is called: the consumer is
+	// positioned once every claim of the session has reached this point
+	if h.pendingClaims.Add(-1) == 0 && h.ready != nil {
+		h.ready()
+	}
+
 	for message := range claim.Messages() {
 		// Stop consuming if one of the other handler goroutines already hit the message limit
 		select {
@@ -424,48 +609,64 @@ func (h *handler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama
 			return nil
 		default:
 		}
-		consumeFunction := h.consumeJSON
-		if h.withAVRO {
-			consumeFunction = h.consumeAVRO
-		}
-		msg, msgJSON, err := consumeFunction(message)
+		added, limitReached, err := h.handle(ctx, message)
 		if err != nil {
 			return err
 		}
-		// Pass filter
-		if h.keyFilter != "" && msg.Key != h.keyFilter {
-			venom.Info(ctx, "ignore message with key: %s", msg.Key)
-			continue
+
+		if added {
+			if h.markOffset {
+				session.MarkMessage(message, "")
+			}
+
+			session.MarkMessage(message, "delivered")
 		}
 
-		h.mutex.Lock()
-		// Check if message limit is hit *before* adding new message
-		messagesLen := len(h.messages)
-		if h.messageLimit > 0 && messagesLen >= h.messageLimit {
-			h.mutex.Unlock()
+		if limitReached {
 			h.messageLimitReached(ctx)
 			return nil
 		}
+	}
 
-		h.messages = append(h.messages, msg)
-		h.messagesJSON = append(h.messagesJSON, msgJSON)
-		h.mutex.Unlock()
-		messagesLen++
+	return nil
+}
 
-		if h.markOffset {
-			session.MarkMessage(message, "")
-		}
+// handle decodes the message and adds it to the consumed messages if it passes the filter.
+// It returns whether the message has been added, and whether the message limit is reached.
+func (h *handler) handle(ctx context.Context, message *sarama.ConsumerMessage) (bool, bool, error) {
+	consumeFunction := h.consumeJSON
+	if h.withAVRO {
+		consumeFunction = h.consumeAVRO
+	} else if h.withSchema {
+		consumeFunction = h.consumeTyped
+	}
+	msg, msgJSON, err := consumeFunction(message)
+	if err != nil {
+		return false, false, err
+	}
+	// Pass filter
+	if !h.filter.match(msg, msgJSON) {
+		venom.Info(ctx, "ignore message with key: %s not matching the filters", msg.Key)
+		return false, false, nil
+	}
 
-		session.MarkMessage(message, "delivered")
+	if h.background != nil {
+		h.background.Push(consumedMessage{msg: msg, msgJSON: msgJSON})
+		return true, false, nil
+	}
 
-		// Check if the message limit is hit
-		if h.messageLimit > 0 && messagesLen >= h.messageLimit {
-			h.messageLimitReached(ctx)
-			return nil
-		}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	// Check if message limit is hit *before* adding new message
+	if h.messageLimit > 0 && len(h.messages) >= h.messageLimit {
+		return false, true, nil
 	}
 
-	return nil
+	h.messages = append(h.messages, msg)
+	h.messagesJSON = append(h.messagesJSON, msgJSON)
+
+	// Check if the message limit is hit
+	return true, h.messageLimit > 0 && len(h.messages) >= h.messageLimit, nil
 }
 
 func (h *handler) messageLimitReached(ctx context.Context) {
@@ -477,27 +678,15 @@ func (h *handler) messageLimitReached(ctx context.Context) {
 }
 
 func (h *handler) consumeJSON(message *sarama.ConsumerMessage) (Message, interface{}, error) {
-	msg := Message{
-		Topic: message.Topic,
-		Key:   string(message.Key),
-		Value: string(message.Value),
-	}
-	msgJSON := MessageJSON{
-		Topic: message.Topic,
-	}
+	msg, msgJSON := newConsumedMessage(message)
+	msg.Value = string(message.Value)
 	convertFromMessage2JSON(&msg, &msgJSON)
 
 	return msg, msgJSON, nil
 }
 
 func (h *handler) consumeAVRO(message *sarama.ConsumerMessage) (Message, interface{}, error) {
-	msg := Message{
-		Topic: message.Topic,
-		Key:   string(message.Key),
-	}
-	msgJSON := MessageJSON{
-		Topic: message.Topic,
-	}
+	msg, msgJSON := newConsumedMessage(message)
 	// 1. Get Schema ID
 	avroMsg, schemaID := GetMessageAvroID(message.Value)
 	schema, err := h.schemaReg.GetSchemaByID(schemaID)
@@ -514,6 +703,63 @@ func (h *handler) consumeAVRO(message *sarama.ConsumerMessage) (Message, interfa
 	return msg, msgJSON, nil
 }
 
+// consumeTyped decodes a message encoded with the schema registered with its ID, whatever its type
+func (h *handler) consumeTyped(message *sarama.ConsumerMessage) (Message, interface{}, error) {
+	msg, msgJSON := newConsumedMessage(message)
+	if len(message.Value) < int(schemaIDSize)+1 || message.Value[0] != magicByte {
+		return msg, nil, fmt.Errorf("message at offset %d is not in the Schema Registry wire format", message.Offset)
+	}
+	// 1. Get Schema ID
+	value, schemaID := GetMessageAvroID(message.Value)
+	schema, schemaType, err := h.schemaReg.GetTypedSchemaByID(schemaID)
+	if err != nil {
+		return msg, nil, fmt.Errorf("can't get Schema with ID %d: %w", schemaID, err)
+	}
+	// 2. Decode the value
+	switch schemaType {
+	case schemaTypeProtobuf:
+		msg.Value, err = ConvertFromProtobuf(value, schema)
+	case schemaTypeJSON:
+		msg.Value = string(value)
+	default:
+		msg.Value, err = ConvertFromAvro(value, schema)
+	}
+	if err != nil {
+		return msg, nil, fmt.Errorf("can't get value from %s message: %w", schemaType, err)
+	}
+	convertFromMessage2JSON(&msg, &msgJSON)
+	return msg, msgJSON, nil
+}
+
+// newConsumedMessage returns the message with its metadata, without its value
+func newConsumedMessage(message *sarama.ConsumerMessage) (Message, MessageJSON) {
+	msg := Message{
+		Topic:     message.Topic,
+		Key:       string(message.Key),
+		Partition: message.Partition,
+		Offset:    message.Offset,
+	}
+	if !message.Timestamp.IsZero() {
+		msg.Timestamp = message.Timestamp.Format(time.RFC3339Nano)
+	}
+	if len(message.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(message.Headers))
+		for _, h := range message.Headers {
+			if h != nil {
+				msg.Headers[string(h.Key)] = string(h.Value)
+			}
+		}
+	}
+	msgJSON := MessageJSON{
+		Topic:     msg.Topic,
+		Headers:   msg.Headers,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+	}
+	return msg, msgJSON
+}
+
 func convertFromMessage2JSON(message *Message, msgJSON *MessageJSON) {
 	// unmarshall the message.Value
 	listMessageJSON := []MessageJSON{}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/ovh/venom"
+)
+
+// consumePartitions consumes the partitions of the topics without a consumer group,
+// starting at the offset or the timestamp of the step
+func (e Executor) consumePartitions(ctx context.Context, config *sarama.Config, h *handler) error {
+	client, err := sarama.NewClient(e.Addrs, config)
+	if err != nil {
+		return fmt.Errorf("error instantiate client err: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("error instantiate consumer err: %w", err)
+	}
+	defer func() { _ = consumer.Close() }()
+
+	// the partition consumers are closed before the consumer, their goroutines end with their channels
+	var partitionConsumers []sarama.PartitionConsumer
+	defer func() {
+		for _, pc := range partitionConsumers {
+			_ = pc.Close()
+		}
+	}()
+
+	// cancelled before closing the consumer, to ignore the errors raised by the closing
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	messages := make(chan *sarama.ConsumerMessage)
+	for _, topic := range e.Topics {
+		partitions := e.Partitions
+		if len(partitions) == 0 {
+			partitions, err = client.Partitions(topic)
+			if err != nil {
+				return fmt.Errorf("unable to get the partitions of topic %s: %w", topic, err)
+			}
+		}
+		for _, partition := range partitions {
+			offset, err := e.startOffset(client, topic, partition)
+			if err != nil {
+				return err
+			}
+			pc, err := consumer.ConsumePartition(topic, partition, offset)
+			if err != nil {
+				return fmt.Errorf("unable to consume partition %d of topic %s: %w", partition, topic, err)
+			}
+			partitionConsumers = append(partitionConsumers, pc)
+			venom.Debug(ctx, "consuming partition %d of topic %s from offset %d", partition, topic, offset)
+
+			go func() {
+				for err := range pc.Errors() {
+					if ctx.Err() == nil {
+						venom.Error(ctx, "error on consume:%s", err)
+					}
+				}
+			}()
+			go func() {
+				for message := range pc.Messages() {
+					select {
+					case messages <- message:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+	}
+	// ConsumePartition resolves the starting offset of each partition before returning
+	if h.ready != nil {
+		h.ready()
+	}
+
+	for {
+		select {
+		case message := <-messages:
+			_, limitReached, err := h.handle(ctx, message)
+			if err != nil {
+				return err
+			}
+			if limitReached {
+				venom.Info(ctx, "message limit reached")
+				return nil
+			}
+		case <-ctx.Done():
+			if e.WaitFor > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				venom.Info(ctx, "wait ended")
+				return nil
+			}
+			return fmt.Errorf("kafka consumed failed: %w", ctx.Err())
+		}
+	}
+}
+
+// startOffset returns the offset to start consuming the partition from
+func (e Executor) startOffset(client sarama.Client, topic string, partition int32) (int64, error) {
+	switch {
+	case e.Offset != nil:
+		return *e.Offset, nil
+	case e.Timestamp != "":
+		t, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q: %w", e.Timestamp, err)
+		}
+		// the offset of the first message produced at or after the timestamp, or the newest offset
+		offset, err := client.GetOffset(topic, partition, t.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("unable to get the offset of partition %d of topic %s at %s: %w", partition, topic, e.Timestamp, err)
+		}
+		return offset, nil
+	case strings.TrimSpace(e.InitialOffset) == "oldest":
+		return sarama.OffsetOldest, nil
+	default:
+		return sarama.OffsetNewest, nil
+	}
+}
+
+// messageFilter selects the consumed messages, empty fields match any message
+type messageFilter struct {
+	key     string
+	headers map[string]string
+	// values are the expected values by path in the JSON value
+	values map[string]interface{}
+}
+
+func (f messageFilter) match(msg Message, msgJSON interface{}) bool {
+	if f.key != "" && msg.Key != f.key {
+		return false
+	}
+	for k, v := range f.headers {
+		if value, ok := msg.Headers[k]; !ok || value != v {
+			return false
+		}
+	}
+	if len(f.values) == 0 {
+		return true
+	}
+	m, ok := msgJSON.(MessageJSON)
+	if !ok {
+		return false
+	}
+	for path, expected := range f.values {
+		value, ok := lookupJSONPath(m.Value, path)
+		if !ok || fmt.Sprint(value) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupJSONPath returns the value at the dot separated path, array items are selected by their index
+func lookupJSONPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			value, ok := t[key]
+			if !ok {
+				return nil, false
+			}
+			v = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
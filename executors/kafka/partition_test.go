@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestMessageFilter_Match(t *testing.T) {
+	msg := Message{Key: "order-1", Headers: map[string]string{"x-event": "created"}, Value: `{"order":{"id":42,"items":[{"sku":"abc"}]}}`}
+	msgJSON := MessageJSON{}
+	convertFromMessage2JSON(&msg, &msgJSON)
+
+	tests := []struct {
+		name   string
+		filter messageFilter
+		match  bool
+	}{
+		{name: "empty", filter: messageFilter{}, match: true},
+		{name: "key", filter: messageFilter{key: "order-1"}, match: true},
+		{name: "other key", filter: messageFilter{key: "order-2"}, match: false},
+		{name: "header", filter: messageFilter{headers: map[string]string{"x-event": "created"}}, match: true},
+		{name: "other header", filter: messageFilter{headers: map[string]string{"x-event": "deleted"}}, match: false},
+		{name: "missing header", filter: messageFilter{headers: map[string]string{"x-other": ""}}, match: false},
+		{name: "value", filter: messageFilter{values: map[string]interface{}{"order.id": 42, "order.items.0.sku": "abc"}}, match: true},
+		{name: "other value", filter: messageFilter{values: map[string]interface{}{"order.id": "43"}}, match: false},
+		{name: "missing value", filter: messageFilter{values: map[string]interface{}{"order.items.1.sku": "abc"}}, match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.filter.match(msg, msgJSON))
+		})
+	}
+}
+
+func TestExecutor_Run_Partitions(t *testing.T) {
+	venom.InitTestLogger(t)
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders", 0, sarama.OffsetNewest, 3).
+			SetOffset("orders", 0, since.UnixMilli(), 1),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 3).
+			SetMessageWithKey("orders", 0, 0, sarama.StringEncoder("order-1"), sarama.StringEncoder(`{"id":1,"status":"paid"}`)).
+			SetMessageWithKey("orders", 0, 1, sarama.StringEncoder("order-2"), sarama.StringEncoder(`{"id":2,"status":"created"}`)).
+			SetMessageWithKey("orders", 0, 2, sarama.StringEncoder("order-3"), sarama.StringEncoder(`{"id":3,"status":"paid"}`)).
+			SetHighWaterMark("orders", 0, 3),
+	})
+
+	step := venom.TestStep{
+		"clientType":   "consumer",
+		"addrs":        []string{broker.Addr()},
+		"topics":       []string{"orders"},
+		"partitions":   []int32{0},
+		"timestamp":    since.Format(time.RFC3339),
+		"valueFilter":  map[string]interface{}{"status": "paid"},
+		"messageLimit": 1,
+	}
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result := res.(Result)
+	require.Empty(t, result.Err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "order-3", result.Messages[0].Key)
+	assert.Equal(t, int32(0), result.Messages[0].Partition)
+	assert.Equal(t, int64(2), result.Messages[0].Offset)
+
+	// measured once the mock broker and the metrics of sarama have started their own goroutines
+	goroutines := runtime.NumGoroutine()
+
+	delete(step, "timestamp")
+	step["offset"] = 0
+	res, err = Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result = res.(Result)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "order-1", result.Messages[0].Key)
+
+	// the partition consumers opened before a failing partition are closed too
+	step["partitions"] = []int32{0, 7}
+	res, err = Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "unable to consume partition 7 of topic orders")
+
+	// the partition consumers and their goroutines are stopped with the step
+	assert.Eventually(t, func() bool { return runtime.NumGoroutine() <= goroutines }, time.Second, 10*time.Millisecond)
+
+	step["partitions"] = []int32{0}
+	step["groupID"] = "venom"
+	res, err = Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "can't be used with a consumer group")
+}
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const protobufSchemaFilename = "schema.proto"
+
+// protobufFiles caches the files compiled from the protobuf schemas
+var protobufFiles = struct {
+	sync.Mutex
+	files map[string]protoreflect.FileDescriptor
+}{files: map[string]protoreflect.FileDescriptor{}}
+
+// compileProtobufSchema compiles a protobuf schema, it can only import the well-known types
+func compileProtobufSchema(schema string) (protoreflect.FileDescriptor, error) {
+	protobufFiles.Lock()
+	defer protobufFiles.Unlock()
+	if fd, ok := protobufFiles.files[schema]; ok {
+		return fd, nil
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protobufSchemaFilename: schema}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protobufSchemaFilename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile Protobuf schema: %w", err)
+	}
+	protobufFiles.files[schema] = files[0]
+	return files[0], nil
+}
+
+// Convert2Protobuf will convert a JSON value to Protobuf encoded binary with help of schema,
+// prefixed by the indexes of the message in the schema.
+// The message is the full name of the message type, default is the first message of the schema.
+func Convert2Protobuf(value []byte, schema, message string) ([]byte, error) {
+	fd, err := compileProtobufSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	md, err := findProtobufMessage(fd, message)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(value, msg); err != nil {
+		return nil, fmt.Errorf("failed to convert value %s 2 Protobuf message %s: %w", value, md.FullName(), err)
+	}
+	btes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Protobuf message %s: %w", md.FullName(), err)
+	}
+	return append(encodeMessageIndexes(md), btes...), nil
+}
+
+// ConvertFromProtobuf will convert value from Protobuf encoded binary prefixed by the message indexes,
+// with help of schema, to JSON
+func ConvertFromProtobuf(value []byte, schema string) (string, error) {
+	fd, err := compileProtobufSchema(schema)
+	if err != nil {
+		return "", err
+	}
+	r := bytes.NewReader(value)
+	indexes, err := decodeMessageIndexes(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Protobuf message indexes: %w", err)
+	}
+
+	var md protoreflect.MessageDescriptor
+	messages := fd.Messages()
+	for _, i := range indexes {
+		if i < 0 || i >= messages.Len() {
+			return "", fmt.Errorf("invalid Protobuf message indexes %v", indexes)
+		}
+		md = messages.Get(i)
+		messages = md.Messages()
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(value[len(value)-r.Len():], msg); err != nil {
+		return "", fmt.Errorf("failed to decode Protobuf message %s: %w", md.FullName(), err)
+	}
+	btes, err := protojson.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert Protobuf message %s 2 JSON: %w", md.FullName(), err)
+	}
+	return string(btes), nil
+}
+
+// findProtobufMessage returns the message type by its name, qualified or relative to the package of the schema
+func findProtobufMessage(fd protoreflect.FileDescriptor, message string) (protoreflect.MessageDescriptor, error) {
+	if message == "" {
+		if fd.Messages().Len() == 0 {
+			return nil, fmt.Errorf("no message in Protobuf schema")
+		}
+		return fd.Messages().Get(0), nil
+	}
+	name := protoreflect.FullName(message)
+	if pkg := string(fd.Package()); pkg != "" && !strings.HasPrefix(message, pkg+".") {
+		name = protoreflect.FullName(pkg + "." + message)
+	}
+	if md := lookupProtobufMessage(fd.Messages(), name); md != nil {
+		return md, nil
+	}
+	return nil, fmt.Errorf("message %s not found in Protobuf schema", name)
+}
+
+func lookupProtobufMessage(messages protoreflect.MessageDescriptors, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		if md.FullName() == name {
+			return md
+		}
+		if nested := lookupProtobufMessage(md.Messages(), name); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
+
+// encodeMessageIndexes returns the path of the message in the schema, as zigzag varints prefixed by their count.
+// The path of the first message is encoded as a single 0.
+func encodeMessageIndexes(md protoreflect.MessageDescriptor) []byte {
+	var indexes []int64
+	for d := protoreflect.Descriptor(md); d != nil; d = d.Parent() {
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		indexes = append([]int64{int64(d.Index())}, indexes...)
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return []byte{0}
+	}
+	buf := binary.AppendVarint(nil, int64(len(indexes)))
+	for _, i := range indexes {
+		buf = binary.AppendVarint(buf, i)
+	}
+	return buf
+}
+
+func decodeMessageIndexes(r io.ByteReader) ([]int, error) {
+	count, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return []int{0}, nil
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid count %d", count)
+	}
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		index, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, int(index))
+	}
+	return indexes, nil
+}
@@ -1,8 +1,13 @@
 package kafka
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	schemaregistry "github.com/landoop/schema-registry"
@@ -14,11 +19,32 @@ type (
 		GetSchemaByID(id int) (string, error)
 		RegisterNewSchema(subject, schema string) (int, error)
 		GetLatestSchema(subject string) (int, string, error)
+		// The typed variants handle the schema types other than Avro: PROTOBUF and JSON
+		GetTypedSchemaByID(id int) (string, string, error)
+		RegisterNewTypedSchema(subject, schema, schemaType string) (int, error)
+		GetLatestTypedSchema(subject string) (int, string, string, error)
 	}
 
 	client struct {
-		client *schemaregistry.Client
+		client     *schemaregistry.Client
+		host       string
+		httpClient *http.Client
 	}
+
+	// registrySchema is a schema as returned by the Schema Registry API
+	registrySchema struct {
+		ID         int    `json:"id,omitempty"`
+		Schema     string `json:"schema,omitempty"`
+		SchemaType string `json:"schemaType,omitempty"`
+	}
+)
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
 )
 
 // NewSchemaRegistry will create new Schema Registry interface
@@ -33,8 +59,14 @@ func NewWithClient(schemaRegistryHost string, httpClient *http.Client) (SchemaRe
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to schema registry: %w", err)
 	}
+	host := strings.TrimSuffix(schemaRegistryHost, "/")
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
 	return &client{
-		client: schemaRegistryClient,
+		client:     schemaRegistryClient,
+		host:       host,
+		httpClient: httpClient,
 	}, nil
 }
 
@@ -66,3 +98,71 @@ func (c client) GetLatestSchema(subject string) (int, string, error) {
 
 	return schema.ID, schema.Schema, nil
 }
+
+// GetTypedSchemaByID will return schema and its type from SchemaRegistry by it's ID (if it exists there)
+func (c client) GetTypedSchemaByID(id int) (string, string, error) {
+	var schema registrySchema
+	if err := c.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &schema); err != nil {
+		return "", "", fmt.Errorf("could not get schema id %d from schema registry: %w", id, err)
+	}
+	return schema.Schema, typeOf(schema), nil
+}
+
+// RegisterNewTypedSchema either register a new schema of the given type and return the ID or get the ID of an already created schema.
+func (c client) RegisterNewTypedSchema(subject, schema, schemaType string) (int, error) {
+	var registered registrySchema
+	err := c.do(http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), registrySchema{Schema: schema, SchemaType: schemaType}, &registered)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register new schema or fetch already created schema ID: %w", err)
+	}
+	return registered.ID, nil
+}
+
+// GetLatestTypedSchema gets latest schema identifier, schema and schema type from the given subject.
+func (c client) GetLatestTypedSchema(subject string) (int, string, string, error) {
+	var schema registrySchema
+	if err := c.do(http.MethodGet, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), nil, &schema); err != nil {
+		return 0, "", "", fmt.Errorf("failed to get latest schema ID: %w", err)
+	}
+	return schema.ID, schema.Schema, typeOf(schema), nil
+}
+
+// typeOf returns the type of the schema, the Schema Registry omits it for Avro schemas
+func typeOf(schema registrySchema) string {
+	if schema.SchemaType == "" {
+		return schemaTypeAvro
+	}
+	return schema.SchemaType
+}
+
+func (c client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		btes, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(btes)
+	}
+	req, err := http.NewRequest(method, c.host+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if in != nil {
+		req.Header.Set("Content-Type", schemaRegistryContentType)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	btes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("schema registry returned %d: %s", resp.StatusCode, btes)
+	}
+	return json.Unmarshal(btes, out)
+}
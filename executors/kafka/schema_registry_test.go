@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// fakeSchemaRegistry is a local stand-in of the Schema Registry API
+type fakeSchemaRegistry struct {
+	mutex    sync.Mutex
+	schemas  []registrySchema
+	subjects map[string][]int
+}
+
+func newFakeSchemaRegistry(t *testing.T) string {
+	r := &fakeSchemaRegistry{subjects: map[string][]int{}}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func (r *fakeSchemaRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[0] == "subjects" && parts[2] == "versions":
+		var schema registrySchema
+		if err := json.NewDecoder(req.Body).Decode(&schema); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, id := range r.subjects[parts[1]] {
+			if s := r.schemas[id-1]; s.Schema == schema.Schema && s.SchemaType == schema.SchemaType {
+				_ = json.NewEncoder(w).Encode(registrySchema{ID: id})
+				return
+			}
+		}
+		schema.ID = len(r.schemas) + 1
+		r.schemas = append(r.schemas, schema)
+		r.subjects[parts[1]] = append(r.subjects[parts[1]], schema.ID)
+		_ = json.NewEncoder(w).Encode(registrySchema{ID: schema.ID})
+	case req.Method == http.MethodGet && len(parts) == 3 && parts[0] == "schemas" && parts[1] == "ids":
+		id, err := strconv.Atoi(parts[2])
+		if err != nil || id < 1 || id > len(r.schemas) {
+			http.NotFound(w, req)
+			return
+		}
+		s := r.schemas[id-1]
+		_ = json.NewEncoder(w).Encode(registrySchema{Schema: s.Schema, SchemaType: s.SchemaType})
+	case req.Method == http.MethodGet && len(parts) == 4 && parts[0] == "subjects" && parts[3] == "latest":
+		ids := r.subjects[parts[1]]
+		if len(ids) == 0 {
+			http.NotFound(w, req)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(r.schemas[ids[len(ids)-1]-1])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+const orderProto = `syntax = "proto3";
+
+package shop;
+
+message Customer {
+  string name = 1;
+}
+
+message Order {
+  message Item {
+    string sku = 1;
+    int32 quantity = 2;
+  }
+  int64 id = 1;
+  repeated Item items = 2;
+}
+`
+
+const orderJSONSchema = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "status": {"type": "string", "enum": ["created", "paid"]}
+  },
+  "required": ["id"]
+}`
+
+func TestExecutor_TypedSchemas(t *testing.T) {
+	venom.InitTestLogger(t)
+	workdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "order.proto"), []byte(orderProto), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "order.json"), []byte(orderJSONSchema), 0o644))
+
+	schemaReg, err := NewSchemaRegistry(newFakeSchemaRegistry(t))
+	require.NoError(t, err)
+
+	consume := func(t *testing.T, value []byte) MessageJSON {
+		h := &handler{withSchema: true, schemaReg: schemaReg}
+		_, msgJSON, err := h.consumeTyped(&sarama.ConsumerMessage{Topic: "orders", Value: value})
+		require.NoError(t, err)
+		return msgJSON.(MessageJSON)
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		var e Executor
+		require.NoError(t, decodeStep(venom.TestStep{
+			"withProtobuf": true,
+			"messages": []interface{}{
+				map[string]interface{}{
+					"topic":              "orders",
+					"protobufSchemaFile": "order.proto",
+					"protobufMessage":    "Order",
+					"value":              map[string]interface{}{"id": 42, "items": []interface{}{map[string]interface{}{"sku": "abc", "quantity": 2}}},
+				},
+				map[string]interface{}{"topic": "orders", "protobufMessage": "shop.Order.Item", "value": `{"sku":"def"}`},
+			},
+		}, &e))
+		e.schemaReg = schemaReg
+
+		value, err := e.getMessageValue(&e.Messages[0], workdir)
+		require.NoError(t, err)
+		// magic byte, schema ID 1, message indexes [1]
+		assert.Equal(t, []byte{0, 0, 0, 0, 1, 2, 2}, value[:7])
+		assert.Equal(t, map[string]interface{}{
+			"id":    "42",
+			"items": []interface{}{map[string]interface{}{"sku": "abc", "quantity": json.Number("2")}},
+		}, consume(t, value).Value)
+
+		// the latest schema of the subject is used without schema file
+		value, err = e.getMessageValue(&e.Messages[1], workdir)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 0, 0, 0, 1, 4, 2, 0}, value[:8])
+		assert.Equal(t, map[string]interface{}{"sku": "def"}, consume(t, value).Value)
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		e := Executor{WithJSONSchema: true, schemaReg: schemaReg}
+		msg := Message{Topic: "payments", JSONSchemaFile: "order.json", Value: `{"id":1,"status":"paid"}`}
+		value, err := e.getMessageValue(&msg, workdir)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 0, 0, 0, 2}, value[:5])
+		assert.Equal(t, map[string]interface{}{"id": json.Number("1"), "status": "paid"}, consume(t, value).Value)
+
+		msg.Value = `{"id":1,"status":"unknown"}`
+		_, err = e.getMessageValue(&msg, workdir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not valid against JSON schema")
+
+		msg = Message{Topic: "orders", Value: `{"id":1}`}
+		_, err = e.getMessageValue(&msg, workdir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "latest schema for subject orders-value is PROTOBUF, not JSON")
+	})
+}
+
+func TestExecutor_Run_SchemaRegistryAddr(t *testing.T) {
+	for _, flag := range []string{"withAVRO", "withProtobuf", "withJSONSchema"} {
+		_, err := Executor{}.Run(context.Background(), venom.TestStep{"clientType": "consumer", "topics": []string{"orders"}, flag: true})
+		assert.EqualError(t, err, "schemaRegistryAddr is mandatory with withAVRO, withProtobuf or withJSONSchema", flag)
+	}
+}
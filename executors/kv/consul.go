@@ -0,0 +1,141 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+type consulStore struct {
+	kv *consul.KV
+}
+
+func newConsul(ctx context.Context, e Executor) (store, error) {
+	config := consul.DefaultConfig()
+	if len(e.Endpoints) > 0 {
+		config.Address = e.Endpoints[0]
+	}
+	if e.Token != "" {
+		config.Token = e.Token
+	}
+	client, err := consul.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &consulStore{kv: client.KV()}, nil
+}
+
+func consulKeyValue(pair *consul.KVPair) KeyValue {
+	return KeyValue{Key: pair.Key, Value: string(pair.Value), Revision: int64(pair.ModifyIndex)}
+}
+
+func (s *consulStore) get(ctx context.Context, key string) (*KeyValue, error) {
+	pair, _, err := s.kv.Get(key, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, nil
+	}
+	kv := consulKeyValue(pair)
+	return &kv, nil
+}
+
+// put returns the modify index of the key, read after the put as consul does not return it
+func (s *consulStore) put(ctx context.Context, key, value string) (int64, error) {
+	if _, err := s.kv.Put(&consul.KVPair{Key: key, Value: []byte(value)}, (&consul.WriteOptions{}).WithContext(ctx)); err != nil {
+		return 0, err
+	}
+	kv, err := s.get(ctx, key)
+	if err != nil || kv == nil {
+		return 0, err
+	}
+	return kv.Revision, nil
+}
+
+// delete returns the number of keys read before the delete, as consul does not return it
+func (s *consulStore) delete(ctx context.Context, key string, prefix bool) (int64, error) {
+	w := (&consul.WriteOptions{}).WithContext(ctx)
+	if prefix {
+		keys, _, err := s.kv.Keys(key, "", (&consul.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := s.kv.DeleteTree(key, w); err != nil {
+			return 0, err
+		}
+		return int64(len(keys)), nil
+	}
+
+	kv, err := s.get(ctx, key)
+	if err != nil || kv == nil {
+		return 0, err
+	}
+	if _, err := s.kv.Delete(key, w); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (s *consulStore) list(ctx context.Context, prefix string) ([]KeyValue, error) {
+	pairs, _, err := s.kv.List(prefix, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	// consul returns the keys sorted
+	kvs := make([]KeyValue, len(pairs))
+	for i, pair := range pairs {
+		kvs[i] = consulKeyValue(pair)
+	}
+	return kvs, nil
+}
+
+// watch runs blocking queries on the key until its modify index is after the revision
+func (s *consulStore) watch(ctx context.Context, key string, revision int64) (*event, error) {
+	q := (&consul.QueryOptions{}).WithContext(ctx)
+	pair, meta, err := s.kv.Get(key, q)
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	// a missing key is reported deleted only once it was seen with a modify index: consul keeps no trace of the
+	// deleted keys, so a key deleted before the watch cannot be told apart from a key that never existed
+	existed := pair != nil
+	if revision == 0 {
+		revision = int64(meta.LastIndex)
+	}
+
+	for {
+		if pair != nil && int64(pair.ModifyIndex) > revision {
+			return &event{kv: consulKeyValue(pair)}, nil
+		}
+		if pair == nil && existed && int64(meta.LastIndex) > revision {
+			return &event{deleted: true, kv: KeyValue{Key: key, Revision: int64(meta.LastIndex)}}, nil
+		}
+		existed = existed || pair != nil
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+
+		q.WaitIndex = meta.LastIndex
+		if deadline, ok := ctx.Deadline(); ok {
+			q.WaitTime = time.Until(deadline)
+		}
+		pair, meta, err = s.kv.Get(key, q)
+		if err != nil {
+			return nil, ctxErr(ctx, err)
+		}
+	}
+}
+
+// ctxErr returns nil if the error is due to the end of the context
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (s *consulStore) close() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consulResponse is a response of the consul KV endpoint, the key is missing without ModifyIndex
+type consulResponse struct {
+	index       int
+	modifyIndex int
+}
+
+// newConsulServer returns a consul server answering the key reads with the responses in order, then with the last one
+func newConsulServer(t *testing.T, responses ...consulResponse) *httptest.Server {
+	var mutex sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		resp := responses[0]
+		if len(responses) > 1 {
+			responses = responses[1:]
+		}
+		mutex.Unlock()
+		// a blocking query without change waits a bit before returning the same index
+		if r.URL.Query().Get("index") == strconv.Itoa(resp.index) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		w.Header().Set("X-Consul-Index", strconv.Itoa(resp.index))
+		if resp.modifyIndex == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"Key": "flags/beta", "Value": []byte("on"), "ModifyIndex": resp.modifyIndex},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestConsulStore_Watch(t *testing.T) {
+	watch := func(srv *httptest.Server, revision int64) *event {
+		s, err := newConsul(context.Background(), Executor{Endpoints: []string{srv.URL}})
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		ev, err := s.watch(ctx, "flags/beta", revision)
+		require.NoError(t, err)
+		return ev
+	}
+
+	// the key does not exist, the index of the other changes is not a delete
+	ev := watch(newConsulServer(t, consulResponse{index: 10}), 5)
+	assert.Nil(t, ev)
+
+	ev = watch(newConsulServer(t, consulResponse{index: 3, modifyIndex: 3}, consulResponse{index: 7}), 3)
+	require.NotNil(t, ev)
+	assert.Equal(t, event{deleted: true, kv: KeyValue{Key: "flags/beta", Revision: 7}}, *ev)
+
+	ev = watch(newConsulServer(t, consulResponse{index: 10}, consulResponse{index: 12, modifyIndex: 12}), 5)
+	require.NotNil(t, ev)
+	assert.Equal(t, event{kv: KeyValue{Key: "flags/beta", Value: "on", Revision: 12}}, *ev)
+}
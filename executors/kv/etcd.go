@@ -0,0 +1,114 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type etcdStore struct {
+	client *clientv3.Client
+}
+
+func newEtcd(ctx context.Context, e Executor) (store, error) {
+	endpoints := e.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []string{"localhost:2379"}
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Context:     ctx,
+		Endpoints:   endpoints,
+		Username:    e.Username,
+		Password:    e.Password,
+		DialTimeout: time.Duration(e.Timeout) * time.Second,
+		Logger:      zap.NewNop(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &etcdStore{client: client}, nil
+}
+
+func etcdKeyValue(kv *mvccpb.KeyValue) KeyValue {
+	return KeyValue{Key: string(kv.Key), Value: string(kv.Value), Revision: kv.ModRevision}
+}
+
+func (s *etcdStore) get(ctx context.Context, key string) (*KeyValue, error) {
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	kv := etcdKeyValue(resp.Kvs[0])
+	return &kv, nil
+}
+
+func (s *etcdStore) put(ctx context.Context, key, value string) (int64, error) {
+	resp, err := s.client.Put(ctx, key, value)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Header.Revision, nil
+}
+
+func (s *etcdStore) delete(ctx context.Context, key string, prefix bool) (int64, error) {
+	var opts []clientv3.OpOption
+	if prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	resp, err := s.client.Delete(ctx, key, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+func (s *etcdStore) list(ctx context.Context, prefix string) ([]KeyValue, error) {
+	opts := []clientv3.OpOption{clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend)}
+	if prefix == "" {
+		opts = append(opts, clientv3.WithFromKey())
+		prefix = "\x00"
+	} else {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	resp, err := s.client.Get(ctx, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]KeyValue, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		kvs[i] = etcdKeyValue(kv)
+	}
+	return kvs, nil
+}
+
+func (s *etcdStore) watch(ctx context.Context, key string, revision int64) (*event, error) {
+	var opts []clientv3.OpOption
+	if revision > 0 {
+		// the events already happened since the revision are replayed
+		opts = append(opts, clientv3.WithRev(revision+1))
+	}
+	for resp := range s.client.Watch(clientv3.WithRequireLeader(ctx), key, opts...) {
+		if err := resp.Err(); err != nil {
+			return nil, err
+		}
+		for _, ev := range resp.Events {
+			kv := etcdKeyValue(ev.Kv)
+			if ev.Type == mvccpb.DELETE {
+				return &event{deleted: true, kv: kv}, nil
+			}
+			return &event{kv: kv}, nil
+		}
+	}
+	// the channel is closed when the context is done
+	return nil, nil
+}
+
+func (s *etcdStore) close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,236 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/jsondecode"
+)
+
+// Name of the executor.
+const Name = "kv"
+
+const defaultTimeout = 5
+
+// New returns a new executor that reads and writes the keys of a key-value store
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor is a venom executor that gets, puts, deletes, lists and watches the keys of a key-value store,
+// with the same results for all the backends
+type Executor struct {
+	// Backend is etcd or consul
+	Backend string `json:"backend" yaml:"backend"`
+	// Endpoints of the store, localhost:2379 for etcd and localhost:8500 for consul by default
+	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// Username and Password of etcd, Token of consul
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"`
+	// Action is get, put, delete, list or watch
+	Action string `json:"action" yaml:"action"`
+	// Key of the action, the prefix of the keys for list
+	Key string `json:"key" yaml:"key"`
+	// Value to put, the values that are not strings are put as JSON
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	// Prefix deletes all the keys starting with Key
+	Prefix bool `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Revision is the revision after which watch waits for a change. Without revision, watch waits for the next change
+	Revision int64 `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// Timeout of the connection and of the action, in seconds. Default 5
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// KeyValue is a key of the store
+type KeyValue struct {
+	Key       string      `json:"key" yaml:"key"`
+	Value     string      `json:"value" yaml:"value"`
+	ValueJSON interface{} `json:"valuejson,omitempty" yaml:"valuejson,omitempty"`
+	// Revision is the revision of the last change of the key: the modification revision of etcd, the modify index of consul
+	Revision int64 `json:"revision" yaml:"revision"`
+}
+
+// Result represents a step result.
+type Result struct {
+	// Found, Key, Value, ValueJSON and Revision are the key read by get and watch
+	Found     bool        `json:"found" yaml:"found"`
+	Key       string      `json:"key,omitempty" yaml:"key,omitempty"`
+	Value     string      `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueJSON interface{} `json:"valuejson,omitempty" yaml:"valuejson,omitempty"`
+	Revision  int64       `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// Keys are the keys listed, sorted by key, and Count their number
+	Keys  []KeyValue `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Count int        `json:"count" yaml:"count"`
+	// Deleted is the number of keys deleted
+	Deleted int64 `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+	// Changed tells whether watch saw a change before the timeout, and Event is put or delete
+	Changed bool   `json:"changed,omitempty" yaml:"changed,omitempty"`
+	Event   string `json:"event,omitempty" yaml:"event,omitempty"`
+}
+
+// event is a change of a key seen by a watch
+type event struct {
+	// deleted is false for a put
+	deleted bool
+	kv      KeyValue
+}
+
+// store is a backend of the executor
+type store interface {
+	// get returns nil if the key does not exist
+	get(ctx context.Context, key string) (*KeyValue, error)
+	// put returns the revision of the key
+	put(ctx context.Context, key, value string) (int64, error)
+	// delete returns the number of keys deleted
+	delete(ctx context.Context, key string, prefix bool) (int64, error)
+	list(ctx context.Context, prefix string) ([]KeyValue, error)
+	// watch waits for the first change of the key after the revision, or after now without revision.
+	// It returns nil if the context is done before a change
+	watch(ctx context.Context, key string, revision int64) (*event, error)
+	close() error
+}
+
+// backends are the constructors of the stores by backend name
+var backends = map[string]func(ctx context.Context, e Executor) (store, error){
+	"etcd":   newEtcd,
+	"consul": newConsul,
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// Run implements the venom.Executor interface for Executor.
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	// the revision is usually interpolated from a variable, as a string
+	var e Executor
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, Result: &e})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(step); err != nil {
+		return nil, err
+	}
+
+	newStore, ok := backends[e.Backend]
+	if !ok {
+		names := make([]string, 0, len(backends))
+		for name := range backends {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("backend %q must be one of %v", e.Backend, names)
+	}
+	if e.Key == "" && e.Action != "list" {
+		return nil, fmt.Errorf("key is mandatory")
+	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeout
+	}
+	timeout := time.Duration(e.Timeout) * time.Second
+
+	s, err := newStore(ctx, e)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", e.Backend, err)
+	}
+	defer s.close()
+
+	// the actions end with the timeout, watch returns without change
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := Result{}
+	switch e.Action {
+	case "get":
+		kv, err := s.get(ctx, e.Key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get key %q: %w", e.Key, err)
+		}
+		if kv != nil {
+			result.setKey(ctx, *kv)
+		}
+
+	case "put":
+		value, err := stringValue(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		revision, err := s.put(ctx, e.Key, value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to put key %q: %w", e.Key, err)
+		}
+		result.setKey(ctx, KeyValue{Key: e.Key, Value: value, Revision: revision})
+
+	case "delete":
+		result.Deleted, err = s.delete(ctx, e.Key, e.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("unable to delete key %q: %w", e.Key, err)
+		}
+
+	case "list":
+		kvs, err := s.list(ctx, e.Key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list keys %q: %w", e.Key, err)
+		}
+		result.Keys = make([]KeyValue, len(kvs))
+		for i, kv := range kvs {
+			kv.ValueJSON = jsondecode.Value(ctx, []byte(kv.Value))
+			result.Keys[i] = kv
+		}
+		result.Count = len(kvs)
+
+	case "watch":
+		ev, err := s.watch(ctx, e.Key, e.Revision)
+		if err != nil {
+			return nil, fmt.Errorf("unable to watch key %q: %w", e.Key, err)
+		}
+		if ev != nil {
+			result.Changed = true
+			result.Event = "put"
+			if ev.deleted {
+				result.Event = "delete"
+				result.Key, result.Revision = ev.kv.Key, ev.kv.Revision
+			} else {
+				result.setKey(ctx, ev.kv)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("action %q must be get, put, delete, list or watch", e.Action)
+	}
+	return result, nil
+}
+
+// setKey sets the key read or written by the action
+func (r *Result) setKey(ctx context.Context, kv KeyValue) {
+	r.Found = true
+	r.Count = 1
+	r.Key = kv.Key
+	r.Value = kv.Value
+	r.ValueJSON = jsondecode.Value(ctx, []byte(kv.Value))
+	r.Revision = kv.Revision
+}
+
+// stringValue returns the value to put, as JSON if it is not a string
+func stringValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("unable to encode value: %w", err)
+		}
+		return string(b), nil
+	}
+}
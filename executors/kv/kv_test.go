@@ -0,0 +1,156 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// memoryStore is a store keeping the keys in memory, with a revision incremented by each change
+type memoryStore struct {
+	mutex    sync.Mutex
+	revision int64
+	keys     map[string]KeyValue
+	events   []event
+}
+
+func (s *memoryStore) get(_ context.Context, key string) (*KeyValue, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	kv, ok := s.keys[key]
+	if !ok {
+		return nil, nil
+	}
+	return &kv, nil
+}
+
+func (s *memoryStore) put(_ context.Context, key, value string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.revision++
+	kv := KeyValue{Key: key, Value: value, Revision: s.revision}
+	s.keys[key] = kv
+	s.events = append(s.events, event{kv: kv})
+	return s.revision, nil
+}
+
+func (s *memoryStore) delete(_ context.Context, key string, prefix bool) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var deleted int64
+	for k := range s.keys {
+		if k == key || (prefix && strings.HasPrefix(k, key)) {
+			s.revision++
+			delete(s.keys, k)
+			s.events = append(s.events, event{deleted: true, kv: KeyValue{Key: k, Revision: s.revision}})
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) list(_ context.Context, prefix string) ([]KeyValue, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var kvs []KeyValue
+	for k, kv := range s.keys {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func (s *memoryStore) watch(ctx context.Context, key string, revision int64) (*event, error) {
+	s.mutex.Lock()
+	if revision == 0 {
+		revision = s.revision
+	}
+	s.mutex.Unlock()
+	for {
+		s.mutex.Lock()
+		for _, ev := range s.events {
+			if ev.kv.Key == key && ev.kv.Revision > revision {
+				s.mutex.Unlock()
+				return &ev, nil
+			}
+		}
+		s.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (s *memoryStore) close() error {
+	return nil
+}
+
+func TestExecutor_Run(t *testing.T) {
+	venom.InitTestLogger(t)
+	s := &memoryStore{keys: map[string]KeyValue{}}
+	backends["memory"] = func(context.Context, Executor) (store, error) { return s, nil }
+	t.Cleanup(func() { delete(backends, "memory") })
+
+	run := func(step venom.TestStep) Result {
+		step["backend"] = "memory"
+		result, err := Executor{}.Run(context.Background(), step)
+		require.NoError(t, err)
+		return result.(Result)
+	}
+
+	result := run(venom.TestStep{"action": "put", "key": "services/api", "value": map[string]interface{}{"port": 8080}})
+	assert.Equal(t, `{"port":8080}`, result.Value)
+	assert.Equal(t, int64(1), result.Revision)
+	run(venom.TestStep{"action": "put", "key": "services/web", "value": "enabled"})
+
+	result = run(venom.TestStep{"action": "get", "key": "services/api"})
+	assert.True(t, result.Found)
+	assert.Equal(t, map[string]interface{}{"port": json.Number("8080")}, result.ValueJSON)
+
+	result = run(venom.TestStep{"action": "get", "key": "services/db"})
+	assert.False(t, result.Found)
+	assert.Equal(t, 0, result.Count)
+
+	result = run(venom.TestStep{"action": "list", "key": "services/"})
+	require.Equal(t, 2, result.Count)
+	assert.Equal(t, "services/api", result.Keys[0].Key)
+	assert.Equal(t, "enabled", result.Keys[1].Value)
+	assert.Nil(t, result.Keys[1].ValueJSON)
+
+	// the change happened after the revision of the get
+	run(venom.TestStep{"action": "put", "key": "services/web", "value": "disabled"})
+	result = run(venom.TestStep{"action": "watch", "key": "services/web", "revision": "2"})
+	assert.True(t, result.Changed)
+	assert.Equal(t, "put", result.Event)
+	assert.Equal(t, "disabled", result.Value)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = s.delete(context.Background(), "services/web", false)
+	}()
+	result = run(venom.TestStep{"action": "watch", "key": "services/web"})
+	assert.True(t, result.Changed)
+	assert.Equal(t, "delete", result.Event)
+
+	result = run(venom.TestStep{"action": "watch", "key": "services/web", "timeout": 1})
+	assert.False(t, result.Changed)
+
+	result = run(venom.TestStep{"action": "delete", "key": "services/", "prefix": true})
+	assert.Equal(t, int64(1), result.Deleted)
+
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"backend": "zookeeper", "action": "get", "key": "a"})
+	assert.EqualError(t, err, `backend "zookeeper" must be one of [consul etcd memory]`)
+}
@@ -0,0 +1,234 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+var (
+	_ venom.Executor          = new(Executor)
+	_ venom.ExecutorWithSetup = new(Executor)
+)
+
+const (
+	// Name of executor
+	Name = "mockserver"
+	// ContextKey is the key of the servers in the testcase context
+	ContextKey = venom.ContextKey("mockserverContext")
+
+	defaultServer    = "default"
+	defaultHost      = "127.0.0.1"
+	defaultTimeoutMs = 5000
+)
+
+// New returns a new Executor
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor represents a Test Exec
+type Executor struct {
+	// Action is one of start (default), stub, requests, wait, reset or stop
+	Action string `json:"action" yaml:"action"`
+	// Server is the name of the server, several servers can run in the same testcase
+	Server string `json:"server" yaml:"server"`
+
+	// Used when Action is start
+	Host string `json:"host" yaml:"host"`
+	// Port is the port to listen on, a random port is chosen when empty
+	Port int `json:"port" yaml:"port"`
+
+	// Used when Action is start or stub
+	Stubs []Stub `json:"stubs" yaml:"stubs"`
+
+	// Used when Action is requests or wait, filters the recorded requests
+	Match *Matcher `json:"match" yaml:"match"`
+	// Used when Action is wait, time to wait for a matching request. In Milliseconds. Default 5000
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Stub is a response served to the requests it matches
+type Stub struct {
+	Matcher  `json:",squash" yaml:",inline" mapstructure:",squash"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+// Matcher matches incoming requests, empty fields match any value
+type Matcher struct {
+	Method  string            `json:"method" yaml:"method"`
+	Path    string            `json:"path" yaml:"path"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	// Body must be equal to the request body, JSON bodies are compared as JSON
+	Body string `json:"body" yaml:"body"`
+	// BodyContains must be contained in the request body
+	BodyContains string `json:"bodyContains" yaml:"bodyContains"`
+}
+
+// Response is the stubbed response
+type Response struct {
+	StatusCode int               `json:"statusCode" yaml:"statusCode"`
+	Headers    map[string]string `json:"headers" yaml:"headers"`
+	Body       string            `json:"body" yaml:"body"`
+	// Delay before sending the response. In Milliseconds
+	Delay int64 `json:"delay" yaml:"delay"`
+}
+
+// Result represents a step result
+type Result struct {
+	URL      string    `json:"url,omitempty" yaml:"url,omitempty"`
+	Port     int       `json:"port,omitempty" yaml:"port,omitempty"`
+	Requests []Request `json:"requests" yaml:"requests"`
+
+	// The request captured by the wait action
+	Method   string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Path     string            `json:"path,omitempty" yaml:"path,omitempty"`
+	Query    string            `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body     string            `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyJSON interface{}       `json:"bodyjson,omitempty" yaml:"bodyjson,omitempty"`
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+type servers struct {
+	sync.Mutex
+	servers map[string]*server
+}
+
+// Setup prepares the servers of the testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, &servers{servers: map[string]*server{}}), nil
+}
+
+// TearDown stops the servers left running by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	store := getServers(ctx)
+	if store == nil {
+		return nil
+	}
+	store.Lock()
+	defer store.Unlock()
+	for name, srv := range store.servers {
+		venom.Debug(ctx, "stopping mock server %q", name)
+		if err := srv.close(); err != nil {
+			return errors.Wrapf(err, "unable to stop mock server %q", name)
+		}
+		delete(store.servers, name)
+	}
+	return nil
+}
+
+func getServers(ctx context.Context) *servers {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*servers)
+}
+
+// Run execute TestStep
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	var e Executor
+	if err := mapstructure.Decode(step, &e); err != nil {
+		return nil, err
+	}
+	if e.Server == "" {
+		e.Server = defaultServer
+	}
+
+	store := getServers(ctx)
+	if store == nil {
+		return nil, errors.New("mock servers are not initialized")
+	}
+
+	switch e.Action {
+	case "", "start":
+		store.Lock()
+		defer store.Unlock()
+		if _, ok := store.servers[e.Server]; ok {
+			return nil, fmt.Errorf("mock server %q is already started", e.Server)
+		}
+		srv, err := e.start(ctx)
+		if err != nil {
+			return nil, err
+		}
+		store.servers[e.Server] = srv
+		return srv.result(nil), nil
+	case "stop":
+		store.Lock()
+		defer store.Unlock()
+		srv, ok := store.servers[e.Server]
+		if !ok {
+			return nil, fmt.Errorf("mock server %q is not started", e.Server)
+		}
+		result := srv.result(nil)
+		delete(store.servers, e.Server)
+		if err := srv.close(); err != nil {
+			return nil, errors.Wrapf(err, "unable to stop mock server %q", e.Server)
+		}
+		return result, nil
+	}
+
+	store.Lock()
+	srv, ok := store.servers[e.Server]
+	store.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("mock server %q is not started", e.Server)
+	}
+	switch e.Action {
+	case "stub":
+		srv.addStubs(e.Stubs)
+		return srv.result(nil), nil
+	case "requests":
+		return srv.result(e.Match), nil
+	case "wait":
+		if e.Timeout == 0 {
+			e.Timeout = defaultTimeoutMs
+		}
+		return srv.wait(ctx, e.Match, time.Duration(e.Timeout)*time.Millisecond)
+	case "reset":
+		srv.reset()
+		return srv.result(nil), nil
+	default:
+		return nil, fmt.Errorf("action %q must be start, stub, requests, wait, reset or stop", e.Action)
+	}
+}
+
+func (e Executor) start(ctx context.Context) (*server, error) {
+	host := e.Host
+	if host == "" {
+		host = defaultHost
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprintf("%d", e.Port)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to start mock server %q", e.Server)
+	}
+
+	srv := &server{
+		port:    l.Addr().(*net.TCPAddr).Port,
+		waited:  map[int]bool{},
+		changed: make(chan struct{}),
+	}
+	srv.url = fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprintf("%d", srv.port)))
+	srv.addStubs(e.Stubs)
+	srv.http = &http.Server{Handler: srv}
+	go func() {
+		if err := srv.http.Serve(l); err != nil && err != http.ErrServerClosed {
+			venom.Error(ctx, "mock server %q stopped: %v", e.Server, err)
+		}
+	}()
+	venom.Debug(ctx, "mock server %q listening on %s", e.Server, srv.url)
+	return srv, nil
+}
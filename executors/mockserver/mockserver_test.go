@@ -0,0 +1,176 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	r := httptest.NewRequest("POST", "/webhook", nil)
+	r.Header.Set("X-Signature", "abc")
+	req := newRequest(r, []byte(`{"id": 1, "status": "done"}`))
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		match   bool
+	}{
+		{name: "empty", matcher: Matcher{}, match: true},
+		{name: "method and path", matcher: Matcher{Method: "post", Path: "/webhook"}, match: true},
+		{name: "other path", matcher: Matcher{Path: "/other"}, match: false},
+		{name: "header", matcher: Matcher{Headers: map[string]string{"x-signature": "abc"}}, match: true},
+		{name: "other header", matcher: Matcher{Headers: map[string]string{"X-Signature": "def"}}, match: false},
+		{name: "json body", matcher: Matcher{Body: `{"status":"done","id":1}`}, match: true},
+		{name: "other json body", matcher: Matcher{Body: `{"status":"failed","id":1}`}, match: false},
+		{name: "body contains", matcher: Matcher{BodyContains: `"done"`}, match: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.matcher.match(req))
+		})
+	}
+}
+
+func TestExecutor_Run(t *testing.T) {
+	venom.InitTestLogger(t)
+	var e Executor
+	ctx, err := e.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	res, err := e.Run(ctx, venom.TestStep{
+		"stubs": []interface{}{
+			map[string]interface{}{
+				"method": "POST",
+				"path":   "/webhook",
+				"response": map[string]interface{}{
+					"statusCode": 202,
+					"headers":    map[string]string{"Content-Type": "application/json"},
+					"body":       `{"ok":true}`,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	url := res.(Result).URL
+	require.NotEmpty(t, url)
+
+	_, err = e.Run(ctx, venom.TestStep{"action": "start"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `mock server "default" is already started`)
+
+	resp := post(t, url+"/webhook", `{"id":1}`)
+	assert.Equal(t, 202, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"ok":true}`, string(body))
+	assert.Equal(t, 404, post(t, url+"/unknown", "").StatusCode)
+
+	// stubs added last take precedence
+	_, err = e.Run(ctx, venom.TestStep{
+		"action": "stub",
+		"stubs": []interface{}{
+			map[string]interface{}{"path": "/webhook", "body": `{"id":2}`, "response": map[string]interface{}{"statusCode": 500}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 500, post(t, url+"/webhook", `{"id": 2}`).StatusCode)
+	assert.Equal(t, 202, post(t, url+"/webhook", `{"id": 3}`).StatusCode)
+
+	res, err = e.Run(ctx, venom.TestStep{"action": "requests", "match": map[string]interface{}{"path": "/webhook"}})
+	require.NoError(t, err)
+	requests := res.(Result).Requests
+	require.Len(t, requests, 3)
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Equal(t, `{"id":1}`, requests[0].Body)
+	assert.True(t, requests[0].Matched)
+
+	res, err = e.Run(ctx, venom.TestStep{"action": "requests"})
+	require.NoError(t, err)
+	assert.Len(t, res.(Result).Requests, 4)
+	assert.False(t, res.(Result).Requests[1].Matched)
+
+	res, err = e.Run(ctx, venom.TestStep{"action": "reset"})
+	require.NoError(t, err)
+	assert.Empty(t, res.(Result).Requests)
+	assert.Equal(t, 404, post(t, url+"/webhook", `{"id":1}`).StatusCode)
+
+	_, err = e.Run(ctx, venom.TestStep{"action": "stop"})
+	require.NoError(t, err)
+	_, err = e.Run(ctx, venom.TestStep{"action": "requests"})
+	require.Error(t, err)
+
+	require.NoError(t, e.TearDown(ctx))
+}
+
+func TestExecutor_TearDown(t *testing.T) {
+	venom.InitTestLogger(t)
+	var e Executor
+	ctx, err := e.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	res, err := e.Run(ctx, venom.TestStep{"server": "payments"})
+	require.NoError(t, err)
+	url := res.(Result).URL
+
+	require.NoError(t, e.TearDown(ctx))
+	_, err = http.Get(url)
+	require.Error(t, err)
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestExecutor_Run_Wait(t *testing.T) {
+	venom.InitTestLogger(t)
+	var e Executor
+	ctx, err := e.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+	defer e.TearDown(ctx) // nolint
+
+	res, err := e.Run(ctx, venom.TestStep{
+		"stubs": []interface{}{map[string]interface{}{"response": map[string]interface{}{"statusCode": 204}}},
+	})
+	require.NoError(t, err)
+	url := res.(Result).URL
+
+	// a callback received before the wait step is captured too
+	assert.Equal(t, 204, post(t, url+"/callback", `{"step":1}`).StatusCode)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		for _, r := range []struct{ path, body string }{{"/other", `{"step":2}`}, {"/callback", `{"step":3}`}} {
+			if resp, err := http.Post(url+r.path, "application/json", strings.NewReader(r.body)); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	wait := venom.TestStep{"action": "wait", "match": map[string]interface{}{"path": "/callback"}, "timeout": 2000}
+	res, err = e.Run(ctx, wait)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", res.(Result).Method)
+	assert.Equal(t, map[string]interface{}{"step": json.Number("1")}, res.(Result).BodyJSON)
+
+	res, err = e.Run(ctx, wait)
+	require.NoError(t, err)
+	assert.Equal(t, `{"step":3}`, res.(Result).Body)
+	assert.Equal(t, "application/json", res.(Result).Headers["Content-Type"])
+
+	wait["timeout"] = 50
+	_, err = e.Run(ctx, wait)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no matching request received after 50ms")
+}
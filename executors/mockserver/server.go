@@ -0,0 +1,188 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/venom"
+)
+
+// Request is a request received by a mock server
+type Request struct {
+	Method   string            `json:"method" yaml:"method"`
+	Path     string            `json:"path" yaml:"path"`
+	Query    string            `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers  map[string]string `json:"headers" yaml:"headers"`
+	Body     string            `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyJSON interface{}       `json:"bodyjson,omitempty" yaml:"bodyjson,omitempty"`
+	// Matched is true when a stub matched the request
+	Matched bool `json:"matched" yaml:"matched"`
+}
+
+type server struct {
+	sync.Mutex
+	url      string
+	port     int
+	http     *http.Server
+	stubs    []Stub
+	requests []Request
+	// waited are the indexes of the requests already returned by the wait action
+	waited map[int]bool
+	// changed is closed when a request is recorded
+	changed chan struct{}
+}
+
+func (s *server) addStubs(stubs []Stub) {
+	s.Lock()
+	defer s.Unlock()
+	s.stubs = append(s.stubs, stubs...)
+}
+
+// reset removes the stubs and the recorded requests
+func (s *server) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.stubs = nil
+	s.requests = nil
+	s.waited = map[int]bool{}
+}
+
+func (s *server) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.http.Shutdown(ctx)
+}
+
+// result returns the recorded requests matching m
+func (s *server) result(m *Matcher) Result {
+	s.Lock()
+	defer s.Unlock()
+	result := Result{URL: s.url, Port: s.port, Requests: []Request{}}
+	for _, r := range s.requests {
+		if m == nil || m.match(r) {
+			result.Requests = append(result.Requests, r)
+		}
+	}
+	return result
+}
+
+// wait returns the first recorded request matching m and not already returned by a previous wait,
+// waiting for it up to timeout
+func (s *server) wait(ctx context.Context, m *Matcher, timeout time.Duration) (Result, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.Lock()
+		for i, r := range s.requests {
+			if s.waited[i] || (m != nil && !m.match(r)) {
+				continue
+			}
+			s.waited[i] = true
+			s.Unlock()
+			result := s.result(m)
+			result.Method, result.Path, result.Query = r.Method, r.Path, r.Query
+			result.Headers, result.Body, result.BodyJSON = r.Headers, r.Body, r.BodyJSON
+			return result, nil
+		}
+		changed := s.changed
+		s.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return Result{}, fmt.Errorf("no matching request received after %v", timeout)
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := newRequest(r, body)
+
+	s.Lock()
+	var stub *Stub
+	// the stubs added last take precedence
+	for i := len(s.stubs) - 1; i >= 0; i-- {
+		if s.stubs[i].match(req) {
+			stub = &s.stubs[i]
+			break
+		}
+	}
+	req.Matched = stub != nil
+	s.requests = append(s.requests, req)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.Unlock()
+
+	if stub == nil {
+		http.Error(w, "no stub matching the request", http.StatusNotFound)
+		return
+	}
+	if stub.Response.Delay > 0 {
+		time.Sleep(time.Duration(stub.Response.Delay) * time.Millisecond)
+	}
+	for k, v := range stub.Response.Headers {
+		w.Header().Set(k, v)
+	}
+	statusCode := stub.Response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(stub.Response.Body))
+}
+
+func newRequest(r *http.Request, body []byte) Request {
+	req := Request{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: map[string]string{},
+		Body:    string(body),
+	}
+	for k, v := range r.Header {
+		req.Headers[k] = strings.Join(v, ",")
+	}
+	var bodyJSON interface{}
+	if err := venom.JSONUnmarshal(body, &bodyJSON); err == nil {
+		req.BodyJSON = bodyJSON
+	}
+	return req
+}
+
+func (m Matcher) match(r Request) bool {
+	if m.Method != "" && !strings.EqualFold(m.Method, r.Method) {
+		return false
+	}
+	if m.Path != "" && m.Path != r.Path {
+		return false
+	}
+	for k, v := range m.Headers {
+		if r.Headers[http.CanonicalHeaderKey(k)] != v {
+			return false
+		}
+	}
+	if m.BodyContains != "" && !strings.Contains(r.Body, m.BodyContains) {
+		return false
+	}
+	if m.Body != "" && strings.TrimSpace(m.Body) != strings.TrimSpace(r.Body) {
+		var expected interface{}
+		if r.BodyJSON == nil || venom.JSONUnmarshal([]byte(m.Body), &expected) != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, r.BodyJSON)
+	}
+	return true
+}
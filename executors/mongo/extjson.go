@@ -0,0 +1,24 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ovh/venom"
+)
+
+// relaxedJSON returns the result of an action as relaxed extended JSON, so that the ObjectIDs are {"$oid": ...}
+// and the dates {"$date": ...} in the assertions, instead of their Go values
+func relaxedJSON(result map[string]any) (map[string]any, error) {
+	if result == nil {
+		return nil, nil
+	}
+	b, err := bson.MarshalExtJSON(bson.M(result), false, false)
+	if err != nil {
+		return nil, err
+	}
+	var converted map[string]any
+	if err := venom.JSONUnmarshal(b, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
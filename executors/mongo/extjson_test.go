@@ -0,0 +1,30 @@
+package mongo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRelaxedJSON(t *testing.T) {
+	id, err := primitive.ObjectIDFromHex("636a7c57d044a5a54b0f8cf7")
+	require.NoError(t, err)
+	date := time.Date(2022, 11, 8, 15, 0, 0, 0, time.UTC)
+
+	result, err := relaxedJSON(map[string]any{
+		"results": []map[string]any{{"_id": id, "createdAt": primitive.NewDateTimeFromTime(date), "count": int32(2)}},
+	})
+	require.NoError(t, err)
+
+	document := result["results"].([]any)[0].(map[string]any)
+	require.Equal(t, map[string]any{"$oid": "636a7c57d044a5a54b0f8cf7"}, document["_id"])
+	require.Equal(t, map[string]any{"$date": "2022-11-08T15:00:00Z"}, document["createdAt"])
+	require.Equal(t, json.Number("2"), document["count"])
+
+	result, err = relaxedJSON(nil)
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
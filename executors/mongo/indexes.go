@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CreateIndexesAction struct {
+	Action
+	Indexes []Index
+}
+
+// Index is an index of a collection, its keys and partial filter are extended JSON
+type Index struct {
+	Keys               string
+	Name               string
+	Unique             bool
+	Sparse             bool
+	ExpireAfterSeconds *int32
+	PartialFilter      string
+}
+
+type DropIndexAction struct {
+	Action
+	Name string
+}
+
+type DistinctAction struct {
+	Action
+	Field  string
+	Filter string
+}
+
+// createIndexes creates the indexes of the action, and returns their names
+func createIndexes(ctx context.Context, collection *mongo.Collection, action map[string]any) (map[string]any, error) {
+	var createIndexesAction CreateIndexesAction
+	if err := mapstructure.Decode(action, &createIndexesAction); err != nil {
+		return nil, err
+	}
+	if len(createIndexesAction.Indexes) == 0 {
+		return nil, fmt.Errorf("indexes are required")
+	}
+
+	models := make([]mongo.IndexModel, 0, len(createIndexesAction.Indexes))
+	for _, index := range createIndexesAction.Indexes {
+		var keys bson.D
+		if err := bson.UnmarshalExtJSON([]byte(index.Keys), false, &keys); err != nil {
+			return nil, fmt.Errorf("invalid keys %q: %w", index.Keys, err)
+		}
+		indexOptions := options.Index()
+		if index.Name != "" {
+			indexOptions.SetName(index.Name)
+		}
+		if index.Unique {
+			indexOptions.SetUnique(true)
+		}
+		if index.Sparse {
+			indexOptions.SetSparse(true)
+		}
+		if index.ExpireAfterSeconds != nil {
+			indexOptions.SetExpireAfterSeconds(*index.ExpireAfterSeconds)
+		}
+		if index.PartialFilter != "" {
+			var partialFilter bson.M
+			if err := bson.UnmarshalExtJSON([]byte(index.PartialFilter), false, &partialFilter); err != nil {
+				return nil, fmt.Errorf("invalid partial filter %q: %w", index.PartialFilter, err)
+			}
+			indexOptions.SetPartialFilterExpression(partialFilter)
+		}
+		models = append(models, mongo.IndexModel{Keys: keys, Options: indexOptions})
+	}
+
+	names, err := collection.Indexes().CreateMany(ctx, models)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"names": names}, nil
+}
+
+// listIndexes returns the specifications of the indexes of the collection
+func listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]any, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []bson.M
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return map[string]any{"results": result}, nil
+}
+
+// dropIndex drops the index of the action
+func dropIndex(ctx context.Context, collection *mongo.Collection, action map[string]any) error {
+	var dropIndexAction DropIndexAction
+	if err := mapstructure.Decode(action, &dropIndexAction); err != nil {
+		return err
+	}
+	if dropIndexAction.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	_, err := collection.Indexes().DropOne(ctx, dropIndexAction.Name)
+	return err
+}
+
+// distinct returns the distinct values of the field of the action
+func distinct(ctx context.Context, collection *mongo.Collection, action map[string]any) (map[string]any, error) {
+	var distinctAction DistinctAction
+	if err := mapstructure.Decode(action, &distinctAction); err != nil {
+		return nil, err
+	}
+	if distinctAction.Field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+
+	filter := bson.M{}
+	if distinctAction.Filter != "" {
+		if err := bson.UnmarshalExtJSON([]byte(distinctAction.Filter), false, &filter); err != nil {
+			return nil, err
+		}
+	}
+
+	values, err := collection.Distinct(ctx, distinctAction.Field, filter)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"results": values}, nil
+}
@@ -29,6 +29,11 @@ type Executor struct {
 	Database   string           `json:"database,omitempty" yaml:"database,omitempty"`
 	Collection string           `json:"collection,omitempty" yaml:"collection,omitempty"`
 	Actions    []map[string]any `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// Transaction runs the actions in a transaction, committed at the end of the step, or aborted on error or if Abort is set
+	Transaction bool `json:"transaction,omitempty" yaml:"transaction,omitempty"`
+	Abort       bool `json:"abort,omitempty" yaml:"abort,omitempty"`
+	// ExtendedJSON returns the results as relaxed extended JSON instead of their Go values
+	ExtendedJSON bool `json:"extended_json,omitempty" yaml:"extended_json,omitempty" mapstructure:"extended_json"`
 }
 
 type Result struct {
@@ -47,6 +52,21 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (any, error) {
 	}
 	defer mongoClient.Disconnect(ctx)
 
+	// the change streams outlive the step, they are opened without the session of the transaction
+	streamCtx := ctx
+	if e.Transaction {
+		session, err := mongoClient.StartSession()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start session: %w", err)
+		}
+		// ending the session aborts the transaction if it is not committed, when an action fails
+		defer session.EndSession(ctx)
+		if err := session.StartTransaction(); err != nil {
+			return nil, fmt.Errorf("failed to start transaction: %w", err)
+		}
+		ctx = mongo.NewSessionContext(ctx, session)
+	}
+
 	results := make([]map[string]any, len(e.Actions))
 
 	for i, action := range e.Actions {
@@ -239,7 +259,7 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (any, error) {
 				if err := bson.UnmarshalExtJSON([]byte(findAction.Options.Projection), false, &projection); err != nil {
 					return nil, err
 				}
-				findOptions = append(findOptions, options.Find().SetSort(projection))
+				findOptions = append(findOptions, options.Find().SetProjection(projection))
 			}
 
 			cursor, err := mongoClient.Database(e.Database).Collection(e.Collection).Find(ctx, filter, findOptions...)
@@ -338,6 +358,64 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (any, error) {
 				return nil, err
 			}
 			results[i] = map[string]any{"results": result}
+
+		case "createIndexes":
+			result, err := createIndexes(ctx, mongoClient.Database(e.Database).Collection(e.Collection), action)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = result
+
+		case "listIndexes":
+			result, err := listIndexes(ctx, mongoClient.Database(e.Database).Collection(e.Collection))
+			if err != nil {
+				return nil, err
+			}
+			results[i] = result
+
+		case "dropIndex":
+			if err := dropIndex(ctx, mongoClient.Database(e.Database).Collection(e.Collection), action); err != nil {
+				return nil, err
+			}
+
+		case "distinct":
+			result, err := distinct(ctx, mongoClient.Database(e.Database).Collection(e.Collection), action)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = result
+
+		case "watch":
+			if err := e.watch(streamCtx, action); err != nil {
+				return nil, err
+			}
+
+		case "collectChanges":
+			result, err := collectChanges(ctx, action)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = result
+		}
+	}
+
+	if e.Transaction && e.Abort {
+		if err := mongo.SessionFromContext(ctx).AbortTransaction(ctx); err != nil {
+			return nil, fmt.Errorf("failed to abort transaction: %w", err)
+		}
+	} else if e.Transaction {
+		if err := mongo.SessionFromContext(ctx).CommitTransaction(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+
+	if e.ExtendedJSON {
+		for i := range results {
+			converted, err := relaxedJSON(results[i])
+			if err != nil {
+				return nil, err
+			}
+			results[i] = converted
 		}
 	}
 
@@ -0,0 +1,164 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+)
+
+// ContextKey is the key of the change streams in the testcase context
+const ContextKey = venom.ContextKey("mongoContext")
+
+type WatchAction struct {
+	Action
+	Name         string
+	Pipeline     []string
+	FullDocument string
+}
+
+type CollectChangesAction struct {
+	Action
+	Name string
+	// Limit is the number of change events to wait for, during Timeout seconds
+	Limit   int
+	Timeout int
+}
+
+// Setup prepares the store of the change streams of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, background.NewConsumers[map[string]any]()), nil
+}
+
+// TearDown closes the change streams left open by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	if streams := getStreams(ctx); streams != nil {
+		streams.StopAll()
+	}
+	return nil
+}
+
+func getStreams(ctx context.Context) *background.Consumers[map[string]any] {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*background.Consumers[map[string]any])
+}
+
+// watch opens a change stream on the collection, or on the database without collection,
+// buffering the change events until the end of the testcase
+func (e Executor) watch(ctx context.Context, action map[string]any) error {
+	var watchAction WatchAction
+	if err := mapstructure.Decode(action, &watchAction); err != nil {
+		return err
+	}
+	if watchAction.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	streams := getStreams(ctx)
+	if streams == nil {
+		return fmt.Errorf("change streams are not available outside of a testcase")
+	}
+	if _, err := streams.Get(watchAction.Name); err == nil {
+		return fmt.Errorf("change stream %q is already opened", watchAction.Name)
+	}
+
+	pipeline := bson.A{}
+	for _, pipelineItemJSON := range watchAction.Pipeline {
+		var pipelineItem bson.M
+		if err := bson.UnmarshalExtJSON([]byte(pipelineItemJSON), false, &pipelineItem); err != nil {
+			return err
+		}
+		pipeline = append(pipeline, pipelineItem)
+	}
+	streamOptions := options.ChangeStream()
+	if watchAction.FullDocument != "" {
+		streamOptions.SetFullDocument(options.FullDocument(watchAction.FullDocument))
+	}
+
+	// the stream outlives the step, and its client
+	bctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	client, err := mongo.Connect(bctx, options.Client().ApplyURI(e.URI))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	var stream *mongo.ChangeStream
+	if e.Collection != "" {
+		stream, err = client.Database(e.Database).Collection(e.Collection).Watch(bctx, pipeline, streamOptions)
+	} else {
+		stream, err = client.Database(e.Database).Watch(bctx, pipeline, streamOptions)
+	}
+	if err != nil {
+		_ = client.Disconnect(bctx)
+		cancel()
+		return fmt.Errorf("failed to open change stream %q: %w", watchAction.Name, err)
+	}
+
+	stopped := make(chan struct{})
+	consumer := background.NewConsumer[map[string]any](func() {
+		cancel()
+		<-stopped
+	})
+	go func() {
+		defer close(stopped)
+		defer func() { _ = client.Disconnect(context.Background()) }()
+		defer func() { _ = stream.Close(context.Background()) }()
+		for stream.Next(bctx) {
+			var event bson.M
+			if err := stream.Decode(&event); err != nil {
+				consumer.Fail(err)
+				return
+			}
+			// returned by collectChanges like the other results, as extended JSON only with extended_json
+			consumer.Push(event)
+		}
+		if bctx.Err() == nil {
+			consumer.Fail(fmt.Errorf("change stream %q closed: %v", watchAction.Name, stream.Err()))
+		}
+	}()
+
+	venom.Debug(ctx, "change stream %q opened", watchAction.Name)
+	if err := streams.Add(watchAction.Name, consumer); err != nil {
+		consumer.Stop()
+		return err
+	}
+	return nil
+}
+
+// collectChanges returns the change events buffered by a change stream,
+// once limit events are received, or after the timeout
+func collectChanges(ctx context.Context, action map[string]any) (map[string]any, error) {
+	var collectAction CollectChangesAction
+	if err := mapstructure.Decode(action, &collectAction); err != nil {
+		return nil, err
+	}
+	streams := getStreams(ctx)
+	if streams == nil {
+		return nil, fmt.Errorf("change streams are not available outside of a testcase")
+	}
+	consumer, err := streams.Get(collectAction.Name)
+	if err != nil {
+		return nil, fmt.Errorf("change stream %q is not opened", collectAction.Name)
+	}
+	if collectAction.Timeout == 0 {
+		collectAction.Timeout = 5
+	}
+
+	events, err := consumer.Collect(ctx, collectAction.Limit, time.Duration(collectAction.Timeout)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("change stream %q: %w", collectAction.Name, err)
+	}
+	if events == nil {
+		events = []map[string]any{}
+	}
+	return map[string]any{"events": events}, nil
+}
@@ -0,0 +1,90 @@
+package mqtt
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+	"github.com/ovh/venom/executors/internal/jsondecode"
+)
+
+// Setup prepares the store of the background subscribers of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, background.NewConsumers[received]()), nil
+}
+
+// TearDown disconnects the background subscribers left running by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	if consumers := getConsumers(ctx); consumers != nil {
+		consumers.StopAll()
+	}
+	return nil
+}
+
+func getConsumers(ctx context.Context) *background.Consumers[received] {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*background.Consumers[received])
+}
+
+// startBackground subscribes to the topics with a client connected until the end of the testcase
+func (e Executor) startBackground(ctx context.Context) error {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return errors.New("background subscribers are not available outside of a testcase")
+	}
+	if _, err := consumers.Get(e.Background); err == nil {
+		return errors.Errorf("background consumer %q is already started", e.Background)
+	}
+
+	var c client
+	consumer := background.NewConsumer[received](func() {
+		c.disconnect()
+	})
+	// the messages are received after the end of the step
+	bctx := context.WithoutCancel(ctx)
+	c, err := e.session(ctx, func(message received) {
+		venom.Debug(bctx, "rx message in background subscriber %q: %s len(%d)", e.Background, message.topic, len(message.payload))
+		consumer.Push(message)
+	})
+	if err != nil {
+		venom.Debug(ctx, "Failed to create session (startBackground)")
+		return err
+	}
+	if err := e.subscribe(ctx, c); err != nil {
+		consumer.Stop()
+		return err
+	}
+	venom.Debug(ctx, "background subscriber %q started on topics %v", e.Background, e.Topics)
+	return consumers.Add(e.Background, consumer)
+}
+
+// collect returns the messages received by a background subscriber, once MessageLimit messages are received
+func (e Executor) collect(ctx context.Context) ([]interface{}, []interface{}, []string, []MessageProperties, error) {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return nil, nil, nil, nil, errors.New("background subscribers are not available outside of a testcase")
+	}
+	consumer, err := consumers.Get(e.Background)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	received, err := consumer.Collect(ctx, e.MessageLimit, time.Duration(e.Timeout)*time.Millisecond)
+
+	messages := []interface{}{}
+	messagesJSON := []interface{}{}
+	topics := []string{}
+	properties := []MessageProperties{}
+	for _, msg := range received {
+		messages = append(messages, msg.payload)
+		messagesJSON = append(messagesJSON, jsondecode.Message(ctx, msg.payload))
+		topics = append(topics, msg.topic)
+		properties = append(properties, msg.properties)
+	}
+	return messages, messagesJSON, topics, properties, err
+}
@@ -0,0 +1,155 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	mq "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/tlsconfig"
+)
+
+// client is a connection to the broker, with MQTT 3.1.1 or MQTT 5
+type client interface {
+	publish(ctx context.Context, m Message) error
+	subscribe(ctx context.Context, topic string) error
+	disconnect()
+}
+
+// received is a message received from the broker
+type received struct {
+	topic      string
+	payload    []byte
+	properties MessageProperties
+}
+
+// MessageProperties represents the properties of a received message, the MQTT 5 properties are empty with MQTT 3.1.1
+type MessageProperties struct {
+	Retained        bool   `json:"retained" yaml:"retained"`
+	QOS             byte   `json:"qos" yaml:"qos"`
+	ContentType     string `json:"contenttype,omitempty" yaml:"contentType,omitempty"`
+	ResponseTopic   string `json:"responsetopic,omitempty" yaml:"responseTopic,omitempty"`
+	CorrelationData string `json:"correlationdata,omitempty" yaml:"correlationData,omitempty"`
+	// MessageExpiry is the remaining lifetime of the message, in seconds
+	MessageExpiry  *uint32           `json:"messageexpiry,omitempty" yaml:"messageExpiry,omitempty"`
+	UserProperties map[string]string `json:"userproperties,omitempty" yaml:"userProperties,omitempty"`
+}
+
+// session prepares a client connection returning a client and a possible error.
+// The received messages are passed to the handler, if not nil.
+func (e Executor) session(ctx context.Context, handler func(received)) (client, error) {
+	tlsConfig, err := e.tlsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.ProtocolVersion == mqttV5 {
+		return e.sessionV5(ctx, tlsConfig, handler)
+	}
+	return e.sessionV3(ctx, tlsConfig, handler)
+}
+
+// sessionV3 connects to the broker with MQTT 3.1.1
+func (e Executor) sessionV3(ctx context.Context, tlsConfig *tls.Config, handler func(received)) (client, error) {
+	venom.Debug(ctx, "creating session to %v, cleanSession: %v, clientID: %v", e.Addrs, !e.PersistSubscription, e.ClientID)
+
+	opts := mq.NewClientOptions().
+		AddBroker(e.Addrs).
+		SetConnectTimeout(time.Duration(e.ConnectTimeout) * time.Millisecond).
+		SetCleanSession(!e.PersistSubscription).
+		SetClientID(e.ClientID).
+		SetProtocolVersion(mqttV311).
+		SetOnConnectHandler(func(client mq.Client) {
+			venom.Debug(ctx, "connection handler called. IsConnected: %v", client.IsConnected())
+		})
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	c := &v3Client{client: mq.NewClient(opts), qos: e.QOS}
+	if handler != nil {
+		c.handler = func(client mq.Client, message mq.Message) {
+			handler(received{
+				topic:      message.Topic(),
+				payload:    message.Payload(),
+				properties: MessageProperties{Retained: message.Retained(), QOS: message.Qos()},
+			})
+		}
+	}
+
+	// MQTT may send messages prior to a subscription taking place (due to pre-existing persistent session).
+	// We cannot subscribe without a connection so we register a route and subscribe later
+	if c.handler != nil {
+		venom.Debug(ctx, "adding routes: %v", e.Topics)
+		for _, topic := range e.Topics {
+			c.client.AddRoute(topic, c.handler)
+		}
+	}
+
+	token := c.client.Connect()
+	select {
+	case <-token.Done():
+		if token.Error() != nil {
+			venom.Debug(ctx, "connection setup failed")
+			return nil, errors.Wrap(token.Error(), "failed to connect to MQTT")
+		}
+		// else connection complete, all good.
+	case <-time.After(time.Duration(e.Timeout) * time.Millisecond):
+		venom.Debug(ctx, "connection timeout")
+		return nil, errors.Wrap(token.Error(), "failed to connect to MQTT")
+	case <-ctx.Done():
+		venom.Debug(ctx, "Context requested cancellation in session()")
+		return nil, errors.New("Context requested cancellation in session()")
+	}
+
+	venom.Debug(ctx, "connection setup completed")
+
+	return c, nil
+}
+
+// v3Client is a MQTT 3.1.1 client
+type v3Client struct {
+	client  mq.Client
+	qos     byte
+	handler mq.MessageHandler
+}
+
+func (c *v3Client) publish(ctx context.Context, m Message) error {
+	return wait(ctx, c.client.Publish(m.Topic, m.QOS, m.Retained, m.Payload))
+}
+
+func (c *v3Client) subscribe(ctx context.Context, topic string) error {
+	handler := c.handler
+	if handler == nil {
+		handler = func(client mq.Client, message mq.Message) {
+			venom.Debug(ctx, "msg received in persist request: %v", string(message.Payload()))
+		}
+	}
+	return wait(ctx, c.client.Subscribe(topic, c.qos, handler))
+}
+
+func (c *v3Client) disconnect() {
+	c.client.Disconnect(disconnectTimeoutMs)
+}
+
+// wait waits for the completion of the token, or for the end of the context
+func wait(ctx context.Context, token mq.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tlsConfig returns the TLS configuration of the step, or nil without TLS options
+func (e Executor) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	return tlsconfig.New(ctx, tlsconfig.Options{
+		RootCA:          e.TLSRootCA,
+		ClientCert:      e.TLSClientCert,
+		ClientKey:       e.TLSClientKey,
+		IgnoreVerifySSL: e.IgnoreVerifySSL,
+	})
+}
@@ -0,0 +1,191 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// selfSigned returns a self-signed certificate and its key, PEM encoded
+func selfSigned(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestExecutor_TLSConfig(t *testing.T) {
+	cert, key := selfSigned(t)
+	workdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "ca.pem"), cert, 0o600))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), workdir)
+
+	config, err := Executor{}.tlsConfig(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	// the root CA is read from the file relative to the testsuite, the client certificate and key are PEM contents
+	config, err = Executor{TLSRootCA: "ca.pem", TLSClientCert: string(cert), TLSClientKey: string(key)}.tlsConfig(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.False(t, config.InsecureSkipVerify)
+
+	config, err = Executor{IgnoreVerifySSL: true}.tlsConfig(ctx)
+	require.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+
+	_, err = Executor{TLSRootCA: "missing.pem"}.tlsConfig(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read TLSRootCA")
+	_, err = Executor{TLSClientCert: string(cert)}.tlsConfig(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse x509 mTLS certificate or key")
+}
+
+func TestDial_UnsupportedScheme(t *testing.T) {
+	_, err := dial(context.Background(), "ws://localhost:1883", time.Second, nil)
+	require.EqualError(t, err, `unsupported scheme "ws", must be tcp, mqtt, ssl, tls or mqtts`)
+}
+
+func TestReceivedV5(t *testing.T) {
+	expiry := uint32(30)
+	p := &paho.Publish{
+		Topic:   "venom/events",
+		QoS:     1,
+		Retain:  true,
+		Payload: []byte(`{"a":"b"}`),
+		Properties: &paho.PublishProperties{
+			ContentType:     "application/json",
+			ResponseTopic:   "venom/replies",
+			CorrelationData: []byte("request-1"),
+			MessageExpiry:   &expiry,
+			User:            paho.UserProperties{{Key: "origin", Value: "venom"}},
+		},
+	}
+	assert.Equal(t, received{
+		topic:   "venom/events",
+		payload: []byte(`{"a":"b"}`),
+		properties: MessageProperties{
+			Retained:        true,
+			QOS:             1,
+			ContentType:     "application/json",
+			ResponseTopic:   "venom/replies",
+			CorrelationData: "request-1",
+			MessageExpiry:   &expiry,
+			UserProperties:  map[string]string{"origin": "venom"},
+		},
+	}, receivedV5(p))
+}
+
+func TestExecutor_Run_ProtocolVersion(t *testing.T) {
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"addrs": "tcp://localhost:1883", "clientType": "publisher", "protocolVersion": 3})
+	require.EqualError(t, err, "protocolVersion 3 must be 4 or 5")
+
+	// the MQTT 5 properties are refused before connecting with MQTT 3.1.1
+	res, err := Executor{}.Run(context.Background(), venom.TestStep{
+		"addrs":      "tcp://localhost:1883",
+		"clientType": "publisher",
+		"messages": []interface{}{
+			map[string]interface{}{"topic": "venom/events", "payload": "a", "userProperties": map[string]string{"origin": "venom"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "has MQTT 5 properties, protocolVersion must be 5")
+}
+
+// serveV5 starts a MQTT 5 broker for one client, publishing count messages on its first subscription
+func serveV5(t *testing.T, count int) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			p, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch content := p.Content.(type) {
+			case *packets.Connect:
+				_, _ = (&packets.Connack{Properties: &packets.Properties{}}).WriteTo(conn)
+			case *packets.Subscribe:
+				_, _ = (&packets.Suback{PacketID: content.PacketID, Reasons: []byte{packets.SubackGrantedQoS0}, Properties: &packets.Properties{}}).WriteTo(conn)
+				for i := 0; i < count; i++ {
+					publish := &packets.Publish{Topic: content.Subscriptions[0].Topic, Payload: []byte(fmt.Sprintf(`{"n":%d}`, i)), Properties: &packets.Properties{}}
+					_, _ = publish.WriteTo(conn)
+				}
+			case *packets.Disconnect:
+				return
+			}
+		}
+	}()
+	return "tcp://" + l.Addr().String()
+}
+
+func TestExecutor_Run_SubscriberV5_MessageLimit(t *testing.T) {
+	venom.InitTestLogger(t)
+	addrs := serveV5(t, 20)
+
+	// more messages than the limit are received, the disconnection must not wait for them to be consumed
+	done := make(chan struct{})
+	var res interface{}
+	var err error
+	go func() {
+		defer close(done)
+		res, err = Executor{}.Run(context.Background(), venom.TestStep{
+			"addrs":           addrs,
+			"clientType":      "subscriber",
+			"protocolVersion": 5,
+			"topics":          []string{"venom/events"},
+			"messageLimit":    2,
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the subscriber step did not end")
+	}
+
+	require.NoError(t, err)
+	result := res.(Result)
+	require.Empty(t, result.Err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"n": json.Number("0")},
+		map[string]interface{}{"n": json.Number("1")},
+	}, result.MessagesJSON)
+}
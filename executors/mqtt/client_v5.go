@@ -0,0 +1,187 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+const keepAliveSeconds = 30
+
+// sessionV5 connects to the broker with MQTT 5
+func (e Executor) sessionV5(ctx context.Context, tlsConfig *tls.Config, handler func(received)) (client, error) {
+	venom.Debug(ctx, "creating MQTT 5 session to %v, cleanStart: %v, clientID: %v", e.Addrs, !e.PersistSubscription, e.ClientID)
+
+	conn, err := dial(ctx, e.Addrs, time.Duration(e.ConnectTimeout)*time.Millisecond, tlsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to MQTT")
+	}
+
+	config := paho.ClientConfig{
+		ClientID: e.ClientID,
+		Conn:     conn,
+		OnClientError: func(err error) {
+			venom.Debug(ctx, "MQTT client error: %v", err)
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			venom.Debug(ctx, "disconnected by the server with reason code %d", d.ReasonCode)
+		},
+	}
+	// MQTT may send messages prior to a subscription taking place (due to pre-existing persistent session),
+	// the handler is registered before the connection
+	if handler != nil {
+		config.OnPublishReceived = []func(paho.PublishReceived) (bool, error){
+			func(pr paho.PublishReceived) (bool, error) {
+				handler(receivedV5(pr.Packet))
+				return true, nil
+			},
+		}
+	}
+	c := paho.NewClient(config)
+
+	connect := &paho.Connect{
+		ClientID:   e.ClientID,
+		KeepAlive:  keepAliveSeconds,
+		CleanStart: !e.PersistSubscription,
+	}
+	if e.PersistSubscription {
+		// the session is kept until a persistent_queue step releases it
+		expiry := uint32(math.MaxUint32)
+		connect.Properties = &paho.ConnectProperties{SessionExpiryInterval: &expiry}
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+	connack, err := c.Connect(connectCtx, connect)
+	if err != nil {
+		venom.Debug(ctx, "connection setup failed")
+		_ = conn.Close()
+		if connack != nil && connack.Properties != nil && connack.Properties.ReasonString != "" {
+			return nil, errors.Wrapf(err, "failed to connect to MQTT: %s", connack.Properties.ReasonString)
+		}
+		return nil, errors.Wrap(err, "failed to connect to MQTT")
+	}
+
+	venom.Debug(ctx, "connection setup completed")
+
+	return &v5Client{
+		client: c,
+		subscription: paho.SubscribeOptions{
+			QoS:               e.QOS,
+			RetainHandling:    e.RetainHandling,
+			RetainAsPublished: e.RetainAsPublished,
+		},
+	}, nil
+}
+
+// dial opens the network connection to the broker, with TLS for the ssl, tls and mqtts schemes
+func dial(ctx context.Context, addrs string, timeout time.Duration, tlsConfig *tls.Config) (net.Conn, error) {
+	u, err := url.Parse(addrs)
+	if err != nil {
+		return nil, err
+	}
+	var port string
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		port = "1883"
+	case "ssl", "tls", "mqtts":
+		port = "8883"
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, must be tcp, mqtt, ssl, tls or mqtts", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if tlsConfig == nil || u.Scheme == "tcp" || u.Scheme == "mqtt" {
+		return dialer.DialContext(ctx, "tcp", host)
+	}
+	conn, err := (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	// the writes on a TLS connection are not thread safe
+	return packets.NewThreadSafeConn(conn), nil
+}
+
+// receivedV5 returns the received message with its MQTT 5 properties
+func receivedV5(p *paho.Publish) received {
+	r := received{
+		topic:      p.Topic,
+		payload:    p.Payload,
+		properties: MessageProperties{Retained: p.Retain, QOS: p.QoS},
+	}
+	if p.Properties != nil {
+		r.properties.ContentType = p.Properties.ContentType
+		r.properties.ResponseTopic = p.Properties.ResponseTopic
+		r.properties.CorrelationData = string(p.Properties.CorrelationData)
+		r.properties.MessageExpiry = p.Properties.MessageExpiry
+		if len(p.Properties.User) > 0 {
+			r.properties.UserProperties = make(map[string]string, len(p.Properties.User))
+			for _, u := range p.Properties.User {
+				r.properties.UserProperties[u.Key] = u.Value
+			}
+		}
+	}
+	return r
+}
+
+// v5Client is a MQTT 5 client
+type v5Client struct {
+	client       *paho.Client
+	subscription paho.SubscribeOptions
+}
+
+func (c *v5Client) publish(ctx context.Context, m Message) error {
+	p := &paho.Publish{
+		Topic:   m.Topic,
+		QoS:     m.QOS,
+		Retain:  m.Retained,
+		Payload: []byte(m.Payload),
+		Properties: &paho.PublishProperties{
+			ContentType:   m.ContentType,
+			ResponseTopic: m.ResponseTopic,
+		},
+	}
+	if m.CorrelationData != "" {
+		p.Properties.CorrelationData = []byte(m.CorrelationData)
+	}
+	if m.MessageExpiry > 0 {
+		expiry := m.MessageExpiry
+		p.Properties.MessageExpiry = &expiry
+	}
+	for k, v := range m.UserProperties {
+		p.Properties.User.Add(k, v)
+	}
+	_, err := c.client.Publish(ctx, p)
+	return err
+}
+
+func (c *v5Client) subscribe(ctx context.Context, topic string) error {
+	options := c.subscription
+	options.Topic = topic
+	suback, err := c.client.Subscribe(ctx, &paho.Subscribe{Subscriptions: []paho.SubscribeOptions{options}})
+	if err != nil && suback != nil && len(suback.Reasons) > 0 {
+		return fmt.Errorf("%w (reason code %d)", err, suback.Reasons[0])
+	}
+	return err
+}
+
+func (c *v5Client) disconnect() {
+	_ = c.client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+}
@@ -5,21 +5,26 @@ import (
 	"fmt"
 	"time"
 
-	mq "github.com/eclipse/paho.mqtt.golang"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/jsondecode"
 )
 
-// Name of executor
-const Name = "mqtt"
+const (
+	// Name of executor
+	Name = "mqtt"
+	// ContextKey is the key of the background subscribers in the testcase context
+	ContextKey = venom.ContextKey("mqttContext")
+)
 
 const (
 	disconnectTimeoutMs      = 500
 	defaultExecutorTimeoutMs = 5000
 	defaultConnectTimeoutMs  = 5000
 	mqttV311                 = 4
+	mqttV5                   = 5
 )
 
 // New returns a new Executor
@@ -30,17 +35,34 @@ func New() venom.Executor {
 type Executor struct {
 	Addrs string `json:"addrs" yaml:"addrs"`
 
-	// ClientType must be "consumer", "producer" or "persistent_queue"
+	// ClientType must be "publisher", "subscriber", "persistent_queue" or "collect"
 	ClientType          string `json:"client_type" yaml:"clientType"`
 	PersistSubscription bool   `json:"persist_subscription" yaml:"persistSubscription"`
 	ClientID            string `json:"client_id" yaml:"clientId"`
+	// ProtocolVersion is 4 for MQTT 3.1.1 or 5 for MQTT 5. Default 4
+	ProtocolVersion uint `json:"protocol_version" yaml:"protocolVersion"`
+
+	// TLS options, used with the ssl, tls or mqtts schemes of Addrs.
+	// The certificates and key are PEM contents, or paths to PEM files
+	TLSClientCert   string `json:"tls_client_cert" yaml:"tls_client_cert" mapstructure:"tls_client_cert"`
+	TLSClientKey    string `json:"tls_client_key" yaml:"tls_client_key" mapstructure:"tls_client_key"`
+	TLSRootCA       string `json:"tls_root_ca" yaml:"tls_root_ca" mapstructure:"tls_root_ca"`
+	IgnoreVerifySSL bool   `json:"ignore_verify_ssl" yaml:"ignore_verify_ssl" mapstructure:"ignore_verify_ssl"`
 
 	// Subscription topic
 	Topics []string `json:"topics" yaml:"topics"`
+	// RetainHandling of the MQTT 5 subscriptions: 0 to receive the retained messages, 1 only for new subscriptions, 2 never
+	RetainHandling byte `json:"retain_handling" yaml:"retainHandling"`
+	// RetainAsPublished keeps the retain flag of the messages forwarded to the MQTT 5 subscriptions
+	RetainAsPublished bool `json:"retain_as_published" yaml:"retainAsPublished"`
 
 	// Represents the limit of message will be read. After limit, consumer stop read message
 	MessageLimit int `json:"message_limit" yaml:"messageLimit"`
 
+	// Background is the name of a subscriber running until the end of the testcase.
+	// Its messages are returned by the steps with the collect ClientType, once MessageLimit messages are received
+	Background string `json:"background" yaml:"background"`
+
 	// Represents the mqtt connection timeout for reading messages. In Milliseconds. Default 5000
 	ConnectTimeout int64 `json:"connect_timeout,omitempty" yaml:"connectTimeout,omitempty"`
 
@@ -59,6 +81,18 @@ type Message struct {
 	QOS      byte   `json:"qos" yaml:"qos"`
 	Retained bool   `json:"retained" yaml:"retained"`
 	Payload  string `json:"payload" yaml:"payload"`
+
+	// MQTT 5 properties
+	ContentType     string            `json:"content_type,omitempty" yaml:"contentType,omitempty"`
+	ResponseTopic   string            `json:"response_topic,omitempty" yaml:"responseTopic,omitempty"`
+	CorrelationData string            `json:"correlation_data,omitempty" yaml:"correlationData,omitempty"`
+	UserProperties  map[string]string `json:"user_properties,omitempty" yaml:"userProperties,omitempty"`
+	// MessageExpiry is the lifetime of the message, in seconds
+	MessageExpiry uint32 `json:"message_expiry,omitempty" yaml:"messageExpiry,omitempty"`
+}
+
+func (m Message) hasV5Properties() bool {
+	return m.ContentType != "" || m.ResponseTopic != "" || m.CorrelationData != "" || len(m.UserProperties) > 0 || m.MessageExpiry > 0
 }
 
 // Result represents a step result.
@@ -67,7 +101,9 @@ type Result struct {
 	Topics       []string      `json:"topics" yaml:"topics"`
 	Messages     []interface{} `json:"messages" yaml:"messages"`
 	MessagesJSON []interface{} `json:"messagesjson" yaml:"messagesJSON"`
-	Err          string        `json:"err" yaml:"error"`
+	// Properties are the properties of the messages, by index
+	Properties []MessageProperties `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Err        string              `json:"err" yaml:"error"`
 }
 
 // GetDefaultAssertions return default assertions for type exec
@@ -86,7 +122,7 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	result := Result{}
 
 	// Default values
-	if e.Addrs == "" {
+	if e.Addrs == "" && e.ClientType != "collect" {
 		return nil, errors.New("address is mandatory")
 	}
 	if e.MessageLimit == 0 {
@@ -98,6 +134,13 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	if e.ConnectTimeout == 0 {
 		e.ConnectTimeout = defaultConnectTimeoutMs
 	}
+	switch e.ProtocolVersion {
+	case 0:
+		e.ProtocolVersion = mqttV311
+	case mqttV311, mqttV5:
+	default:
+		return nil, fmt.Errorf("protocolVersion %d must be %d or %d", e.ProtocolVersion, mqttV311, mqttV5)
+	}
 
 	var err error
 	switch e.ClientType {
@@ -112,12 +155,24 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 			result.Err = err.Error()
 		}
 	case "subscriber":
-		result.Messages, result.MessagesJSON, result.Topics, err = e.consumeMessages(ctx)
+		if e.Background != "" {
+			err = e.startBackground(ctx)
+		} else {
+			result.Messages, result.MessagesJSON, result.Topics, result.Properties, err = e.consumeMessages(ctx)
+		}
+		if err != nil {
+			result.Err = err.Error()
+		}
+	case "collect":
+		if e.Background == "" {
+			return nil, errors.New("background is mandatory to collect messages")
+		}
+		result.Messages, result.MessagesJSON, result.Topics, result.Properties, err = e.collect(ctx)
 		if err != nil {
 			result.Err = err.Error()
 		}
 	default:
-		return nil, fmt.Errorf("clientType %q must be publisher, subscriber or persistent_queue", e.ClientType)
+		return nil, fmt.Errorf("clientType %q must be publisher, subscriber, persistent_queue or collect", e.ClientType)
 	}
 
 	elapsed := time.Since(start)
@@ -126,80 +181,38 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	return result, nil
 }
 
-// session prepares a client connection returning a client and a possible error
-func (e Executor) session(ctx context.Context, subscriber func(client mq.Client, message mq.Message)) (mq.Client, error) {
-	venom.Debug(ctx, "creating session to %v, cleanSession: %v, clientID: %v", e.Addrs, !e.PersistSubscription, e.ClientID)
-
-	opts := mq.NewClientOptions().
-		AddBroker(e.Addrs).
-		SetConnectTimeout(time.Duration(e.ConnectTimeout) * time.Millisecond).
-		SetCleanSession(!e.PersistSubscription).
-		SetClientID(e.ClientID).
-		SetProtocolVersion(mqttV311).
-		SetOnConnectHandler(func(client mq.Client) {
-			venom.Debug(ctx, "connection handler called. IsConnected: %v", client.IsConnected())
-		})
-
-	client := mq.NewClient(opts)
-
-	// MQTT may send messages prior to a subscription taking place (due to pre-existing persistent session).
-	// We cannot subscribe without a connection so we register a route and subscribe later
-	if subscriber != nil {
-		venom.Debug(ctx, "adding routes: %v", e.Topics)
-		for _, topic := range e.Topics {
-			client.AddRoute(topic, subscriber)
+// publishMessages is a step that sends configured messages to client connection
+func (e Executor) publishMessages(ctx context.Context) error {
+	for i, m := range e.Messages {
+		if len(m.Topic) == 0 {
+			return errors.Errorf("mandatory field Topic was empty in Messages[%v](%v)", i, m)
 		}
-	}
-
-	token := client.Connect()
-	select {
-	case <-token.Done():
-		if token.Error() != nil {
-			venom.Debug(ctx, "connection setup failed")
-			return nil, errors.Wrap(token.Error(), "failed to connect to MQTT")
+		if e.ProtocolVersion != mqttV5 && m.hasV5Properties() {
+			return errors.Errorf("Messages[%v](%v) has MQTT 5 properties, protocolVersion must be 5", i, m)
 		}
-		// else connection complete, all good.
-	case <-time.After(time.Duration(e.Timeout) * time.Millisecond):
-		venom.Debug(ctx, "connection timeout")
-		return nil, errors.Wrap(token.Error(), "failed to connect to MQTT")
-	case <-ctx.Done():
-		venom.Debug(ctx, "Context requested cancellation in session()")
-		return nil, errors.New("Context requested cancellation in session()")
 	}
 
-	venom.Debug(ctx, "connection setup completed")
-
-	return client, nil
-}
-
-// publishMessages is a step that sends configured messages to client connection
-func (e Executor) publishMessages(ctx context.Context) error {
 	client, err := e.session(ctx, nil)
 	if err != nil {
 		venom.Debug(ctx, "Failed to create session (publishMessages)")
 		return err
 	}
-	defer client.Disconnect(disconnectTimeoutMs)
+	defer client.disconnect()
 
 	for i, m := range e.Messages {
-		if len(m.Topic) == 0 {
-			return errors.Errorf("mandatory field Topic was empty in Messages[%v](%v)", i, m)
-		}
-
-		token := client.Publish(m.Topic, m.QOS, m.Retained, m.Payload)
-		select {
-		case <-token.Done():
-			if token.Error() != nil {
-				venom.Debug(ctx, "Message publish failed")
-				return errors.Wrapf(token.Error(), "Message publish failed: Messages[%v](%v)", i, m)
-			}
-			// else publish complete, all good.
-		case <-time.After(time.Duration(e.Timeout) * time.Millisecond):
-			venom.Debug(ctx, "Publish attempt timed out")
-			return errors.Errorf("Publish attempt timed out on topic %v", m.Topic)
-		case <-ctx.Done():
+		err := e.withTimeout(ctx, func(ctx context.Context) error { return client.publish(ctx, m) })
+		switch {
+		case err == nil:
+			// publish complete, all good.
+		case ctx.Err() != nil:
 			venom.Debug(ctx, "Context requested cancellation in publishMessages()")
 			return errors.New("Context requested cancellation in publishMessages()")
+		case errors.Is(err, context.DeadlineExceeded):
+			venom.Debug(ctx, "Publish attempt timed out")
+			return errors.Errorf("Publish attempt timed out on topic %v", m.Topic)
+		default:
+			venom.Debug(ctx, "Message publish failed")
+			return errors.Wrapf(err, "Message publish failed: Messages[%v](%v)", i, m)
 		}
 		venom.Debug(ctx, "Message[%v] %q sent (topic: %q)", i, m.Payload, m.Topic)
 	}
@@ -208,40 +221,29 @@ func (e Executor) publishMessages(ctx context.Context) error {
 }
 
 // consumeMessages is a step to consume messages from mqtt broker using client connection
-func (e Executor) consumeMessages(ctx context.Context) (messages []interface{}, messagesJSON []interface{}, topics []string, err error) {
-	ch := make(chan mq.Message, 1)
-	defer close(ch)
-	subscriber := newSubscriber(ctx, ch)
+func (e Executor) consumeMessages(ctx context.Context) (messages []interface{}, messagesJSON []interface{}, topics []string, properties []MessageProperties, err error) {
+	ch := make(chan received, 1)
+	done := make(chan struct{})
+	subscriber := newSubscriber(ctx, ch, done)
 	client, err := e.session(ctx, subscriber)
 	if err != nil {
 		venom.Debug(ctx, "Failed to create session (consumeMessages)")
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
-	defer client.Disconnect(disconnectTimeoutMs)
+	defer client.disconnect()
+	// the handler must not block the disconnection, which waits for it with MQTT 5
+	defer close(done)
 
 	start := time.Now()
 
-	for _, topic := range e.Topics {
-		token := client.Subscribe(topic, e.QOS, subscriber)
-		select {
-		case <-token.Done():
-			if token.Error() != nil {
-				venom.Debug(ctx, "Failed to subscribe")
-				return nil, nil, nil, errors.Wrapf(token.Error(), "failed to subscribe to topic %v", topic)
-			}
-			// else subscription complete, all good.
-		case <-time.After(time.Duration(e.Timeout) * time.Millisecond):
-			venom.Debug(ctx, "Subscription attempt timed out")
-			return nil, nil, nil, errors.Errorf("Subscription attempt timed out on topic %v", topic)
-		case <-ctx.Done():
-			venom.Debug(ctx, "Context requested cancellation")
-			return nil, nil, nil, errors.New("Context requested cancellation")
-		}
+	if err := e.subscribe(ctx, client); err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	messages = []interface{}{}
 	messagesJSON = []interface{}{}
 	topics = []string{}
+	properties = []MessageProperties{}
 
 	venom.Debug(ctx, "message limit %d", e.MessageLimit)
 	ctx2, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
@@ -249,38 +251,54 @@ func (e Executor) consumeMessages(ctx context.Context) (messages []interface{},
 	for i := 0; i < e.MessageLimit; i++ {
 		venom.Debug(ctx, "Reading message n° %d", i)
 
-		var t string
-		var m []byte
+		var msg received
 		select {
-		case msg := <-ch:
-			m = msg.Payload()
-			t = msg.Topic()
+		case msg = <-ch:
 		case <-ctx2.Done():
-			break
 		}
+		t, m := msg.topic, msg.payload
 
 		messages = append(messages, m)
 		topics = append(topics, t)
+		properties = append(properties, msg.properties)
 
 		s := string(m)
 		venom.Debug(ctx, "message received. topic: %s len(%d), %s", t, len(m), s)
 
-		var bodyJSONArray []interface{}
-		if err := venom.JSONUnmarshal(m, &bodyJSONArray); err != nil {
-			bodyJSONMap := map[string]interface{}{}
-			err := venom.JSONUnmarshal(m, &bodyJSONMap)
-			if err != nil {
-				venom.Debug(ctx, "unable to decode message as json")
-			}
-			messagesJSON = append(messagesJSON, bodyJSONMap)
-		} else {
-			messagesJSON = append(messagesJSON, bodyJSONArray)
-		}
+		messagesJSON = append(messagesJSON, jsondecode.Message(ctx, m))
 	}
 	d := time.Since(start)
 	venom.Debug(ctx, "read(s) took %v msec", d.Milliseconds())
 
-	return messages, messagesJSON, topics, nil
+	return messages, messagesJSON, topics, properties, nil
+}
+
+// subscribe subscribes the client to the topics of the step
+func (e Executor) subscribe(ctx context.Context, client client) error {
+	for _, topic := range e.Topics {
+		err := e.withTimeout(ctx, func(ctx context.Context) error { return client.subscribe(ctx, topic) })
+		switch {
+		case err == nil:
+			// subscription complete, all good.
+		case ctx.Err() != nil:
+			venom.Debug(ctx, "Context requested cancellation")
+			return errors.New("Context requested cancellation")
+		case errors.Is(err, context.DeadlineExceeded):
+			venom.Debug(ctx, "Subscription attempt timed out")
+			return errors.Errorf("Subscription attempt timed out on topic %v", topic)
+		default:
+			venom.Debug(ctx, "Failed to subscribe")
+			return errors.Wrapf(err, "failed to subscribe to topic %v", topic)
+		}
+	}
+	return nil
+}
+
+// withTimeout calls f with a context cancelled after the timeout of the step
+func (e Executor) withTimeout(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+	return f(ctx)
 }
 
 // persistMessages is a step that registers or un-registers persistent topic subscriptions against a given client id
@@ -290,36 +308,20 @@ func (e Executor) persistMessages(ctx context.Context) error {
 		venom.Debug(ctx, "Failed to create session (persistMessages)")
 		return err
 	}
-	defer client.Disconnect(disconnectTimeoutMs)
+	defer client.disconnect()
 
-	for _, topic := range e.Topics {
-		token := client.Subscribe(topic, e.QOS, func(client mq.Client, message mq.Message) {
-			venom.Debug(ctx, "msg received in persist request: %v", string(message.Payload()))
-		})
-		select {
-		case <-token.Done():
-			if token.Error() != nil {
-				venom.Debug(ctx, "Failed to subscribe")
-				return errors.Wrapf(token.Error(), "failed to subscribe to topic %v", topic)
-			}
-			// else subscription complete, all good.
-		case <-time.After(time.Duration(e.Timeout) * time.Millisecond):
-			venom.Debug(ctx, "Subscription attempt timed out")
-			return errors.Errorf("Subscription attempt timed out on topic %v", topic)
-		case <-ctx.Done():
-			venom.Debug(ctx, "Context requested cancellation")
-			return errors.New("Context requested cancellation")
-		}
-	}
-	return nil
+	return e.subscribe(ctx, client)
 }
 
-// newSubscriber is a topic subscription handler that forwards onto the passed channel
-func newSubscriber(ctx context.Context, ch chan mq.Message) func(client mq.Client, message mq.Message) {
-	return func(client mq.Client, message mq.Message) {
-		t := message.Topic()
-		m := message.Payload()
-		venom.Debug(ctx, "rx message in subscribe handler: %s len(%d), %v", t, len(m), m)
-		ch <- message
+// newSubscriber is a topic subscription handler that forwards onto the passed channel,
+// the messages received once done is closed are dropped
+func newSubscriber(ctx context.Context, ch chan received, done <-chan struct{}) func(message received) {
+	return func(message received) {
+		venom.Debug(ctx, "rx message in subscribe handler: %s len(%d), %v", message.topic, len(message.payload), message.payload)
+		select {
+		case ch <- message:
+		case <-done:
+			venom.Debug(ctx, "message dropped, the message limit is reached")
+		}
 	}
 }
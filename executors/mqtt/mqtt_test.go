@@ -7,7 +7,6 @@ import (
 	"testing"
 	"time"
 
-	mq "github.com/eclipse/paho.mqtt.golang"
 	"github.com/mitchellh/mapstructure"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -53,7 +52,7 @@ func TestExecutor_Run_InvalidClientType(t *testing.T) {
 
 	// This should return an error (not in result.Err) for invalid client type
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "must be publisher, subscriber or persistent_queue")
+	assert.Contains(t, err.Error(), "must be publisher, subscriber, persistent_queue or collect")
 }
 
 func TestExecutor_Run_DefaultValues(t *testing.T) {
@@ -252,14 +251,19 @@ func TestConstants(t *testing.T) {
 
 func TestNewSubscriber(t *testing.T) {
 	ctx := context.Background()
-	ch := make(chan mq.Message, 10)
-	defer close(ch)
+	ch := make(chan received, 1)
+	done := make(chan struct{})
 
-	subscriber := newSubscriber(ctx, ch)
+	subscriber := newSubscriber(ctx, ch, done)
 	require.NotNil(t, subscriber)
+	subscriber(received{topic: "venom/events"})
 
-	// Note: Testing the actual subscriber would require a mock mq.Client and mq.Message
-	// which is complex without a proper MQTT broker
+	// the messages are dropped once done is closed, instead of blocking the client
+	close(done)
+	for i := 0; i < 10; i++ {
+		subscriber(received{topic: "venom/events"})
+	}
+	assert.Len(t, ch, 1)
 }
 
 func TestExecutor_Run_TimeTracking(t *testing.T) {
@@ -551,7 +555,7 @@ func TestResult_ErrorHandling(t *testing.T) {
 }
 
 func TestExecutor_ClientTypes(t *testing.T) {
-	validTypes := []string{"publisher", "subscriber", "persistent_queue"}
+	validTypes := []string{"publisher", "subscriber", "persistent_queue", "collect"}
 	invalidTypes := []string{"consumer", "producer", "invalid", ""}
 
 	for _, ct := range validTypes {
@@ -620,3 +624,17 @@ func TestExecutor_ClientIDUniqueness(t *testing.T) {
 	assert.Equal(t, "client-1", e1.ClientID)
 	assert.Equal(t, "client-2", e2.ClientID)
 }
+
+func TestExecutor_Run_Collect(t *testing.T) {
+	venom.InitTestLogger(t)
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+	defer Executor{}.TearDown(ctx) //nolint
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"clientType": "collect"})
+	require.EqualError(t, err, "background is mandatory to collect messages")
+
+	res, err := Executor{}.Run(ctx, venom.TestStep{"clientType": "collect", "background": "events"})
+	require.NoError(t, err)
+	assert.Equal(t, `background consumer "events" is not started`, res.(Result).Err)
+}
@@ -0,0 +1,283 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/jsondecode"
+)
+
+// Admin actions, used when ClientType is admin
+const (
+	actionCreateStream   = "createStream"
+	actionDeleteStream   = "deleteStream"
+	actionDescribeStream = "describeStream"
+	actionPurgeStream    = "purgeStream"
+	actionCreateBucket   = "createBucket"
+	actionDeleteBucket   = "deleteBucket"
+)
+
+// Key-value actions, used when ClientType is kv
+const (
+	actionPut    = "put"
+	actionGet    = "get"
+	actionDelete = "delete"
+	actionKeys   = "keys"
+)
+
+type (
+	// StreamDescription represents a stream described by the admin actions
+	StreamDescription struct {
+		Name          string   `json:"name" yaml:"name"`
+		Subjects      []string `json:"subjects" yaml:"subjects"`
+		Messages      uint64   `json:"messages" yaml:"messages"`
+		Bytes         uint64   `json:"bytes" yaml:"bytes"`
+		FirstSequence uint64   `json:"firstsequence" yaml:"firstSequence"`
+		LastSequence  uint64   `json:"lastsequence" yaml:"lastSequence"`
+		Consumers     int      `json:"consumers" yaml:"consumers"`
+	}
+
+	// Entry represents a key-value entry
+	Entry struct {
+		Key       string      `json:"key" yaml:"key"`
+		Found     bool        `json:"found" yaml:"found"`
+		Value     string      `json:"value,omitempty" yaml:"value,omitempty"`
+		ValueJSON interface{} `json:"valuejson,omitempty" yaml:"valueJSON,omitempty"`
+		Revision  uint64      `json:"revision,omitempty" yaml:"revision,omitempty"`
+	}
+)
+
+// publishJetStream sends the messages of the step to their stream, and returns their acknowledgements
+func (e Executor) publishJetStream(ctx context.Context, nc *nats.Conn) ([]PubAck, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	acks := make([]PubAck, 0, len(e.Messages))
+	for i, m := range e.Messages {
+		msg, err := e.natsMsg(i, m)
+		if err != nil {
+			return nil, err
+		}
+		ack, err := withTimeout(ctx, e, func(ctx context.Context) (*jetstream.PubAck, error) { return js.PublishMsg(ctx, msg) })
+		if err != nil {
+			return nil, fmt.Errorf("unable to publish messages[%d] on %s: %w", i, msg.Subject, err)
+		}
+		venom.Debug(ctx, "message[%d] published on %s, stream %s sequence %d", i, msg.Subject, ack.Stream, ack.Sequence)
+		acks = append(acks, PubAck{Stream: ack.Stream, Sequence: ack.Sequence, Duplicate: ack.Duplicate})
+	}
+	return acks, nil
+}
+
+// consumeJetStream reads and acknowledges messages from the durable consumer of the stream, created on the subject
+// if it does not exist, until MessageLimit messages are received or until the timeout
+func (e Executor) consumeJetStream(ctx context.Context, nc *nats.Conn) ([]Message, error) {
+	if e.Stream == "" || e.Consumer == "" {
+		return nil, errors.New("stream and consumer are mandatory to consume messages with JetStream")
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := withTimeout(ctx, e, func(ctx context.Context) (jetstream.Consumer, error) {
+		consumer, err := js.Consumer(ctx, e.Stream, e.Consumer)
+		if errors.Is(err, jetstream.ErrConsumerNotFound) {
+			venom.Debug(ctx, "creating consumer %s on stream %s", e.Consumer, e.Stream)
+			return js.CreateConsumer(ctx, e.Stream, jetstream.ConsumerConfig{
+				Durable:       e.Consumer,
+				FilterSubject: e.Subject,
+				AckPolicy:     jetstream.AckExplicitPolicy,
+			})
+		}
+		return consumer, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get consumer %s of stream %s: %w", e.Consumer, e.Stream, err)
+	}
+
+	deadline := time.Now().Add(time.Duration(e.Timeout) * time.Millisecond)
+	messages := []Message{}
+	for e.MessageLimit == 0 || len(messages) < e.MessageLimit {
+		wait := time.Until(deadline)
+		if wait < time.Millisecond {
+			venom.Debug(ctx, "%d messages received before the timeout", len(messages))
+			break
+		}
+		msg, err := consumer.Next(jetstream.FetchMaxWait(wait))
+		if errors.Is(err, nats.ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read messages: %w", err)
+		}
+		if err := msg.Ack(); err != nil {
+			return nil, fmt.Errorf("unable to acknowledge message: %w", err)
+		}
+		m := Message{Subject: msg.Subject(), Payload: string(msg.Data())}
+		if headers := msg.Headers(); len(headers) > 0 {
+			m.Headers = make(map[string]string, len(headers))
+			for k, v := range headers {
+				if len(v) > 0 {
+					m.Headers[k] = v[0]
+				}
+			}
+		}
+		if metadata, err := msg.Metadata(); err == nil {
+			m.Sequence = metadata.Sequence.Stream
+		}
+		venom.Debug(ctx, "message received on %s, sequence %d", m.Subject, m.Sequence)
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// admin runs the admin action of the step on a stream or a key-value bucket
+func (e Executor) admin(ctx context.Context, nc *nats.Conn) (*StreamDescription, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+
+	switch e.Action {
+	case actionCreateStream:
+		config := jetstream.StreamConfig{
+			Name:     e.Stream,
+			Subjects: e.Subjects,
+			Replicas: e.Replicas,
+			MaxMsgs:  e.MaxMsgs,
+		}
+		if config.MaxMsgs == 0 {
+			config.MaxMsgs = -1
+		}
+		switch e.Storage {
+		case "", "file":
+			config.Storage = jetstream.FileStorage
+		case "memory":
+			config.Storage = jetstream.MemoryStorage
+		default:
+			return nil, fmt.Errorf("storage %q must be file or memory", e.Storage)
+		}
+		stream, err := js.CreateOrUpdateStream(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create stream %s: %w", e.Stream, err)
+		}
+		return describeStream(ctx, stream)
+	case actionDeleteStream:
+		if err := js.DeleteStream(ctx, e.Stream); err != nil {
+			return nil, fmt.Errorf("unable to delete stream %s: %w", e.Stream, err)
+		}
+		return nil, nil
+	case actionDescribeStream, actionPurgeStream:
+		stream, err := js.Stream(ctx, e.Stream)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get stream %s: %w", e.Stream, err)
+		}
+		if e.Action == actionPurgeStream {
+			if err := stream.Purge(ctx); err != nil {
+				return nil, fmt.Errorf("unable to purge stream %s: %w", e.Stream, err)
+			}
+		}
+		return describeStream(ctx, stream)
+	case actionCreateBucket:
+		_, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+			Bucket:   e.Bucket,
+			History:  e.History,
+			TTL:      time.Duration(e.TTL) * time.Second,
+			Replicas: e.Replicas,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create bucket %s: %w", e.Bucket, err)
+		}
+		return nil, nil
+	case actionDeleteBucket:
+		if err := js.DeleteKeyValue(ctx, e.Bucket); err != nil {
+			return nil, fmt.Errorf("unable to delete bucket %s: %w", e.Bucket, err)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("action must be one of %s, %s, %s, %s, %s or %s", actionCreateStream, actionDeleteStream,
+			actionDescribeStream, actionPurgeStream, actionCreateBucket, actionDeleteBucket)
+	}
+}
+
+func describeStream(ctx context.Context, stream jetstream.Stream) (*StreamDescription, error) {
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe stream: %w", err)
+	}
+	return &StreamDescription{
+		Name:          info.Config.Name,
+		Subjects:      info.Config.Subjects,
+		Messages:      info.State.Msgs,
+		Bytes:         info.State.Bytes,
+		FirstSequence: info.State.FirstSeq,
+		LastSequence:  info.State.LastSeq,
+		Consumers:     info.State.Consumers,
+	}, nil
+}
+
+// kv runs the kv action of the step on the key-value bucket
+func (e Executor) kv(ctx context.Context, nc *nats.Conn) (*Entry, []string, error) {
+	if e.Bucket == "" {
+		return nil, nil, errors.New("bucket is mandatory")
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+	kv, err := js.KeyValue(ctx, e.Bucket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get bucket %s: %w", e.Bucket, err)
+	}
+
+	switch e.Action {
+	case actionPut:
+		revision, err := kv.Put(ctx, e.Key, []byte(e.Value))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to put key %s: %w", e.Key, err)
+		}
+		return &Entry{Key: e.Key, Found: true, Value: e.Value, ValueJSON: jsondecode.Message(ctx, []byte(e.Value)), Revision: revision}, nil, nil
+	case actionGet:
+		entry, err := kv.Get(ctx, e.Key)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return &Entry{Key: e.Key}, nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get key %s: %w", e.Key, err)
+		}
+		return &Entry{Key: e.Key, Found: true, Value: string(entry.Value()), ValueJSON: jsondecode.Message(ctx, entry.Value()), Revision: entry.Revision()}, nil, nil
+	case actionDelete:
+		if err := kv.Delete(ctx, e.Key); err != nil {
+			return nil, nil, fmt.Errorf("unable to delete key %s: %w", e.Key, err)
+		}
+		return nil, nil, nil
+	case actionKeys:
+		keys, err := kv.Keys(ctx)
+		if errors.Is(err, jetstream.ErrNoKeysFound) {
+			return nil, []string{}, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to list keys: %w", err)
+		}
+		return nil, keys, nil
+	default:
+		return nil, nil, fmt.Errorf("action must be one of %s, %s, %s or %s", actionPut, actionGet, actionDelete, actionKeys)
+	}
+}
+
+// withTimeout calls f with a context cancelled after the timeout of the step
+func withTimeout[T any](ctx context.Context, e Executor, f func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+	return f(ctx)
+}
@@ -0,0 +1,98 @@
+package nats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func TestExecutor_Run_JetStream(t *testing.T) {
+	venom.InitTestLogger(t)
+	url := runServer(t)
+
+	res := run(t, venom.TestStep{
+		"url":        url,
+		"clientType": "admin",
+		"action":     "createStream",
+		"stream":     "ORDERS",
+		"subjects":   []string{"orders.>"},
+		"storage":    "memory",
+	})
+	require.NotNil(t, res.Stream)
+	assert.Equal(t, StreamDescription{Name: "ORDERS", Subjects: []string{"orders.>"}}, *res.Stream)
+
+	res = run(t, venom.TestStep{
+		"url":        url,
+		"clientType": "publisher",
+		"jetstream":  true,
+		"messages": []interface{}{
+			map[string]interface{}{"subject": "orders.created", "payload": `{"id": 1}`, "headers": map[string]string{"Nats-Msg-Id": "1"}},
+			map[string]interface{}{"subject": "orders.created", "payload": `{"id": 1}`, "headers": map[string]string{"Nats-Msg-Id": "1"}},
+			map[string]interface{}{"subject": "orders.paid", "payload": `{"id": 1}`},
+		},
+	})
+	assert.Equal(t, []PubAck{
+		{Stream: "ORDERS", Sequence: 1},
+		{Stream: "ORDERS", Sequence: 1, Duplicate: true},
+		{Stream: "ORDERS", Sequence: 2},
+	}, res.Acks)
+
+	// the durable consumer is created on the first read, the next reads resume after the acknowledged messages
+	consume := venom.TestStep{
+		"url":          url,
+		"clientType":   "subscriber",
+		"jetstream":    true,
+		"stream":       "ORDERS",
+		"consumer":     "venom",
+		"subject":      "orders.>",
+		"messageLimit": 1,
+		"timeout":      500,
+	}
+	res = run(t, consume)
+	require.Len(t, res.Messages, 1)
+	assert.Equal(t, Message{Subject: "orders.created", Payload: `{"id": 1}`, Headers: map[string]string{"Nats-Msg-Id": "1"}, Sequence: 1}, res.Messages[0])
+	consume["messageLimit"] = 0
+	res = run(t, consume)
+	require.Len(t, res.Messages, 1)
+	assert.Equal(t, "orders.paid", res.Messages[0].Subject)
+	assert.Equal(t, uint64(2), res.Messages[0].Sequence)
+
+	res = run(t, venom.TestStep{"url": url, "clientType": "admin", "action": "purgeStream", "stream": "ORDERS"})
+	assert.Equal(t, uint64(0), res.Stream.Messages)
+	assert.Equal(t, 1, res.Stream.Consumers)
+	run(t, venom.TestStep{"url": url, "clientType": "admin", "action": "deleteStream", "stream": "ORDERS"})
+
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"url": url, "clientType": "admin", "action": "describeStream", "stream": "ORDERS"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to get stream ORDERS")
+}
+
+func TestExecutor_Run_KeyValue(t *testing.T) {
+	venom.InitTestLogger(t)
+	url := runServer(t)
+
+	run(t, venom.TestStep{"url": url, "clientType": "admin", "action": "createBucket", "bucket": "config", "history": 5})
+
+	kv := func(action, key, value string) Result {
+		return run(t, venom.TestStep{"url": url, "clientType": "kv", "bucket": "config", "action": action, "key": key, "value": value})
+	}
+	assert.Equal(t, []string{}, kv("keys", "", "").Keys)
+	assert.Equal(t, uint64(1), kv("put", "feature", `{"enabled": true}`).Entry.Revision)
+	assert.Equal(t, uint64(2), kv("put", "feature", `{"enabled": false}`).Entry.Revision)
+
+	entry := kv("get", "feature", "").Entry
+	assert.Equal(t, &Entry{Key: "feature", Found: true, Value: `{"enabled": false}`, ValueJSON: map[string]interface{}{"enabled": false}, Revision: 2}, entry)
+	assert.Equal(t, []string{"feature"}, kv("keys", "", "").Keys)
+
+	kv("delete", "feature", "")
+	assert.False(t, kv("get", "feature", "").Entry.Found)
+
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"url": url, "clientType": "kv", "bucket": "config", "action": "watch"})
+	require.EqualError(t, err, "action must be one of put, get, delete or keys")
+
+	run(t, venom.TestStep{"url": url, "clientType": "admin", "action": "deleteBucket", "bucket": "config"})
+}
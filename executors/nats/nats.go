@@ -0,0 +1,314 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nats-io/nats.go"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/jsondecode"
+)
+
+// Name of executor
+const Name = "nats"
+
+const (
+	defaultTimeoutMs        = 5000
+	defaultConnectTimeoutMs = 5000
+)
+
+// New returns a new Executor
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor represents the nats executor
+type Executor struct {
+	// URL of the server, nats://localhost:4222 by default
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"`
+
+	// ClientType must be "publisher", "subscriber", "requester", "admin" or "kv"
+	ClientType string `json:"client_type" yaml:"clientType"`
+	// JetStream publishes the messages with an acknowledgement, or consumes them from a durable consumer
+	JetStream bool `json:"jetstream" yaml:"jetstream"`
+
+	// Subject of the subscriber, and default subject of the messages
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	// QueueGroup of the subscriber
+	QueueGroup string `json:"queue_group,omitempty" yaml:"queueGroup,omitempty"`
+	// Messages sent by the publisher and the requester, or by the subscriber once subscribed
+	Messages []Message `json:"messages,omitempty" yaml:"messages,omitempty"`
+
+	// MessageLimit is the number of messages the subscriber waits for. Without limit, it reads messages until the timeout
+	MessageLimit int `json:"message_limit" yaml:"messageLimit"`
+	// Timeout to read messages and to wait for the replies. In Milliseconds. Default 5000
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// ConnectTimeout to the server. In Milliseconds. Default 5000
+	ConnectTimeout int64 `json:"connect_timeout,omitempty" yaml:"connectTimeout,omitempty"`
+
+	// Stream and Consumer are the JetStream stream and durable consumer of the subscriber, and the stream of the admin actions
+	Stream   string `json:"stream,omitempty" yaml:"stream,omitempty"`
+	Consumer string `json:"consumer,omitempty" yaml:"consumer,omitempty"`
+
+	// Used when ClientType is admin or kv
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Subjects, Storage ("file" or "memory"), Replicas and MaxMsgs of the created stream
+	Subjects []string `json:"subjects,omitempty" yaml:"subjects,omitempty"`
+	Storage  string   `json:"storage,omitempty" yaml:"storage,omitempty"`
+	Replicas int      `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	MaxMsgs  int64    `json:"max_msgs,omitempty" yaml:"maxMsgs,omitempty"`
+	// Bucket, History and TTL (in seconds) of the key-value bucket
+	Bucket  string `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	History uint8  `json:"history,omitempty" yaml:"history,omitempty"`
+	TTL     int64  `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Key and Value of the kv actions
+	Key   string `json:"key,omitempty" yaml:"key,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Message represents a message sent or received
+type Message struct {
+	Subject string            `json:"subject" yaml:"subject"`
+	Payload string            `json:"payload" yaml:"payload"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Sequence of the message in its JetStream stream
+	Sequence uint64 `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+}
+
+// PubAck represents the acknowledgement of a message published with JetStream
+type PubAck struct {
+	Stream    string `json:"stream" yaml:"stream"`
+	Sequence  uint64 `json:"sequence" yaml:"sequence"`
+	Duplicate bool   `json:"duplicate" yaml:"duplicate"`
+}
+
+// Result represents a step result.
+type Result struct {
+	TimeSeconds  float64       `json:"timeseconds" yaml:"timeSeconds"`
+	Messages     []Message     `json:"messages" yaml:"messages"`
+	MessagesJSON []interface{} `json:"messagesjson" yaml:"messagesJSON"`
+	// Acks of the messages published with JetStream, by index
+	Acks []PubAck `json:"acks,omitempty" yaml:"acks,omitempty"`
+	// Stream described by the admin actions
+	Stream *StreamDescription `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// Entry of the kv actions, and Keys of the bucket
+	Entry *Entry   `json:"entry,omitempty" yaml:"entry,omitempty"`
+	Keys  []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// ZeroValueResult return an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// GetDefaultAssertions return default assertions for type exec
+func (Executor) GetDefaultAssertions() *venom.StepAssertions {
+	return &venom.StepAssertions{}
+}
+
+// Run execute TestStep
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	var e Executor
+	if err := mapstructure.Decode(step, &e); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := Result{}
+
+	// Default values
+	if e.URL == "" {
+		e.URL = nats.DefaultURL
+	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeoutMs
+	}
+	if e.ConnectTimeout == 0 {
+		e.ConnectTimeout = defaultConnectTimeoutMs
+	}
+
+	switch e.ClientType {
+	case "publisher", "subscriber", "requester", "admin", "kv":
+	default:
+		return nil, fmt.Errorf("clientType %q must be publisher, subscriber, requester, admin or kv", e.ClientType)
+	}
+
+	nc, err := e.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	switch e.ClientType {
+	case "publisher":
+		if e.JetStream {
+			result.Acks, err = e.publishJetStream(ctx, nc)
+		} else {
+			err = e.publish(ctx, nc)
+		}
+	case "subscriber":
+		if e.JetStream {
+			result.Messages, err = e.consumeJetStream(ctx, nc)
+		} else {
+			result.Messages, err = e.subscribe(ctx, nc)
+		}
+	case "requester":
+		result.Messages, err = e.request(ctx, nc)
+	case "admin":
+		result.Stream, err = e.admin(ctx, nc)
+	case "kv":
+		result.Entry, result.Keys, err = e.kv(ctx, nc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.MessagesJSON = make([]interface{}, 0, len(result.Messages))
+	for _, m := range result.Messages {
+		result.MessagesJSON = append(result.MessagesJSON, jsondecode.Message(ctx, []byte(m.Payload)))
+	}
+	if result.Messages == nil {
+		result.Messages = []Message{}
+	}
+	result.TimeSeconds = time.Since(start).Seconds()
+
+	return result, nil
+}
+
+// connect opens the connection to the server
+func (e Executor) connect(ctx context.Context) (*nats.Conn, error) {
+	opts := []nats.Option{
+		nats.Name("venom"),
+		nats.Timeout(time.Duration(e.ConnectTimeout) * time.Millisecond),
+	}
+	if e.User != "" {
+		opts = append(opts, nats.UserInfo(e.User, e.Password))
+	}
+	if e.Token != "" {
+		opts = append(opts, nats.Token(e.Token))
+	}
+	venom.Debug(ctx, "connecting to %s", e.URL)
+	nc, err := nats.Connect(e.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", e.URL, err)
+	}
+	return nc, nil
+}
+
+// natsMsg returns the NATS message of the i-th message of the step
+func (e Executor) natsMsg(i int, m Message) (*nats.Msg, error) {
+	subject := m.Subject
+	if subject == "" {
+		subject = e.Subject
+	}
+	if subject == "" {
+		return nil, fmt.Errorf("subject is mandatory in messages[%d]", i)
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = []byte(m.Payload)
+	for k, v := range m.Headers {
+		msg.Header.Set(k, v)
+	}
+	return msg, nil
+}
+
+// publish sends the messages of the step
+func (e Executor) publish(ctx context.Context, nc *nats.Conn) error {
+	for i, m := range e.Messages {
+		msg, err := e.natsMsg(i, m)
+		if err != nil {
+			return err
+		}
+		if err := nc.PublishMsg(msg); err != nil {
+			return fmt.Errorf("unable to publish messages[%d] on %s: %w", i, msg.Subject, err)
+		}
+		venom.Debug(ctx, "message[%d] published on %s", i, msg.Subject)
+	}
+	// the messages are buffered until the flush
+	if err := nc.FlushTimeout(time.Duration(e.Timeout) * time.Millisecond); err != nil {
+		return fmt.Errorf("unable to flush the messages: %w", err)
+	}
+	return nil
+}
+
+// subscribe subscribes to the subject, sends the messages of the step,
+// then reads messages until MessageLimit messages are received or until the timeout
+func (e Executor) subscribe(ctx context.Context, nc *nats.Conn) ([]Message, error) {
+	if e.Subject == "" {
+		return nil, errors.New("subject is mandatory to subscribe")
+	}
+	var sub *nats.Subscription
+	var err error
+	if e.QueueGroup != "" {
+		sub, err = nc.QueueSubscribeSync(e.Subject, e.QueueGroup)
+	} else {
+		sub, err = nc.SubscribeSync(e.Subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to %s: %w", e.Subject, err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+	// the subscription is registered on the server before the messages are sent
+	if err := nc.Flush(); err != nil {
+		return nil, fmt.Errorf("unable to subscribe to %s: %w", e.Subject, err)
+	}
+	if err := e.publish(ctx, nc); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+	messages := []Message{}
+	for e.MessageLimit == 0 || len(messages) < e.MessageLimit {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				venom.Debug(ctx, "%d messages received before the timeout", len(messages))
+				break
+			}
+			return nil, fmt.Errorf("unable to read messages: %w", err)
+		}
+		venom.Debug(ctx, "message received on %s", msg.Subject)
+		messages = append(messages, newMessage(msg))
+	}
+	return messages, nil
+}
+
+// request sends the messages of the step as requests and returns their replies
+func (e Executor) request(ctx context.Context, nc *nats.Conn) ([]Message, error) {
+	replies := make([]Message, 0, len(e.Messages))
+	for i, m := range e.Messages {
+		msg, err := e.natsMsg(i, m)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := nc.RequestMsg(msg, time.Duration(e.Timeout)*time.Millisecond)
+		if err != nil {
+			return nil, fmt.Errorf("no reply to messages[%d] on %s: %w", i, msg.Subject, err)
+		}
+		venom.Debug(ctx, "reply received to messages[%d] on %s", i, msg.Subject)
+		replies = append(replies, newMessage(reply))
+	}
+	return replies, nil
+}
+
+// newMessage returns the received message, with the first value of its headers
+func newMessage(msg *nats.Msg) Message {
+	m := Message{Subject: msg.Subject, Payload: string(msg.Data)}
+	if len(msg.Header) > 0 {
+		m.Headers = make(map[string]string, len(msg.Header))
+		for k, v := range msg.Header {
+			if len(v) > 0 {
+				m.Headers[k] = v[0]
+			}
+		}
+	}
+	return m
+}
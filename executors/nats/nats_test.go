@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// runServer starts a local nats-server with JetStream
+func runServer(t *testing.T) string {
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go s.Start()
+	require.True(t, s.ReadyForConnections(5*time.Second), "nats-server is not ready")
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
+
+func run(t *testing.T, step venom.TestStep) Result {
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	return res.(Result)
+}
+
+func TestExecutor_Run_InvalidClientType(t *testing.T) {
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"clientType": "consumer"})
+	require.EqualError(t, err, `clientType "consumer" must be publisher, subscriber, requester, admin or kv`)
+}
+
+func TestExecutor_Run_Subscriber(t *testing.T) {
+	venom.InitTestLogger(t)
+	url := runServer(t)
+
+	// the messages of the subscriber are sent once subscribed
+	res := run(t, venom.TestStep{
+		"url":          url,
+		"clientType":   "subscriber",
+		"subject":      "orders.*",
+		"messageLimit": 2,
+		"messages": []interface{}{
+			map[string]interface{}{"subject": "orders.created", "payload": `{"id": 1}`, "headers": map[string]string{"Origin": "venom"}},
+			map[string]interface{}{"subject": "orders.paid", "payload": "paid"},
+			map[string]interface{}{"subject": "orders.shipped", "payload": "shipped"},
+		},
+	})
+	require.Len(t, res.Messages, 2)
+	assert.Equal(t, Message{Subject: "orders.created", Payload: `{"id": 1}`, Headers: map[string]string{"Origin": "venom"}}, res.Messages[0])
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, res.MessagesJSON[0])
+	assert.Equal(t, "orders.paid", res.Messages[1].Subject)
+
+	// without limit, the messages are read until the timeout
+	res = run(t, venom.TestStep{
+		"url":        url,
+		"clientType": "subscriber",
+		"subject":    "orders.created",
+		"timeout":    200,
+		"messages":   []interface{}{map[string]interface{}{"payload": "a"}, map[string]interface{}{"payload": "b"}},
+	})
+	require.Len(t, res.Messages, 2)
+	assert.Equal(t, "b", res.Messages[1].Payload)
+}
+
+func TestExecutor_Run_Requester(t *testing.T) {
+	venom.InitTestLogger(t)
+	url := runServer(t)
+
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer nc.Close()
+	_, err = nc.Subscribe("greeter", func(msg *nats.Msg) {
+		_ = msg.Respond([]byte(`{"hello": "` + string(msg.Data) + `"}`))
+	})
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	res := run(t, venom.TestStep{
+		"url":        url,
+		"clientType": "requester",
+		"subject":    "greeter",
+		"messages":   []interface{}{map[string]interface{}{"payload": "venom"}},
+	})
+	require.Len(t, res.Messages, 1)
+	assert.Equal(t, map[string]interface{}{"hello": "venom"}, res.MessagesJSON[0])
+
+	_, err = Executor{}.Run(context.Background(), venom.TestStep{
+		"url":        url,
+		"clientType": "requester",
+		"timeout":    100,
+		"messages":   []interface{}{map[string]interface{}{"subject": "nobody", "payload": "venom"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no reply to messages[0] on nobody")
+}
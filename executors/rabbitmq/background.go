@@ -0,0 +1,120 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+)
+
+// Setup prepares the store of the background subscribers of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, background.NewConsumers[amqp.Delivery]()), nil
+}
+
+// TearDown stops the background subscribers left running by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	if consumers := getConsumers(ctx); consumers != nil {
+		consumers.StopAll()
+	}
+	return nil
+}
+
+func getConsumers(ctx context.Context) *background.Consumers[amqp.Delivery] {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*background.Consumers[amqp.Delivery])
+}
+
+// startBackground declares the queue and starts a subscriber running until the end of the testcase
+func (e Executor) startBackground(ctx context.Context) error {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return errors.New("background subscribers are not available outside of a testcase")
+	}
+	if _, err := consumers.Get(e.Background); err == nil {
+		return fmt.Errorf("background consumer %q is already started", e.Background)
+	}
+
+	conn, ch, err := e.openChannel(ctx)
+	if err != nil {
+		return err
+	}
+	q, err := e.declareQueue(ctx, ch)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+	deliveries, err := ch.Consume(
+		q.Name,      // queue
+		"",          // consumer
+		e.Ack == "", // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+
+	stopping := make(chan struct{})
+	stopped := make(chan struct{})
+	consumer := background.NewConsumer[amqp.Delivery](func() {
+		close(stopping)
+		ch.Close()
+		conn.Close()
+		<-stopped
+	})
+	go func() {
+		defer close(stopped)
+		for d := range deliveries {
+			if err := e.acknowledge(d); err != nil {
+				consumer.Fail(err)
+			}
+			consumer.Push(d)
+		}
+		select {
+		case <-stopping:
+		default:
+			consumer.Fail(fmt.Errorf("background subscriber %q stopped: the channel is closed", e.Background))
+		}
+	}()
+	venom.Debug(ctx, "background subscriber %q started on queue '%s'", e.Background, q.Name)
+	return consumers.Add(e.Background, consumer)
+}
+
+// collect returns the messages received by a background subscriber, once MessageLimit messages are received,
+// or the messages received before the timeout with an error
+func (e Executor) collect(ctx context.Context) ([]string, []interface{}, []interface{}, []amqp.Table, error) {
+	consumers := getConsumers(ctx)
+	if consumers == nil {
+		return nil, nil, nil, nil, errors.New("background subscribers are not available outside of a testcase")
+	}
+	consumer, err := consumers.Get(e.Background)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	deliveries, err := consumer.Collect(ctx, e.MessageLimit, time.Duration(e.Timeout)*time.Second)
+
+	body := []string{}
+	bodyJSON := []interface{}{}
+	messages := []interface{}{}
+	headers := []amqp.Table{}
+	for _, d := range deliveries {
+		headers = append(headers, d.Headers)
+		messages = append(messages, d)
+		body, bodyJSON = e.processMessage(ctx, d, true, body, bodyJSON)
+	}
+	return body, bodyJSON, messages, headers, err
+}
@@ -2,8 +2,10 @@ package rabbitmq
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/mitchellh/mapstructure"
@@ -13,8 +15,18 @@ import (
 	"github.com/streadway/amqp"
 )
 
-// Name of executor
-const Name = "rabbitmq"
+const (
+	// Name of executor
+	Name = "rabbitmq"
+	// ContextKey is the key of the background subscribers in the testcase context
+	ContextKey = venom.ContextKey("rabbitmqContext")
+
+	defaultTimeoutSeconds = 5
+
+	ackAck    = "ack"
+	ackNack   = "nack"
+	ackReject = "reject"
+)
 
 // New returns a new Executor
 func New() venom.Executor {
@@ -29,6 +41,13 @@ type Message struct {
 	ContentType     string     `json:"content_type" yaml:"contentType"`
 	ContentEncoding string     `json:"content_encoding" yaml:"contentEncoding"`
 	ReplyTo         string     `json:"reply_to" yaml:"replyTo"`
+	CorrelationID   string     `json:"correlation_id" yaml:"correlationId"`
+	MessageID       string     `json:"message_id" yaml:"messageId"`
+	Type            string     `json:"type" yaml:"type"`
+	// Priority of the message, from 0 to 9
+	Priority uint8 `json:"priority" yaml:"priority"`
+	// Expiration is the TTL of the message in milliseconds, eg: "60000"
+	Expiration string `json:"expiration" yaml:"expiration"`
 }
 
 // Executor represents a Test Exec
@@ -38,13 +57,19 @@ type Executor struct {
 	User     string `json:"user" yaml:"user"`
 	Password string `json:"password" yaml:"password"`
 
-	// ClientType must be "consumer" or "producer"
+	// ClientType must be "publisher", "subscriber", "client" or "collect"
 	ClientType string `json:"client_type" yaml:"clientType"`
 
 	// QName represents the RabbitMQ queue name
 	QName string `json:"q_name" yaml:"qName"`
 	// Durable represents the RabbitMQ durable parameter
 	Durable bool `json:"durable" yaml:"durable"`
+	// QueueArguments are the optional arguments of the declared queue, eg: x-max-priority
+	QueueArguments map[string]interface{} `json:"queue_arguments" yaml:"queueArguments"`
+	// DeadLetterExchange is the exchange the rejected or expired messages of the declared queue are republished to
+	DeadLetterExchange string `json:"dead_letter_exchange" yaml:"deadLetterExchange"`
+	// DeadLetterRoutingKey is the routing key of the dead-lettered messages, default their routing key
+	DeadLetterRoutingKey string `json:"dead_letter_routing_key" yaml:"deadLetterRoutingKey"`
 
 	// Exchange represents the RabbitMQ exchange
 	Exchange string `json:"exchange" yaml:"exchange"`
@@ -55,6 +80,20 @@ type Executor struct {
 
 	// Represents the limit of message will be read. After limit, consumer stop read message
 	MessageLimit int `json:"message_limit" yaml:"messageLimit"`
+	// Ack is the acknowledgement of the consumed messages: ack, nack or reject. Default the messages are acknowledged on delivery
+	Ack string `json:"ack" yaml:"ack"`
+	// Requeue the messages which are nacked or rejected, instead of discarding or dead-lettering them
+	Requeue bool `json:"requeue" yaml:"requeue"`
+
+	// Used when ClientType is client
+	// ExclusiveReplyQueue awaits the replies on an exclusive queue instead of the direct reply-to pseudo queue
+	ExclusiveReplyQueue bool `json:"exclusive_reply_queue" yaml:"exclusiveReplyQueue"`
+
+	// Background is the name of a subscriber running until the end of the testcase.
+	// Its messages are returned by the steps with the collect ClientType, once MessageLimit messages are received
+	Background string `json:"background" yaml:"background"`
+	// Timeout to collect the messages of a background subscriber or to await the replies of a client, in seconds. Default 5
+	Timeout int `json:"timeout" yaml:"timeout"`
 
 	// Used when ClientType is producer
 	// Messages represents the message sended by producer
@@ -92,7 +131,7 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	result := Result{}
 
 	// Default values
-	if e.Addrs == "" {
+	if e.Addrs == "" && e.ClientType != "collect" {
 		return nil, errors.New("address is mandatory")
 	}
 	if e.ExchangeType == "" {
@@ -101,51 +140,56 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	if e.MessageLimit == 0 {
 		e.MessageLimit = 1
 	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeoutSeconds
+	}
+	switch e.Ack {
+	case "", ackAck, ackNack, ackReject:
+	default:
+		return nil, fmt.Errorf("ack %q must be %s, %s or %s", e.Ack, ackAck, ackNack, ackReject)
+	}
 
 	switch e.ClientType {
 	case "publisher":
 		workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
-		err := e.publishMessages(ctx, workdir, nil, nil, false)
+		err := e.publishMessages(ctx, workdir, nil, nil, "")
 		if err != nil {
 			result.Err = err.Error()
 			return nil, err
 		}
 	case "subscriber":
+		if e.Background != "" {
+			if err := e.startBackground(ctx); err != nil {
+				result.Err = err.Error()
+				return nil, err
+			}
+			break
+		}
 		var err error
 		result.Body, result.BodyJSON, result.Messages, result.Headers, err = e.consumeMessages(ctx)
 		if err != nil {
 			result.Err = err.Error()
 			return nil, err
 		}
-	case "client":
-		conn, ch, err := e.openChannel(ctx)
+	case "collect":
+		if e.Background == "" {
+			return nil, errors.New("background is mandatory to collect messages")
+		}
+		// the messages received before the timeout are returned with the error, like the other collect steps
+		var err error
+		result.Body, result.BodyJSON, result.Messages, result.Headers, err = e.collect(ctx)
 		if err != nil {
 			result.Err = err.Error()
-			return nil, err
-		}
-		defer ch.Close()
-		defer conn.Close()
-		delivery, consumererr := ch.Consume("amq.rabbitmq.reply-to", "", true, false, false, false, nil)
-		if consumererr != nil {
-			return nil, consumererr
 		}
-		venom.Info(ctx, "Reply consumer started.")
-
-		workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
-		err = e.publishMessages(ctx, workdir, conn, ch, true)
+	case "client":
+		var err error
+		result.Body, result.BodyJSON, result.Messages, result.Headers, err = e.call(ctx)
 		if err != nil {
 			result.Err = err.Error()
 			return nil, err
 		}
-
-		d := <-delivery
-		body := []string{}
-		bodyJSON := []interface{}{}
-		body, bodyJSON = e.processMessage(ctx, d, true, body, bodyJSON)
-		result.Body = body
-		result.BodyJSON = bodyJSON
 	default:
-		return nil, fmt.Errorf("clientType %q must be publisher or subscriber or client", e.ClientType)
+		return nil, fmt.Errorf("clientType %q must be publisher or subscriber or client or collect", e.ClientType)
 	}
 
 	elapsed := time.Since(start)
@@ -154,7 +198,8 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	return result, nil
 }
 
-func (e Executor) publishMessages(ctx context.Context, workdir string, connection *amqp.Connection, channel *amqp.Channel, rpc bool) error {
+// publishMessages publishes the messages of the step, with the replyTo queue if not empty
+func (e Executor) publishMessages(ctx context.Context, workdir string, connection *amqp.Connection, channel *amqp.Channel, replyTo string) error {
 	var ch *amqp.Channel
 	var err error
 	if connection == nil || channel == nil {
@@ -190,12 +235,12 @@ func (e Executor) publishMessages(ctx context.Context, workdir string, connectio
 		}
 		routingKey = e.QName
 		_, err := ch.QueueDeclare(
-			e.QName,   // name
-			e.Durable, // durable
-			false,     // delete when unused
-			false,     // exclusive
-			false,     // no-wait
-			nil,       // arguments
+			e.QName,            // name
+			e.Durable,          // durable
+			false,              // delete when unused
+			false,              // exclusive
+			false,              // no-wait
+			e.queueArguments(), // arguments
 		)
 		if err != nil {
 			return err
@@ -205,27 +250,13 @@ func (e Executor) publishMessages(ctx context.Context, workdir string, connectio
 
 	venom.Debug(ctx, "%d message to send", len(e.Messages))
 	for i := range e.Messages {
-		deliveryMode := amqp.Persistent
-		if !e.Messages[i].Persistent {
-			deliveryMode = amqp.Transient
-		}
-		var replyTo string = e.Messages[i].ReplyTo
-		if rpc {
-			replyTo = "amq.rabbitmq.reply-to"
-		}
 		err = ch.Publish(
 			e.Exchange, // exchange
 			routingKey, // routing key
 			false,      // mandatory
 			false,      // immediate
-			amqp.Publishing{
-				DeliveryMode:    deliveryMode,
-				ContentType:     e.Messages[i].ContentType,
-				ContentEncoding: e.Messages[i].ContentEncoding,
-				ReplyTo:         replyTo,
-				Body:            []byte(e.Messages[i].Value),
-				Headers:         e.Messages[i].Headers,
-			})
+			e.Messages[i].publishing(replyTo),
+		)
 		if err != nil {
 			return err
 		}
@@ -235,6 +266,30 @@ func (e Executor) publishMessages(ctx context.Context, workdir string, connectio
 	return nil
 }
 
+// publishing returns the message to publish with its properties, replyTo overrides the ReplyTo of the message if not empty
+func (m Message) publishing(replyTo string) amqp.Publishing {
+	deliveryMode := amqp.Persistent
+	if !m.Persistent {
+		deliveryMode = amqp.Transient
+	}
+	if replyTo == "" {
+		replyTo = m.ReplyTo
+	}
+	return amqp.Publishing{
+		DeliveryMode:    deliveryMode,
+		ContentType:     m.ContentType,
+		ContentEncoding: m.ContentEncoding,
+		ReplyTo:         replyTo,
+		CorrelationId:   m.CorrelationID,
+		MessageId:       m.MessageID,
+		Type:            m.Type,
+		Priority:        m.Priority,
+		Expiration:      m.Expiration,
+		Body:            []byte(m.Value),
+		Headers:         amqpTable(m.Headers),
+	}
+}
+
 func (e Executor) openChannel(ctx context.Context) (*amqp.Connection, *amqp.Channel, error) {
 	uri, err := amqp.ParseURI(e.Addrs)
 	if err != nil {
@@ -282,16 +337,49 @@ func (e Executor) consumeMessages(ctx context.Context) ([]string, []interface{},
 	defer conn.Close()
 	defer ch.Close()
 
+	q, err := e.declareQueue(ctx, ch)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	body := []string{}
+	bodyJSON := []interface{}{}
+	messages := []interface{}{}
+	headers := []amqp.Table{}
+
+	for i := 0; i < e.MessageLimit; i++ {
+		venom.Debug(ctx, "Read message n° %d", i)
+
+		msg, ok, err := ch.Get(q.Name, e.Ack == "") // Read one message from RabbitMQ
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if ok {
+			if err := e.acknowledge(msg); err != nil {
+				return nil, nil, nil, nil, err
+			}
+		}
+
+		headers = append(headers, msg.Headers)
+		messages = append(messages, msg)
+		body, bodyJSON = e.processMessage(ctx, msg, ok, body, bodyJSON)
+	}
+
+	return body, bodyJSON, messages, headers, err
+}
+
+// declareQueue declares the queue of the subscriber, and binds it to the exchange if defined
+func (e Executor) declareQueue(ctx context.Context, ch *amqp.Channel) (amqp.Queue, error) {
 	q, err := ch.QueueDeclare(
-		e.QName,   // name
-		e.Durable, // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+		e.QName,            // name
+		e.Durable,          // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		e.queueArguments(), // arguments
 	)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return q, err
 	}
 	venom.Debug(ctx, "Q declared '%s'", q.Name)
 
@@ -307,7 +395,7 @@ func (e Executor) consumeMessages(ctx context.Context) ([]string, []interface{},
 			nil,            // arguments
 		)
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return q, err
 		}
 		venom.Debug(ctx, "exchange declared '%s' '%s'", e.Exchange, e.ExchangeType)
 
@@ -319,28 +407,164 @@ func (e Executor) consumeMessages(ctx context.Context) ([]string, []interface{},
 			nil,          // arguments
 		)
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return q, err
 		}
 		venom.Debug(ctx, "Q binded '%s' '%s'", q.Name, e.RoutingKey)
 	}
+	return q, nil
+}
+
+// call publishes the messages and awaits their replies, once MessageLimit replies are received
+func (e Executor) call(ctx context.Context) ([]string, []interface{}, []interface{}, []amqp.Table, error) {
+	conn, ch, err := e.openChannel(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	replyTo := "amq.rabbitmq.reply-to"
+	if e.ExclusiveReplyQueue {
+		q, err := ch.QueueDeclare(
+			"",    // name, generated by the server
+			false, // durable
+			true,  // delete when unused
+			true,  // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		replyTo = q.Name
+	}
+	deliveries, err := ch.Consume(replyTo, "", true, false, false, false, nil)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	venom.Info(ctx, "Reply consumer started on '%s'.", replyTo)
+
+	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
+	if err := e.publishMessages(ctx, workdir, conn, ch, replyTo); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return e.awaitReplies(ctx, deliveries)
+}
+
+// awaitReplies returns the replies to the published messages, once MessageLimit replies are received
+func (e Executor) awaitReplies(ctx context.Context, deliveries <-chan amqp.Delivery) ([]string, []interface{}, []interface{}, []amqp.Table, error) {
+	// the replies are matched to the requests by correlation id, when set
+	correlationIDs := map[string]bool{}
+	for i := range e.Messages {
+		if e.Messages[i].CorrelationID != "" {
+			correlationIDs[e.Messages[i].CorrelationID] = true
+		}
+	}
 
 	body := []string{}
 	bodyJSON := []interface{}{}
 	messages := []interface{}{}
 	headers := []amqp.Table{}
+	timeout := time.After(time.Duration(e.Timeout) * time.Second)
+	for len(messages) < e.MessageLimit {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return nil, nil, nil, nil, errors.New("reply consumer closed")
+			}
+			if len(correlationIDs) > 0 && !correlationIDs[d.CorrelationId] {
+				venom.Info(ctx, "ignore reply with correlation id %q", d.CorrelationId)
+				continue
+			}
+			headers = append(headers, d.Headers)
+			messages = append(messages, d)
+			body, bodyJSON = e.processMessage(ctx, d, true, body, bodyJSON)
+		case <-timeout:
+			return nil, nil, nil, nil, fmt.Errorf("%d replies received after %ds, expected %d", len(messages), e.Timeout, e.MessageLimit)
+		case <-ctx.Done():
+			return nil, nil, nil, nil, ctx.Err()
+		}
+	}
+	return body, bodyJSON, messages, headers, nil
+}
 
-	for i := 0; i < e.MessageLimit; i++ {
-		venom.Debug(ctx, "Read message n° %d", i)
+// acknowledge acks, nacks or rejects the message, when the messages are not acknowledged on delivery
+func (e Executor) acknowledge(msg amqp.Delivery) error {
+	switch e.Ack {
+	case ackAck:
+		return msg.Ack(false)
+	case ackNack:
+		return msg.Nack(false, e.Requeue)
+	case ackReject:
+		return msg.Reject(e.Requeue)
+	}
+	return nil
+}
 
-		msg, ok, err := ch.Get(q.Name, true) // Read one message from RabbitMQ
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+// queueArguments returns the arguments of the declared queue
+func (e Executor) queueArguments() amqp.Table {
+	if len(e.QueueArguments) == 0 && e.DeadLetterExchange == "" && e.DeadLetterRoutingKey == "" {
+		return nil
+	}
+	args := amqpTable(e.QueueArguments)
+	if args == nil {
+		args = amqp.Table{}
+	}
+	if e.DeadLetterExchange != "" || e.DeadLetterRoutingKey != "" {
+		// the default exchange routes the dead-lettered messages to the queue named by their routing key
+		args["x-dead-letter-exchange"] = e.DeadLetterExchange
+	}
+	if e.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = e.DeadLetterRoutingKey
+	}
+	return args
+}
 
-		headers = append(headers, msg.Headers)
-		messages = append(messages, msg)
-		body, bodyJSON = e.processMessage(ctx, msg, ok, body, bodyJSON)
+// amqpTable converts the values decoded from the YAML to the types supported by the AMQP tables,
+// the numbers are sent as integers when they have no fractional part
+func amqpTable(m map[string]interface{}) amqp.Table {
+	if m == nil {
+		return nil
+	}
+	table := make(amqp.Table, len(m))
+	for k, v := range m {
+		table[k] = amqpValue(v)
 	}
+	return table
+}
 
-	return body, bodyJSON, messages, headers, err
+func amqpValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case uint:
+		return int64(t)
+	case uint32:
+		return int64(t)
+	case int8:
+		return int16(t)
+	case uint16:
+		return int32(t)
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		return amqpTable(t)
+	case amqp.Table:
+		return amqpTable(t)
+	case []interface{}:
+		values := make([]interface{}, len(t))
+		for i := range t {
+			values[i] = amqpValue(t[i])
+		}
+		return values
+	default:
+		return v
+	}
 }
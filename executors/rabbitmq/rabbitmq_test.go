@@ -0,0 +1,179 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/background"
+)
+
+func TestExecutor_QueueArguments(t *testing.T) {
+	var e Executor
+	require.NoError(t, mapstructure.Decode(venom.TestStep{
+		"qName":              "orders",
+		"deadLetterExchange": "orders.dlx",
+		"queueArguments": map[string]interface{}{
+			"x-message-ttl":  float64(60000),
+			"x-max-priority": uint(9),
+			"x-ratio":        0.5,
+			"x-nested":       map[string]interface{}{"a": []interface{}{float64(1), "b"}},
+		},
+	}, &e))
+
+	args := e.queueArguments()
+	assert.Equal(t, amqp.Table{
+		"x-message-ttl":          int64(60000),
+		"x-max-priority":         int64(9),
+		"x-ratio":                0.5,
+		"x-nested":               amqp.Table{"a": []interface{}{int64(1), "b"}},
+		"x-dead-letter-exchange": "orders.dlx",
+	}, args)
+	require.NoError(t, args.Validate())
+
+	assert.Nil(t, Executor{QName: "orders"}.queueArguments())
+}
+
+func TestExecutor_Run_InvalidAck(t *testing.T) {
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"addrs": "amqp://localhost:5672", "clientType": "subscriber", "ack": "drop"})
+	require.EqualError(t, err, `ack "drop" must be ack, nack or reject`)
+}
+
+func TestExecutor_QueueArguments_DefaultDeadLetterExchange(t *testing.T) {
+	e := Executor{QName: "orders", DeadLetterRoutingKey: "orders.dlq"}
+	assert.Equal(t, amqp.Table{"x-dead-letter-exchange": "", "x-dead-letter-routing-key": "orders.dlq"}, e.queueArguments())
+}
+
+func TestAmqpTable(t *testing.T) {
+	assert.Nil(t, amqpTable(nil))
+	assert.Equal(t, amqp.Table{
+		"uint":     int64(7),
+		"uint32":   int64(8),
+		"int8":     int16(-1),
+		"uint16":   int32(2),
+		"integer":  int64(3),
+		"float":    2.5,
+		"big":      float64(1 << 60),
+		"number":   int64(42),
+		"decimal":  0.25,
+		"string":   "venom",
+		"table":    amqp.Table{"nested": int64(1)},
+		"list":     []interface{}{int64(1), 1.5, amqp.Table{"a": "b"}},
+		"existing": amqp.Table{"n": int64(4)},
+	}, amqpTable(map[string]interface{}{
+		"uint":     uint(7),
+		"uint32":   uint32(8),
+		"int8":     int8(-1),
+		"uint16":   uint16(2),
+		"integer":  float64(3),
+		"float":    2.5,
+		"big":      float64(1 << 60),
+		"number":   json.Number("42"),
+		"decimal":  json.Number("0.25"),
+		"string":   "venom",
+		"table":    map[string]interface{}{"nested": float64(1)},
+		"list":     []interface{}{float64(1), 1.5, map[string]interface{}{"a": "b"}},
+		"existing": amqp.Table{"n": float64(4)},
+	}))
+}
+
+func TestMessage_Publishing(t *testing.T) {
+	var e Executor
+	require.NoError(t, mapstructure.Decode(venom.TestStep{
+		"messages": []interface{}{
+			map[string]interface{}{
+				"value":           `{"id":1}`,
+				"headers":         map[string]interface{}{"x-retries": float64(2)},
+				"persistent":      true,
+				"contentType":     "application/json",
+				"contentEncoding": "gzip",
+				"replyTo":         "orders.replies",
+				"correlationId":   "order-1",
+				"messageId":       "message-1",
+				"type":            "order.created",
+				"priority":        5,
+				"expiration":      "60000",
+			},
+			map[string]interface{}{"value": "ping"},
+		},
+	}, &e))
+
+	assert.Equal(t, amqp.Publishing{
+		Headers:         amqp.Table{"x-retries": int64(2)},
+		ContentType:     "application/json",
+		ContentEncoding: "gzip",
+		DeliveryMode:    amqp.Persistent,
+		Priority:        5,
+		CorrelationId:   "order-1",
+		ReplyTo:         "orders.replies",
+		Expiration:      "60000",
+		MessageId:       "message-1",
+		Type:            "order.created",
+		Body:            []byte(`{"id":1}`),
+	}, e.Messages[0].publishing(""))
+
+	// the reply queue of a client step replaces the one of the message
+	assert.Equal(t, "amq.rabbitmq.reply-to", e.Messages[0].publishing("amq.rabbitmq.reply-to").ReplyTo)
+	assert.Equal(t, amqp.Publishing{DeliveryMode: amqp.Transient, Body: []byte("ping")}, e.Messages[1].publishing(""))
+}
+
+func TestExecutor_AwaitReplies(t *testing.T) {
+	venom.InitTestLogger(t)
+	e := Executor{
+		Messages:     []Message{{Value: "a", CorrelationID: "order-1"}, {Value: "b", CorrelationID: "order-2"}},
+		MessageLimit: 2,
+		Timeout:      1,
+	}
+	deliveries := make(chan amqp.Delivery, 3)
+	deliveries <- amqp.Delivery{CorrelationId: "order-2", Body: []byte(`{"id":2}`)}
+	deliveries <- amqp.Delivery{CorrelationId: "other", Body: []byte(`{"id":3}`)}
+	deliveries <- amqp.Delivery{CorrelationId: "order-1", Body: []byte(`{"id":1}`)}
+
+	// the replies to other requests are ignored
+	body, bodyJSON, messages, _, err := e.awaitReplies(context.Background(), deliveries)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":2}`, `{"id":1}`}, body)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, bodyJSON[1])
+	assert.Len(t, messages, 2)
+
+	deliveries <- amqp.Delivery{CorrelationId: "other"}
+	deliveries <- amqp.Delivery{CorrelationId: "order-1"}
+	_, _, _, _, err = e.awaitReplies(context.Background(), deliveries)
+	assert.EqualError(t, err, "1 replies received after 1s, expected 2")
+
+	// without correlation id, all the replies are returned
+	e.Messages = []Message{{Value: "a"}}
+	deliveries <- amqp.Delivery{CorrelationId: "other", Body: []byte("x")}
+	deliveries <- amqp.Delivery{Body: []byte("y")}
+	body, _, _, _, err = e.awaitReplies(context.Background(), deliveries)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, body)
+}
+
+func TestExecutor_Run_CollectTimeout(t *testing.T) {
+	venom.InitTestLogger(t)
+	ctx, err := Executor{}.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+	defer Executor{}.TearDown(ctx) //nolint
+
+	consumer := background.NewConsumer[amqp.Delivery](func() {})
+	require.NoError(t, getConsumers(ctx).Add("orders", consumer))
+	consumer.Push(amqp.Delivery{Body: []byte(`{"id":1}`)})
+
+	// the messages received before the timeout are returned with the error
+	start := time.Now()
+	res, err := Executor{}.Run(ctx, venom.TestStep{"clientType": "collect", "background": "orders", "messageLimit": 2, "timeout": 1})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	result := res.(Result)
+	assert.Equal(t, "1 messages received after 1s, expected 2", result.Err)
+	assert.Equal(t, []string{`{"id":1}`}, result.Body)
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": json.Number("1")}}, result.BodyJSON)
+}
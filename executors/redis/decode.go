@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// StreamEntry represents an entry of a stream
+type StreamEntry struct {
+	ID     string            `json:"id" yaml:"id"`
+	Fields map[string]string `json:"fields" yaml:"fields"`
+}
+
+// decodeResponse returns the typed response of the command
+func decodeResponse(name string, args []interface{}, res interface{}) interface{} {
+	var v interface{}
+	var err error
+	switch strings.ToUpper(name) {
+	case "HGETALL":
+		v, err = decodeMap(res)
+	case "CONFIG":
+		if len(args) > 0 && strings.EqualFold(fmt.Sprint(args[0]), "GET") {
+			v, err = decodeMap(res)
+		} else {
+			v = decodeValue(res)
+		}
+	case "XINFO":
+		if len(args) > 0 && strings.EqualFold(fmt.Sprint(args[0]), "STREAM") {
+			v, err = decodeMap(res)
+		} else {
+			v, err = decodeMaps(res)
+		}
+	case "XRANGE", "XREVRANGE", "XCLAIM":
+		v, err = decodeEntries(res)
+	case "XREAD", "XREADGROUP":
+		v, err = decodeStreams(res)
+	default:
+		v = decodeValue(res)
+	}
+	if err != nil {
+		return decodeValue(res)
+	}
+	return v
+}
+
+// decodeValue returns the reply with strings instead of bulk strings and errors
+func decodeValue(res interface{}) interface{} {
+	switch v := res.(type) {
+	case []byte:
+		return string(v)
+	case redis.Error:
+		return v.Error()
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = decodeValue(v[i])
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// decodeMap returns the map of a field-value reply
+func decodeMap(res interface{}) (map[string]interface{}, error) {
+	values, err := redis.Values(res, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("odd number of values: %d", len(values))
+	}
+	m := make(map[string]interface{}, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		k, err := redis.String(values[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = decodeValue(values[i+1])
+	}
+	return m, nil
+}
+
+// decodeMaps returns the maps of an array of field-value replies
+func decodeMaps(res interface{}) ([]map[string]interface{}, error) {
+	values, err := redis.Values(res, nil)
+	if err != nil {
+		return nil, err
+	}
+	maps := make([]map[string]interface{}, len(values))
+	for i := range values {
+		if maps[i], err = decodeMap(values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return maps, nil
+}
+
+// decodeEntries returns the entries of a stream range reply
+func decodeEntries(res interface{}) ([]StreamEntry, error) {
+	values, err := redis.Values(res, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StreamEntry, 0, len(values))
+	for _, value := range values {
+		entry, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry) != 2 {
+			return nil, fmt.Errorf("unexpected stream entry of %d values", len(entry))
+		}
+		id, err := redis.String(entry[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		// the fields of the pending entries deleted from the stream are nil
+		var fields map[string]string
+		if entry[1] != nil {
+			if fields, err = redis.StringMap(entry[1], nil); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, StreamEntry{ID: id, Fields: fields})
+	}
+	return entries, nil
+}
+
+// decodeStreams returns the entries of a stream read reply, by stream
+func decodeStreams(res interface{}) (map[string][]StreamEntry, error) {
+	// no entry was read before the timeout
+	if res == nil {
+		return map[string][]StreamEntry{}, nil
+	}
+	values, err := redis.Values(res, nil)
+	if err != nil {
+		return nil, err
+	}
+	streams := make(map[string][]StreamEntry, len(values))
+	for _, value := range values {
+		stream, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(stream) != 2 {
+			return nil, fmt.Errorf("unexpected stream of %d values", len(stream))
+		}
+		name, err := redis.String(stream[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if streams[name], err = decodeEntries(stream[1]); err != nil {
+			return nil, err
+		}
+	}
+	return streams, nil
+}
+
+// acknowledge acknowledges the entries read by a XREADGROUP command with the given arguments
+func acknowledge(conn redis.Conn, args []interface{}, res interface{}) error {
+	var group string
+	for i := 0; i < len(args)-1; i++ {
+		if strings.EqualFold(fmt.Sprint(args[i]), "GROUP") {
+			group = fmt.Sprint(args[i+1])
+			break
+		}
+	}
+	if group == "" {
+		return fmt.Errorf("unable to acknowledge the entries: missing group in XREADGROUP %v", args)
+	}
+	streams, err := decodeStreams(res)
+	if err != nil {
+		return fmt.Errorf("unable to acknowledge the entries: %w", err)
+	}
+	for stream, entries := range streams {
+		if len(entries) == 0 {
+			continue
+		}
+		ackArgs := redis.Args{stream, group}
+		for _, entry := range entries {
+			ackArgs = append(ackArgs, entry.ID)
+		}
+		if _, err := conn.Do("XACK", ackArgs...); err != nil {
+			return fmt.Errorf("unable to acknowledge the entries of stream %s: %w", stream, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/ovh/venom"
+)
+
+// subscription is a connection subscribed to the channels and patterns of the step
+type subscription struct {
+	conn redis.PubSubConn
+}
+
+// subscribe opens a dedicated connection and waits for the confirmation of its subscriptions
+func (e Executor) subscribe(ctx context.Context) (*subscription, error) {
+	conn, err := e.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &subscription{conn: redis.PubSubConn{Conn: conn}}
+
+	if len(e.Subscribe) > 0 {
+		if err := s.conn.Subscribe(redis.Args{}.AddFlat(e.Subscribe)...); err != nil {
+			s.close()
+			return nil, fmt.Errorf("unable to subscribe to %v: %w", e.Subscribe, err)
+		}
+	}
+	if len(e.PSubscribe) > 0 {
+		if err := s.conn.PSubscribe(redis.Args{}.AddFlat(e.PSubscribe)...); err != nil {
+			s.close()
+			return nil, fmt.Errorf("unable to subscribe to %v: %w", e.PSubscribe, err)
+		}
+	}
+	for confirmed := 0; confirmed < len(e.Subscribe)+len(e.PSubscribe); {
+		switch v := s.conn.ReceiveWithTimeout(time.Duration(e.Timeout) * time.Millisecond).(type) {
+		case redis.Subscription:
+			venom.Debug(ctx, "%s %s", v.Kind, v.Channel)
+			confirmed++
+		case error:
+			s.close()
+			return nil, fmt.Errorf("unable to subscribe: %w", v)
+		}
+	}
+	return s, nil
+}
+
+// receive reads messages until limit messages are received or until the timeout
+func (s *subscription) receive(ctx context.Context, limit int, timeout time.Duration) ([]Message, error) {
+	deadline := time.Now().Add(timeout)
+	messages := []Message{}
+	for limit == 0 || len(messages) < limit {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		switch v := s.conn.ReceiveWithTimeout(wait).(type) {
+		case redis.Message:
+			venom.Debug(ctx, "message received on %s", v.Channel)
+			messages = append(messages, Message{Channel: v.Channel, Pattern: v.Pattern, Payload: string(v.Data)})
+		case error:
+			if err, ok := v.(net.Error); ok && err.Timeout() {
+				venom.Debug(ctx, "%d messages received before the timeout", len(messages))
+				return messages, nil
+			}
+			return nil, fmt.Errorf("unable to read messages: %w", v)
+		}
+	}
+	return messages, nil
+}
+
+func (s *subscription) close() {
+	_ = s.conn.Close()
+}
@@ -3,10 +3,13 @@ package redis
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	shellwords "github.com/mattn/go-shellwords"
@@ -14,11 +17,15 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/jsondecode"
+	"github.com/ovh/venom/executors/internal/tlsconfig"
 )
 
 // Name of executor
 const Name = "redis"
 
+const defaultTimeoutMs = 5000
+
 // New returns a new Executor
 func New() venom.Executor {
 	return &Executor{}
@@ -29,6 +36,28 @@ type Executor struct {
 	DialURL  string   `json:"dialURL,omitempty" yaml:"dialURL,omitempty" mapstructure:"dialURL"`
 	Commands []string `json:"commands,omitempty" yaml:"commands,omitempty"`
 	FilePath string   `json:"path,omitempty" yaml:"path,omitempty" mapstructure:"path"`
+
+	// Username and Password of the ACL authentication, if not set in DialURL
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+
+	// TLS options, used with the rediss scheme of DialURL.
+	// The certificates and key are PEM contents, or paths to PEM files
+	TLSClientCert   string `json:"tls_client_cert,omitempty" yaml:"tls_client_cert,omitempty" mapstructure:"tls_client_cert"`
+	TLSClientKey    string `json:"tls_client_key,omitempty" yaml:"tls_client_key,omitempty" mapstructure:"tls_client_key"`
+	TLSRootCA       string `json:"tls_root_ca,omitempty" yaml:"tls_root_ca,omitempty" mapstructure:"tls_root_ca"`
+	IgnoreVerifySSL bool   `json:"ignore_verify_ssl,omitempty" yaml:"ignore_verify_ssl,omitempty" mapstructure:"ignore_verify_ssl"`
+
+	// Subscribe and PSubscribe are the channels and patterns subscribed to before running the commands
+	Subscribe  []string `json:"subscribe,omitempty" yaml:"subscribe,omitempty"`
+	PSubscribe []string `json:"psubscribe,omitempty" yaml:"psubscribe,omitempty"`
+	// MessageLimit is the number of messages the subscriptions wait for. Without limit, they read messages until the timeout
+	MessageLimit int `json:"messageLimit,omitempty" yaml:"messageLimit,omitempty"`
+	// Timeout to read the messages. In Milliseconds. Default 5000
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Ack acknowledges the stream entries read by the XREADGROUP commands
+	Ack bool `json:"ack,omitempty" yaml:"ack,omitempty"`
 }
 
 // Command represents a redis command and the result
@@ -36,11 +65,22 @@ type Command struct {
 	Name     string        `json:"name,omitempty" yaml:"name,omitempty"`
 	Args     []interface{} `json:"args,omitempty" yaml:"args,omitempty"`
 	Response interface{}   `json:"response,omitempty" yaml:"response,omitempty"`
+	// Value is the typed response: strings, integers, arrays, and maps for the field-value replies and the stream entries
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Message represents a message received by the subscriptions
+type Message struct {
+	Channel string `json:"channel" yaml:"channel"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Payload string `json:"payload" yaml:"payload"`
 }
 
 // Result represents a step result.
 type Result struct {
-	Commands []Command `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Commands     []Command     `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Messages     []Message     `json:"messages,omitempty" yaml:"messages,omitempty"`
+	MessagesJSON []interface{} `json:"messagesjson,omitempty" yaml:"messagesJSON,omitempty"`
 }
 
 // ZeroValueResult return an empty implementation of this executor result
@@ -61,11 +101,15 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	if e.DialURL == "" {
 		return nil, fmt.Errorf("missing dialURL")
 	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeoutMs
+	}
 
-	redisClient, err := redis.DialURL(e.DialURL)
+	redisClient, err := e.dial(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer redisClient.Close()
 
 	workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
 
@@ -78,6 +122,17 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 	} else {
 		commands = e.Commands
 	}
+
+	// the subscriptions are registered before the commands publish the messages
+	var sub *subscription
+	if len(e.Subscribe) > 0 || len(e.PSubscribe) > 0 {
+		sub, err = e.subscribe(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer sub.close()
+	}
+
 	result := Result{Commands: []Command{}}
 
 	for i := range commands {
@@ -100,17 +155,68 @@ func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, erro
 			Name:     name,
 			Args:     args,
 			Response: r,
+			Value:    decodeResponse(name, args, res),
 		})
 
+		if e.Ack && strings.EqualFold(name, "XREADGROUP") {
+			if err := acknowledge(redisClient, args, res); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if sub != nil {
+		result.Messages, err = sub.receive(ctx, e.MessageLimit, time.Duration(e.Timeout)*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		result.MessagesJSON = make([]interface{}, 0, len(result.Messages))
+		for _, m := range result.Messages {
+			result.MessagesJSON = append(result.MessagesJSON, jsondecode.Message(ctx, []byte(m.Payload)))
+		}
 	}
 	return result, nil
 }
 
+// dial opens a connection to the server, with the credentials and the TLS options of the step
+func (e Executor) dial(ctx context.Context) (redis.Conn, error) {
+	options := []redis.DialOption{}
+	if e.Username != "" {
+		options = append(options, redis.DialUsername(e.Username))
+	}
+	if e.Password != "" {
+		options = append(options, redis.DialPassword(e.Password))
+	}
+	tlsConfig, err := e.tlsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		options = append(options, redis.DialTLSConfig(tlsConfig))
+	}
+	return redis.DialURLContext(ctx, e.DialURL, options...)
+}
+
+// tlsConfig returns the TLS configuration of the step, or nil without TLS options
+func (e Executor) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	return tlsconfig.New(ctx, tlsconfig.Options{
+		RootCA:          e.TLSRootCA,
+		ClientCert:      e.TLSClientCert,
+		ClientKey:       e.TLSClientKey,
+		IgnoreVerifySSL: e.IgnoreVerifySSL,
+	})
+}
+
 func getCommandDetails(command string) (name string, arg []interface{}, err error) {
-	cmd, err := shellwords.Parse(command)
+	parser := shellwords.NewParser()
+	cmd, err := parser.Parse(command)
 	if err != nil {
 		return "", nil, err
 	}
+	// the parser stops at the shell operators, such as the > ID of XREADGROUP
+	if parser.Position >= 0 {
+		return "", nil, fmt.Errorf("unable to parse command %q: %q must be quoted", command, command[parser.Position:parser.Position+1])
+	}
 
 	name = cmd[0]
 	arguments := append(cmd[:0], cmd[1:]...)
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func run(t *testing.T, step venom.TestStep) Result {
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	return res.(Result)
+}
+
+func TestExecutor_Run_Values(t *testing.T) {
+	venom.InitTestLogger(t)
+	s := miniredis.RunT(t)
+
+	res := run(t, venom.TestStep{
+		"dialURL": "redis://" + s.Addr(),
+		"commands": []string{
+			"SET foo bar",
+			"INCR counter",
+			"GET missing",
+			"HSET user:1 name venom age 12",
+			"HGETALL user:1",
+		},
+	})
+	require.Len(t, res.Commands, 5)
+	// the responses keep their untyped values
+	assert.Equal(t, "OK", res.Commands[0].Response)
+	assert.Equal(t, []interface{}{"age", "12", "name", "venom"}, res.Commands[4].Response)
+
+	assert.Equal(t, "OK", res.Commands[0].Value)
+	assert.Equal(t, int64(1), res.Commands[1].Value)
+	assert.Nil(t, res.Commands[2].Value)
+	assert.Equal(t, int64(2), res.Commands[3].Value)
+	assert.Equal(t, map[string]interface{}{"name": "venom", "age": "12"}, res.Commands[4].Value)
+}
+
+func TestExecutor_Run_Streams(t *testing.T) {
+	venom.InitTestLogger(t)
+	s := miniredis.RunT(t)
+	dialURL := "redis://" + s.Addr()
+
+	run(t, venom.TestStep{
+		"dialURL": dialURL,
+		"commands": []string{
+			"XADD orders 1-0 id 1 status created",
+			"XADD orders 2-0 id 1 status paid",
+			"XGROUP CREATE orders billing 0",
+		},
+	})
+
+	res := run(t, venom.TestStep{
+		"dialURL":  dialURL,
+		"commands": []string{"XRANGE orders - +", "XREAD COUNT 1 STREAMS orders 0"},
+	})
+	assert.Equal(t, []StreamEntry{
+		{ID: "1-0", Fields: map[string]string{"id": "1", "status": "created"}},
+		{ID: "2-0", Fields: map[string]string{"id": "1", "status": "paid"}},
+	}, res.Commands[0].Value)
+	assert.Equal(t, map[string][]StreamEntry{
+		"orders": {{ID: "1-0", Fields: map[string]string{"id": "1", "status": "created"}}},
+	}, res.Commands[1].Value)
+
+	// the entries read by the group are acknowledged
+	res = run(t, venom.TestStep{
+		"dialURL":  dialURL,
+		"ack":      true,
+		"commands": []string{"XREADGROUP GROUP billing venom COUNT 2 STREAMS orders '>'", "XPENDING orders billing"},
+	})
+	assert.Len(t, res.Commands[0].Value.(map[string][]StreamEntry)["orders"], 2)
+	assert.Equal(t, int64(0), res.Commands[1].Value.([]interface{})[0])
+
+	res = run(t, venom.TestStep{
+		"dialURL":  dialURL,
+		"commands": []string{"XREADGROUP GROUP billing venom COUNT 2 STREAMS orders '>'"},
+	})
+	assert.Equal(t, map[string][]StreamEntry{}, res.Commands[0].Value)
+}
+
+func TestExecutor_Run_Subscribe(t *testing.T) {
+	venom.InitTestLogger(t)
+	s := miniredis.RunT(t)
+
+	res := run(t, venom.TestStep{
+		"dialURL":      "redis://" + s.Addr(),
+		"subscribe":    []string{"invalidations"},
+		"psubscribe":   []string{"orders.*"},
+		"messageLimit": 2,
+		"commands": []string{
+			`PUBLISH invalidations '{"key": "user:1"}'`,
+			"PUBLISH orders.created 1",
+			"PUBLISH orders.paid 1",
+		},
+	})
+	require.Len(t, res.Messages, 2)
+	assert.Equal(t, Message{Channel: "invalidations", Payload: `{"key": "user:1"}`}, res.Messages[0])
+	assert.Equal(t, map[string]interface{}{"key": "user:1"}, res.MessagesJSON[0])
+	assert.Equal(t, Message{Channel: "orders.created", Pattern: "orders.*", Payload: "1"}, res.Messages[1])
+
+	// without limit, the messages are read until the timeout
+	res = run(t, venom.TestStep{
+		"dialURL":   "redis://" + s.Addr(),
+		"subscribe": []string{"invalidations"},
+		"timeout":   100,
+		"commands":  []string{"PUBLISH invalidations 1", "PUBLISH invalidations 2"},
+	})
+	require.Len(t, res.Messages, 2)
+	assert.Equal(t, "2", res.Messages[1].Payload)
+}
+
+func TestGetCommandDetails(t *testing.T) {
+	name, args, err := getCommandDetails(`XREADGROUP GROUP billing venom STREAMS orders '>'`)
+	require.NoError(t, err)
+	assert.Equal(t, "XREADGROUP", name)
+	assert.Equal(t, []interface{}{"GROUP", "billing", "venom", "STREAMS", "orders", ">"}, args)
+
+	_, _, err = getCommandDetails("XREADGROUP GROUP billing venom STREAMS orders >")
+	require.EqualError(t, err, `unable to parse command "XREADGROUP GROUP billing venom STREAMS orders >": ">" must be quoted`)
+}
+
+func TestExecutor_Run_Auth(t *testing.T) {
+	venom.InitTestLogger(t)
+	s := miniredis.RunT(t)
+	s.RequireUserAuth("venom", "secret")
+
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"dialURL": "redis://" + s.Addr(), "commands": []string{"PING"}})
+	require.Error(t, err)
+
+	res := run(t, venom.TestStep{"dialURL": "redis://" + s.Addr(), "username": "venom", "password": "secret", "commands": []string{"PING"}})
+	assert.Equal(t, "PONG", res.Commands[0].Value)
+}
+
+func TestExecutor_Run_TLS(t *testing.T) {
+	venom.InitTestLogger(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	s := miniredis.NewMiniRedis()
+	require.NoError(t, s.StartTLS(&tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}))
+	defer s.Close()
+
+	res := run(t, venom.TestStep{"dialURL": "rediss://" + s.Addr(), "tls_root_ca": string(certPEM), "commands": []string{"PING"}})
+	assert.Equal(t, "PONG", res.Commands[0].Value)
+
+	_, err = Executor{}.Run(context.Background(), venom.TestStep{"dialURL": "rediss://" + s.Addr(), "commands": []string{"PING"}})
+	require.Error(t, err)
+	res = run(t, venom.TestStep{"dialURL": "rediss://" + s.Addr(), "ignore_verify_ssl": true, "commands": []string{"PING"}})
+	assert.Equal(t, "PONG", res.Commands[0].Value)
+}
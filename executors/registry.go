@@ -4,14 +4,21 @@ import (
 	"github.com/ovh/venom"
 	"github.com/ovh/venom/executors/amqp"
 	"github.com/ovh/venom/executors/couchbase"
+	"github.com/ovh/venom/executors/cql"
 	"github.com/ovh/venom/executors/dbfixtures"
+	"github.com/ovh/venom/executors/dbsnapshot"
+	"github.com/ovh/venom/executors/elasticsearch"
 	"github.com/ovh/venom/executors/exec"
+	"github.com/ovh/venom/executors/graphql"
 	"github.com/ovh/venom/executors/grpc"
 	"github.com/ovh/venom/executors/http"
 	"github.com/ovh/venom/executors/imap"
 	"github.com/ovh/venom/executors/kafka"
+	"github.com/ovh/venom/executors/kv"
+	"github.com/ovh/venom/executors/mockserver"
 	"github.com/ovh/venom/executors/mongo"
 	"github.com/ovh/venom/executors/mqtt"
+	"github.com/ovh/venom/executors/nats"
 	"github.com/ovh/venom/executors/ovhapi"
 	"github.com/ovh/venom/executors/rabbitmq"
 	"github.com/ovh/venom/executors/readfile"
@@ -20,28 +27,37 @@ import (
 	"github.com/ovh/venom/executors/sql"
 	"github.com/ovh/venom/executors/ssh"
 	"github.com/ovh/venom/executors/web"
+	"github.com/ovh/venom/executors/websocket"
 )
 
 type Constructor func() venom.Executor
 
 // Registry is a map of executors to executor constructor functions.
 var Registry map[string]Constructor = map[string]Constructor{
-	amqp.Name:       amqp.New,
-	dbfixtures.Name: dbfixtures.New,
-	exec.Name:       exec.New,
-	grpc.Name:       grpc.New,
-	http.Name:       http.New,
-	imap.Name:       imap.New,
-	kafka.Name:      kafka.New,
-	mqtt.Name:       mqtt.New,
-	ovhapi.Name:     ovhapi.New,
-	rabbitmq.Name:   rabbitmq.New,
-	readfile.Name:   readfile.New,
-	redis.Name:      redis.New,
-	smtp.Name:       smtp.New,
-	sql.Name:        sql.New,
-	ssh.Name:        ssh.New,
-	mongo.Name:      mongo.New,
-	web.Name:        web.New,
-	couchbase.Name:  couchbase.New,
+	amqp.Name:          amqp.New,
+	dbfixtures.Name:    dbfixtures.New,
+	dbsnapshot.Name:    dbsnapshot.New,
+	elasticsearch.Name: elasticsearch.New,
+	exec.Name:          exec.New,
+	graphql.Name:       graphql.New,
+	grpc.Name:          grpc.New,
+	http.Name:          http.New,
+	imap.Name:          imap.New,
+	kafka.Name:         kafka.New,
+	kv.Name:            kv.New,
+	mockserver.Name:    mockserver.New,
+	mqtt.Name:          mqtt.New,
+	nats.Name:          nats.New,
+	ovhapi.Name:        ovhapi.New,
+	rabbitmq.Name:      rabbitmq.New,
+	readfile.Name:      readfile.New,
+	redis.Name:         redis.New,
+	smtp.Name:          smtp.New,
+	sql.Name:           sql.New,
+	ssh.Name:           ssh.New,
+	mongo.Name:         mongo.New,
+	web.Name:           web.New,
+	websocket.Name:     websocket.New,
+	couchbase.Name:     couchbase.New,
+	cql.Name:           cql.New,
 }
@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Column represents a column of the rows returned by a SQL query
+type Column struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is the database type of the column, as reported by the driver
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// handleRows iter on each SQL rows result sets and serialize it into a []Row, with the columns of the rows.
+func handleRows(rows *sqlx.Rows) ([]Row, []Column, error) {
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make([]Column, len(types))
+	for i, t := range types {
+		columns[i] = Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+
+	res := []Row{}
+	for rows.Next() {
+		row := make(Row)
+		if err := rows.MapScan(row); err != nil {
+			return nil, nil, err
+		}
+		for _, c := range columns {
+			row[c.Name] = normalizeValue(c.Type, row[c.Name])
+		}
+		res = append(res, row)
+	}
+	if err := rows.Err(); err != nil {
+		return res, columns, err
+	}
+	return res, columns, nil
+}
+
+// normalizeValue returns the value of a column as a JSON-friendly type: the numbers are int64 or float64,
+// the decimals that are not integers are strings, the times are RFC 3339 strings, and the bytes are strings, base64 encoded if they are not valid UTF-8
+func normalizeValue(databaseType string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, bool, int64, float64, string:
+		return v
+	case []byte:
+		return normalizeBytes(databaseType, value)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= 1<<63-1 {
+			return int64(u)
+		}
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	}
+	return v
+}
+
+// normalizeBytes returns the value of a column scanned as bytes, parsed according to the database type of the column
+func normalizeBytes(databaseType string, b []byte) interface{} {
+	s := string(b)
+	// the types may have a size or a precision, e.g. DECIMAL(10,2)
+	t := strings.ToUpper(databaseType)
+	if i := strings.Index(t, "("); i >= 0 {
+		t = t[:i]
+	}
+	t = strings.TrimSpace(strings.TrimPrefix(t, "UNSIGNED "))
+	switch t {
+	case "INT", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8", "YEAR":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "DECIMAL", "NUMERIC", "NUMBER":
+		// a float64 would round the decimals, they are integers only when they fit exactly
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		return s
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	if !utf8.Valid(b) {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return s
+}
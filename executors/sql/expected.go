@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// csvNull is the value of the NULL columns in the CSV files, the YAML and JSON files use null.
+// A value starting with a backslash is escaped with another one, so \\N is the string \N.
+const csvNull = `\N`
+
+// readExpectedFile reads the expected rows of a CSV file with a header, or of a YAML or JSON array of objects
+func readExpectedFile(file string) ([]Row, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(path.Ext(file)) {
+	case ".csv":
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read CSV file %q", file)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("CSV file %q has no header", file)
+		}
+		rows := make([]Row, 0, len(records)-1)
+		for _, record := range records[1:] {
+			row := make(Row, len(record))
+			for i, column := range records[0] {
+				row[column] = csvValue(record[i])
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case ".yml", ".yaml", ".json":
+		rows := []Row{}
+		if err := yaml.NewDecoder(f).Decode(&rows); err != nil {
+			return nil, errors.Wrapf(err, "failed to read file %q", file)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("expected file %q must be a CSV, YAML or JSON file", file)
+}
+
+// csvValue returns the value of a CSV field: nil for NULL, or the field without its escaping backslash
+func csvValue(field string) interface{} {
+	if field == csvNull {
+		return nil
+	}
+	if strings.HasPrefix(field, `\\`) {
+		return field[1:]
+	}
+	return field
+}
+
+// compareRows matches the expected rows with the rows, in any order, on the columns of the expected rows.
+// An expected row matching several rows is paired so that as many expected rows as possible are matched.
+// It returns the description of the missing and unexpected rows, or an empty string if the rows match
+func compareRows(expected []Row, r QueryResult) (string, error) {
+	returned := make(map[string]bool, len(r.Columns))
+	for _, c := range r.Columns {
+		returned[c.Name] = true
+	}
+	for _, row := range expected {
+		for column := range row {
+			if !returned[column] {
+				return "", fmt.Errorf("column %q of the expected rows is not returned by the query", column)
+			}
+		}
+	}
+
+	// maximum bipartite matching between the expected rows and the rows, with augmenting paths
+	candidates := make([][]int, len(expected))
+	for i, e := range expected {
+		for j, row := range r.Rows {
+			if matchRow(e, row) {
+				candidates[i] = append(candidates[i], j)
+			}
+		}
+	}
+	matchedBy := make([]int, len(r.Rows))
+	for j := range matchedBy {
+		matchedBy[j] = -1
+	}
+	var augment func(i int, visited []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for _, j := range candidates[i] {
+			if visited[j] {
+				continue
+			}
+			visited[j] = true
+			if matchedBy[j] < 0 || augment(matchedBy[j], visited) {
+				matchedBy[j] = i
+				return true
+			}
+		}
+		return false
+	}
+
+	var missing []Row
+	for i, e := range expected {
+		if !augment(i, make([]bool, len(r.Rows))) {
+			missing = append(missing, e)
+		}
+	}
+	var unexpected []Row
+	for j, row := range r.Rows {
+		if matchedBy[j] < 0 {
+			unexpected = append(unexpected, row)
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rows returned, %d rows expected", len(r.Rows), len(expected))
+	for _, row := range missing {
+		fmt.Fprintf(&b, "\n- %s", formatRow(row))
+	}
+	for _, row := range unexpected {
+		fmt.Fprintf(&b, "\n+ %s", formatRow(row))
+	}
+	return b.String(), nil
+}
+
+// matchRow returns true if the columns of the expected row have the same values in the row,
+// a nil expected value only matches NULL
+func matchRow(expected, row Row) bool {
+	for column, v := range expected {
+		if (v == nil) != (row[column] == nil) {
+			return false
+		}
+		if v != nil && valueString(v) != valueString(row[column]) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueString returns the value as compared with the expected values
+func valueString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatRow returns the row as JSON, with sorted columns
+func formatRow(row Row) string {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Sprint(map[string]interface{}(row))
+	}
+	return string(b)
+}
@@ -2,8 +2,12 @@ package sql
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path"
+	"reflect"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -22,6 +26,7 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/ovh/venom"
+	"github.com/ovh/venom/executors/internal/dbpool"
 )
 
 // Name of the executor.
@@ -34,10 +39,28 @@ func New() venom.Executor {
 
 // Executor is a venom executor can execute SQL queries
 type Executor struct {
-	File     string   `json:"file,omitempty" yaml:"file,omitempty"`
-	Commands []string `json:"commands,omitempty" yaml:"commands,omitempty"`
-	Driver   string   `json:"driver" yaml:"driver"`
-	DSN      string   `json:"dsn" yaml:"dsn"`
+	File     string    `json:"file,omitempty" yaml:"file,omitempty"`
+	Commands []Command `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Driver   string    `json:"driver" yaml:"driver"`
+	DSN      string    `json:"dsn" yaml:"dsn"`
+	// Connection is the name of a connection declared in the sql.connections variable, used instead of Driver and DSN
+	Connection string `json:"connection,omitempty" yaml:"connection,omitempty"`
+	// Exec runs all the commands without returning rows
+	Exec bool `json:"exec,omitempty" yaml:"exec,omitempty"`
+	// Transaction runs the commands in a transaction, rolled back at the end of the step unless Commit is set
+	Transaction bool `json:"transaction,omitempty" yaml:"transaction,omitempty"`
+	Commit      bool `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// ExpectedRows, or the rows of the CSV, YAML or JSON ExpectedFile, are compared with the rows of the last command, in any order
+	ExpectedRows []Row  `json:"expected_rows,omitempty" yaml:"expected_rows,omitempty" mapstructure:"expected_rows"`
+	ExpectedFile string `json:"expected_file,omitempty" yaml:"expected_file,omitempty" mapstructure:"expected_file"`
+}
+
+// Command is a SQL query with its bind parameters. A command can be set as a string, without parameters
+type Command struct {
+	Query string        `json:"query" yaml:"query"`
+	Args  []interface{} `json:"args,omitempty" yaml:"args,omitempty"`
+	// Exec runs the query without returning rows, the result reports the affected rows and the last inserted ID
+	Exec bool `json:"exec,omitempty" yaml:"exec,omitempty"`
 }
 
 // Rows represents an array of Row
@@ -48,7 +71,11 @@ type Row map[string]interface{}
 
 // QueryResult represents a rows return by a SQL query execution.
 type QueryResult struct {
-	Rows Rows `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Rows    Rows     `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Columns []Column `json:"columns,omitempty" yaml:"columns,omitempty"`
+	// RowsAffected and LastInsertID are set by the exec commands, if the driver supports them
+	RowsAffected *int64 `json:"rows_affected,omitempty" yaml:"rows_affected,omitempty"`
+	LastInsertID *int64 `json:"last_insert_id,omitempty" yaml:"last_insert_id,omitempty"`
 }
 
 // Result represents a step result.
@@ -59,32 +86,49 @@ type Result struct {
 // Run implements the venom.Executor interface for Executor.
 func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
 	// Transform step to Executor instance.
-	if err := mapstructure.Decode(step, &e); err != nil {
+	if err := decodeStep(step, &e); err != nil {
 		return nil, err
 	}
-	// Connect to the database and ping it.
-	venom.Debug(ctx, "connecting to database %s, %s\n", e.Driver, e.DSN)
-	db, err := sqlx.Connect(e.Driver, e.DSN)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to connect to database")
+	var db *sqlx.DB
+	if e.Connection != "" {
+		var err error
+		if db, _, err = dbpool.Get(ctx, e.Connection); err != nil {
+			return nil, err
+		}
+		defer dbpool.LogStats(ctx, e.Connection, db)
+	} else {
+		// Connect to the database and ping it.
+		venom.Debug(ctx, "connecting to database %s, %s\n", e.Driver, e.DSN)
+		var err error
+		db, err = sqlx.Connect(e.Driver, e.DSN)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to database")
+		}
+		defer db.Close()
+	}
+
+	var ext sqlx.ExtContext = db
+	if e.Transaction {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to begin transaction")
+		}
+		// a committed transaction is not rolled back
+		defer func() { _ = tx.Rollback() }()
+		ext = tx
 	}
-	defer db.Close()
 
 	results := []QueryResult{}
 	// Execute commands on database
 	// if the argument is specified.
 	if len(e.Commands) != 0 {
-		for i, s := range e.Commands {
+		for i, c := range e.Commands {
 			venom.Debug(ctx, "Executing command number %d\n", i)
-			rows, err := db.Queryx(s)
+			r, err := e.run(ctx, ext, c)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed to exec command number %d", i)
 			}
-			r, err := handleRows(rows)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to parse SQL rows for command number %d", i)
-			}
-			results = append(results, QueryResult{Rows: r})
+			results = append(results, r)
 		}
 	} else if e.File != "" {
 		workdir := venom.StringVarFromCtx(ctx, "venom.testsuite.workdir")
@@ -94,20 +138,131 @@ func (e Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, er
 		if errs != nil {
 			return nil, errs
 		}
-		rows, err := db.Queryx(string(sbytes))
+		r, err := e.run(ctx, ext, Command{Query: string(sbytes)})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to exec SQL file %q", file)
 		}
-		r, err := handleRows(rows)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse SQL rows for SQL file %q", file)
+		results = append(results, r)
+	}
+
+	if err := e.compare(ctx, results); err != nil {
+		return nil, err
+	}
+
+	if tx, ok := ext.(*sqlx.Tx); ok {
+		if e.Commit {
+			if err := tx.Commit(); err != nil {
+				return nil, errors.Wrapf(err, "failed to commit transaction")
+			}
+		} else {
+			venom.Debug(ctx, "rolling back transaction\n")
 		}
-		results = append(results, QueryResult{Rows: r})
 	}
 	r := Result{Queries: results}
 	return r, nil
 }
 
+// run executes the command, with its bind parameters
+func (e Executor) run(ctx context.Context, ext sqlx.ExtContext, c Command) (QueryResult, error) {
+	args := make([]interface{}, len(c.Args))
+	for i := range c.Args {
+		args[i] = bindValue(c.Args[i])
+	}
+	if !c.Exec && !e.Exec {
+		rows, err := ext.QueryxContext(ctx, c.Query, args...)
+		if err != nil {
+			return QueryResult{}, err
+		}
+		r, columns, err := handleRows(rows)
+		if err != nil {
+			return QueryResult{}, errors.Wrapf(err, "failed to parse SQL rows")
+		}
+		return QueryResult{Rows: r, Columns: columns}, nil
+	}
+
+	res, err := ext.ExecContext(ctx, c.Query, args...)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	r := QueryResult{}
+	if n, err := res.RowsAffected(); err == nil {
+		r.RowsAffected = &n
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.LastInsertID = &id
+	}
+	return r, nil
+}
+
+// compare compares the rows of the last command with the expected rows of the step, if any
+func (e Executor) compare(ctx context.Context, results []QueryResult) error {
+	expected := e.ExpectedRows
+	if e.ExpectedFile != "" {
+		file := path.Join(venom.StringVarFromCtx(ctx, "venom.testsuite.workdir"), e.ExpectedFile)
+		var err error
+		if expected, err = readExpectedFile(file); err != nil {
+			return err
+		}
+	} else if expected == nil {
+		return nil
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no rows to compare with the expected rows")
+	}
+	diff, err := compareRows(expected, results[len(results)-1])
+	if err != nil {
+		return err
+	}
+	if diff != "" {
+		return fmt.Errorf("rows do not match the expected rows: %s", diff)
+	}
+	return nil
+}
+
+// bindValue returns the bind parameter of a step value: the integral numbers are integers,
+// and the objects and arrays are JSON strings
+func bindValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int64(value)
+		}
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		if btes, err := json.Marshal(value); err == nil {
+			return string(btes)
+		}
+	}
+	return v
+}
+
+// decodeStep decodes the step, the commands can be set as strings
+func decodeStep(step venom.TestStep, e *Executor) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to == reflect.TypeOf(Command{}) {
+				return Command{Query: data.(string)}, nil
+			}
+			return data, nil
+		},
+		Result: e,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(step)
+}
+
+// Setup reads the connections declared in the sql.connections variable of the testsuite
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return dbpool.Setup(ctx, vars)
+}
+
+// TearDown logs the statistics of the connections used by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	dbpool.TearDown(ctx)
+	return nil
+}
+
 // ZeroValueResult return an empty implementation of this executor result
 func (Executor) ZeroValueResult() interface{} {
 	return Result{}
@@ -117,20 +272,3 @@ func (Executor) ZeroValueResult() interface{} {
 func (e Executor) GetDefaultAssertions() venom.StepAssertions {
 	return venom.StepAssertions{Assertions: []venom.Assertion{}}
 }
-
-// handleRows iter on each SQL rows result sets and serialize it into a []Row.
-func handleRows(rows *sqlx.Rows) ([]Row, error) {
-	defer rows.Close()
-	res := []Row{}
-	for rows.Next() {
-		row := make(Row)
-		if err := rows.MapScan(row); err != nil {
-			return nil, err
-		}
-		res = append(res, row)
-	}
-	if err := rows.Err(); err != nil {
-		return res, err
-	}
-	return res, nil
-}
@@ -0,0 +1,211 @@
+package sql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+func run(t *testing.T, step venom.TestStep) Result {
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	return res.(Result)
+}
+
+// sqlite returns the DSN of a database with an employee table
+func sqlite(t *testing.T) string {
+	dsn := filepath.Join(t.TempDir(), "venom.db")
+	run(t, venom.TestStep{
+		"driver":   "sqlite",
+		"dsn":      dsn,
+		"exec":     true,
+		"commands": []interface{}{"CREATE TABLE employee (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, age INTEGER)"},
+	})
+	return dsn
+}
+
+func TestExecutor_Run_Args(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn := sqlite(t)
+
+	res := run(t, venom.TestStep{
+		"driver": "sqlite",
+		"dsn":    dsn,
+		"commands": []interface{}{
+			map[string]interface{}{"query": "INSERT INTO employee (name, age) VALUES (?, ?)", "args": []interface{}{"O'Reilly", float64(42)}, "exec": true},
+			map[string]interface{}{"query": "INSERT INTO employee (name, age) VALUES (?, ?), (?, ?)", "args": []interface{}{"Jack", 21, "Jill", 22}, "exec": true},
+			map[string]interface{}{"query": "SELECT name, age FROM employee WHERE name = ?", "args": []interface{}{"O'Reilly"}},
+			"SELECT count(*) AS n FROM employee",
+		},
+	})
+	require.Len(t, res.Queries, 4)
+	assert.Equal(t, int64(1), *res.Queries[0].RowsAffected)
+	assert.Equal(t, int64(1), *res.Queries[0].LastInsertID)
+	assert.Equal(t, int64(2), *res.Queries[1].RowsAffected)
+	assert.Equal(t, int64(3), *res.Queries[1].LastInsertID)
+	assert.Equal(t, Rows{{"name": "O'Reilly", "age": int64(42)}}, res.Queries[2].Rows)
+	assert.Nil(t, res.Queries[2].RowsAffected)
+	assert.Equal(t, Rows{{"n": int64(3)}}, res.Queries[3].Rows)
+}
+
+func TestExecutor_Run_Transaction(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn := sqlite(t)
+
+	insert := map[string]interface{}{"query": "INSERT INTO employee (name) VALUES (?)", "args": []interface{}{"Jack"}, "exec": true}
+	count := "SELECT count(*) AS n FROM employee"
+
+	// the transaction is rolled back at the end of the step
+	res := run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "transaction": true, "commands": []interface{}{insert, count}})
+	assert.Equal(t, Rows{{"n": int64(1)}}, res.Queries[1].Rows)
+	res = run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": []interface{}{count}})
+	assert.Equal(t, Rows{{"n": int64(0)}}, res.Queries[0].Rows)
+
+	// and on failure
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{"driver": "sqlite", "dsn": dsn, "transaction": true, "commit": true, "commands": []interface{}{insert, "SELECT * FROM missing"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to exec command number 1")
+	res = run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": []interface{}{count}})
+	assert.Equal(t, Rows{{"n": int64(0)}}, res.Queries[0].Rows)
+
+	run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "transaction": true, "commit": true, "commands": []interface{}{insert}})
+	res = run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": []interface{}{count}})
+	assert.Equal(t, Rows{{"n": int64(1)}}, res.Queries[0].Rows)
+}
+
+func TestBindValue(t *testing.T) {
+	assert.Equal(t, int64(42), bindValue(float64(42)))
+	assert.Equal(t, 4.2, bindValue(4.2))
+	assert.Equal(t, `{"a":[1,"b"]}`, bindValue(map[string]interface{}{"a": []interface{}{1, "b"}}))
+	assert.Equal(t, "venom", bindValue("venom"))
+	assert.Nil(t, bindValue(nil))
+}
+
+func TestExecutor_Run_Connection(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn := sqlite(t)
+	vars := venom.H{"sql.connections": map[string]interface{}{"main": map[string]interface{}{"driver": "sqlite", "dsn": dsn}}}
+
+	ctx, err := Executor{}.Setup(context.Background(), vars)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := Executor{}.Run(ctx, venom.TestStep{"connection": "main", "commands": []interface{}{"INSERT INTO employee (name) VALUES ('Jack')"}})
+		require.NoError(t, err)
+	}
+	res, err := Executor{}.Run(ctx, venom.TestStep{"connection": "main", "commands": []interface{}{"SELECT count(*) AS n FROM employee"}})
+	require.NoError(t, err)
+	assert.Equal(t, Rows{{"n": int64(2)}}, res.(Result).Queries[0].Rows)
+	require.NoError(t, Executor{}.TearDown(ctx))
+
+	_, err = Executor{}.Run(context.Background(), venom.TestStep{"connection": "main", "commands": []interface{}{"SELECT 1"}})
+	require.EqualError(t, err, `connection "main" is not declared in the sql.connections variable`)
+}
+
+func TestExecutor_Run_Columns(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn := filepath.Join(t.TempDir(), "venom.db")
+	res := run(t, venom.TestStep{
+		"driver": "sqlite",
+		"dsn":    dsn,
+		"commands": []interface{}{
+			map[string]interface{}{"query": "CREATE TABLE product (id INTEGER, price DECIMAL(10,2), data BLOB, created DATETIME)", "exec": true},
+			map[string]interface{}{"query": "INSERT INTO product VALUES (1, 10.5, x'00ff', '2024-01-02 03:04:05')", "exec": true},
+			"SELECT * FROM product",
+		},
+	})
+	q := res.Queries[2]
+	assert.Equal(t, []Column{{Name: "id", Type: "INTEGER"}, {Name: "price", Type: "DECIMAL(10,2)"}, {Name: "data", Type: "BLOB"}, {Name: "created", Type: "DATETIME"}}, q.Columns)
+	assert.Equal(t, Rows{{"id": int64(1), "price": 10.5, "data": "AP8=", "created": "2024-01-02T03:04:05Z"}}, q.Rows)
+}
+
+func TestNormalizeValue(t *testing.T) {
+	// the values of the MySQL text protocol are scanned as bytes
+	assert.Equal(t, int64(42), normalizeValue("UNSIGNED BIGINT", []byte("42")))
+	assert.Equal(t, "10.50", normalizeValue("DECIMAL", []byte("10.50")))
+	assert.Equal(t, "12345678901234567.89", normalizeValue("NUMERIC(20,2)", []byte("12345678901234567.89")))
+	assert.Equal(t, 10.5, normalizeValue("DOUBLE", []byte("10.5")))
+	assert.Equal(t, int64(10), normalizeValue("NUMERIC", []byte("10")))
+	assert.Equal(t, "Jack", normalizeValue("VARCHAR", []byte("Jack")))
+	assert.Equal(t, "/w==", normalizeValue("BYTEA", []byte{0xff}))
+	assert.Equal(t, int64(42), normalizeValue("INT4", int32(42)))
+	assert.Equal(t, float64(0.5), normalizeValue("FLOAT4", float32(0.5)))
+	assert.Nil(t, normalizeValue("INT4", nil))
+}
+
+func TestExecutor_Run_ExpectedRows(t *testing.T) {
+	venom.InitTestLogger(t)
+	dsn := sqlite(t)
+	run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "exec": true, "commands": []interface{}{
+		"INSERT INTO employee (name, age) VALUES ('Jack', 21), ('Jill', 22), ('Joe', NULL)",
+	}})
+	query := []interface{}{"SELECT id, name, age FROM employee"}
+
+	// the rows match in any order, on the columns of the expected rows
+	run(t, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": query, "expected_rows": []interface{}{
+		map[string]interface{}{"name": "Joe", "age": nil},
+		map[string]interface{}{"name": "Jill", "age": float64(22)},
+		map[string]interface{}{"name": "Jack", "age": "21"},
+	}})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "employees.csv"), []byte("name,age\nJill,22\nJack,21\nJoe,\\N\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "employees.yml"), []byte("- {id: 1, name: Jack}\n- {id: 2, name: Jill}\n"), 0o644))
+	ctx := context.WithValue(context.Background(), venom.ContextKey("var.venom.testsuite.workdir"), dir)
+	_, err := Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": query, "expected_file": "employees.csv"})
+	require.NoError(t, err)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": query, "expected_file": "employees.yml"})
+	require.EqualError(t, err, `rows do not match the expected rows: 3 rows returned, 2 rows expected
++ {"age":null,"id":3,"name":"Joe"}`)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": query, "expected_rows": []interface{}{
+		map[string]interface{}{"name": "Jack", "age": 21},
+		map[string]interface{}{"name": "Jill", "age": 23},
+		map[string]interface{}{"name": "Joe", "age": nil},
+	}})
+	require.EqualError(t, err, `rows do not match the expected rows: 3 rows returned, 3 rows expected
+- {"age":23,"name":"Jill"}
++ {"age":22,"id":2,"name":"Jill"}`)
+
+	_, err = Executor{}.Run(ctx, venom.TestStep{"driver": "sqlite", "dsn": dsn, "commands": query, "expected_rows": []interface{}{
+		map[string]interface{}{"salary": 1},
+	}})
+	require.EqualError(t, err, `column "salary" of the expected rows is not returned by the query`)
+}
+
+func TestCompareRows(t *testing.T) {
+	result := QueryResult{
+		Columns: []Column{{Name: "name"}, {Name: "age"}},
+		Rows: Rows{
+			{"name": "Jack", "age": int64(21)},
+			{"name": "Jack", "age": int64(30)},
+			{"name": "NULL", "age": nil},
+		},
+	}
+
+	// the first expected row matches both Jack rows, the second one only the first: both are matched
+	diff, err := compareRows([]Row{{"name": "Jack"}, {"name": "Jack", "age": 21}, {"name": "NULL", "age": nil}}, result)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	// the string NULL does not match a NULL value, nor a nil value the string NULL
+	diff, err = compareRows([]Row{{"name": "Jack"}, {"name": "Jack"}, {"name": nil, "age": "NULL"}}, result)
+	require.NoError(t, err)
+	assert.Equal(t, `3 rows returned, 3 rows expected
+- {"age":"NULL","name":null}
++ {"age":null,"name":"NULL"}`, diff)
+}
+
+func TestReadExpectedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "expected.csv")
+	require.NoError(t, os.WriteFile(file, []byte("name,nickname\nJoe,\\N\nNULL,\\\\N\n"), 0o644))
+	rows, err := readExpectedFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"name": "Joe", "nickname": nil}, {"name": "NULL", "nickname": `\N`}}, rows)
+}
@@ -0,0 +1,317 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/venom"
+)
+
+var (
+	_ venom.Executor          = new(Executor)
+	_ venom.ExecutorWithSetup = new(Executor)
+)
+
+const (
+	// Name of executor
+	Name = "websocket"
+	// ContextKey is the key of the persistent connections in the testcase context
+	ContextKey = venom.ContextKey("websocketContext")
+
+	defaultTimeoutMs = 5000
+)
+
+// New returns a new Executor
+func New() venom.Executor {
+	return &Executor{}
+}
+
+// Executor represents a Test Exec
+type Executor struct {
+	URL             string            `json:"url" yaml:"url"`
+	Headers         map[string]string `json:"headers" yaml:"headers"`
+	Subprotocols    []string          `json:"subprotocols" yaml:"subprotocols"`
+	IgnoreVerifySSL bool              `json:"ignore_verify_ssl" yaml:"ignore_verify_ssl" mapstructure:"ignore_verify_ssl"`
+
+	// Connection is the name of a connection kept open across the steps of the testcase.
+	// When empty, the connection is closed at the end of the step.
+	Connection string `json:"connection" yaml:"connection"`
+	// Close closes the named connection at the end of the step
+	Close bool `json:"close" yaml:"close"`
+
+	// Messages represents the messages sent, in order, once connected
+	Messages []Message `json:"messages" yaml:"messages"`
+
+	// MessageLimit represents the number of incoming messages to read before returning
+	MessageLimit int `json:"messageLimit" yaml:"messageLimit"`
+	// Match is a regexp, reading stops on the first incoming message matching it
+	Match string `json:"match" yaml:"match"`
+	// Timeout for reading messages. In Milliseconds. Default 5000
+	Timeout int64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Message represents a message sent on the websocket.
+// Type is one of text (default), binary or json. Binary payloads are base64 encoded,
+// json payloads are marshalled before being sent as a text message.
+type Message struct {
+	Type    string      `json:"type" yaml:"type"`
+	Payload interface{} `json:"payload" yaml:"payload"`
+}
+
+// Result represents a step result
+type Result struct {
+	TimeSeconds  float64       `json:"timeseconds" yaml:"timeSeconds"`
+	Messages     []string      `json:"messages" yaml:"messages"`
+	MessagesJSON []interface{} `json:"messagesjson" yaml:"messagesJSON"`
+	Matched      bool          `json:"matched" yaml:"matched"`
+	Err          string        `json:"err" yaml:"error"`
+}
+
+type connections struct {
+	sync.Mutex
+	conns map[string]*ws.Conn
+}
+
+// ZeroValueResult returns an empty implementation of this executor result
+func (Executor) ZeroValueResult() interface{} {
+	return Result{}
+}
+
+// GetDefaultAssertions return default assertions for type websocket
+func (Executor) GetDefaultAssertions() *venom.StepAssertions {
+	return &venom.StepAssertions{Assertions: []venom.Assertion{"result.err ShouldBeEmpty"}}
+}
+
+// Setup prepares the store of the connections shared across the steps of a testcase
+func (Executor) Setup(ctx context.Context, vars venom.H) (context.Context, error) {
+	return context.WithValue(ctx, ContextKey, &connections{conns: map[string]*ws.Conn{}}), nil
+}
+
+// TearDown closes all the connections left open by the testcase
+func (Executor) TearDown(ctx context.Context) error {
+	store := getConnections(ctx)
+	if store == nil {
+		return nil
+	}
+	store.Lock()
+	defer store.Unlock()
+	for name, conn := range store.conns {
+		venom.Debug(ctx, "closing websocket connection %q", name)
+		closeConn(conn)
+		delete(store.conns, name)
+	}
+	return nil
+}
+
+func getConnections(ctx context.Context) *connections {
+	i := ctx.Value(ContextKey)
+	if i == nil {
+		return nil
+	}
+	return i.(*connections)
+}
+
+// Run execute TestStep
+func (Executor) Run(ctx context.Context, step venom.TestStep) (interface{}, error) {
+	var e Executor
+	if err := mapstructure.Decode(step, &e); err != nil {
+		return nil, err
+	}
+	if e.Timeout == 0 {
+		e.Timeout = defaultTimeoutMs
+	}
+
+	var match *regexp.Regexp
+	if e.Match != "" {
+		var err error
+		match, err = regexp.Compile(e.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid match expression %q", e.Match)
+		}
+	}
+
+	start := time.Now()
+	conn, err := e.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.Connection == "" || e.Close {
+		defer e.release(ctx, conn)
+	}
+
+	result := Result{
+		Messages:     []string{},
+		MessagesJSON: []interface{}{},
+	}
+	if err := e.sendMessages(conn); err != nil {
+		result.Err = err.Error()
+	} else if e.MessageLimit > 0 || match != nil {
+		result.Messages, result.MessagesJSON, result.Matched, err = e.readMessages(ctx, conn, match)
+		if err != nil {
+			result.Err = err.Error()
+			// a connection can't be read anymore once a read has failed
+			if e.Connection != "" && !e.Close {
+				e.release(ctx, conn)
+			}
+		}
+	}
+
+	result.TimeSeconds = time.Since(start).Seconds()
+	return result, nil
+}
+
+// connect returns the named connection if it is already open, or dials a new one
+func (e Executor) connect(ctx context.Context) (*ws.Conn, error) {
+	store := getConnections(ctx)
+	if e.Connection != "" && store != nil {
+		store.Lock()
+		defer store.Unlock()
+		if conn, ok := store.conns[e.Connection]; ok {
+			venom.Debug(ctx, "reusing websocket connection %q", e.Connection)
+			return conn, nil
+		}
+	}
+
+	if e.URL == "" {
+		return nil, errors.New("url is mandatory")
+	}
+
+	dialer := ws.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: time.Duration(e.Timeout) * time.Millisecond,
+		Subprotocols:     e.Subprotocols,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: e.IgnoreVerifySSL},
+	}
+	header := http.Header{}
+	for k, v := range e.Headers {
+		header.Set(k, v)
+	}
+
+	venom.Debug(ctx, "connecting to %s", e.URL)
+	conn, resp, err := dialer.DialContext(ctx, e.URL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, errors.Wrapf(err, "unable to connect to %s: status %d", e.URL, resp.StatusCode)
+		}
+		return nil, errors.Wrapf(err, "unable to connect to %s", e.URL)
+	}
+
+	if e.Connection != "" && store != nil {
+		store.conns[e.Connection] = conn
+	}
+	return conn, nil
+}
+
+// release closes the connection and forgets it if it was a named one
+func (e Executor) release(ctx context.Context, conn *ws.Conn) {
+	if store := getConnections(ctx); e.Connection != "" && store != nil {
+		store.Lock()
+		delete(store.conns, e.Connection)
+		store.Unlock()
+	}
+	closeConn(conn)
+}
+
+func closeConn(conn *ws.Conn) {
+	_ = conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	_ = conn.Close()
+}
+
+func (e Executor) sendMessages(conn *ws.Conn) error {
+	for i, m := range e.Messages {
+		messageType, data, err := m.encode()
+		if err != nil {
+			return errors.Wrapf(err, "unable to encode message %d", i)
+		}
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			return errors.Wrapf(err, "unable to send message %d", i)
+		}
+	}
+	return nil
+}
+
+func (m Message) encode() (int, []byte, error) {
+	switch m.Type {
+	case "", "text":
+		return ws.TextMessage, []byte(fmt.Sprintf("%v", m.Payload)), nil
+	case "binary":
+		s, ok := m.Payload.(string)
+		if !ok {
+			return 0, nil, fmt.Errorf("binary payload must be a base64 string")
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return 0, nil, err
+		}
+		return ws.BinaryMessage, data, nil
+	case "json":
+		data, err := json.Marshal(m.Payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		return ws.TextMessage, data, nil
+	default:
+		return 0, nil, fmt.Errorf("type %q must be text, binary or json", m.Type)
+	}
+}
+
+// readMessages reads incoming messages until messageLimit is reached, a message matches or the timeout expires.
+// Binary messages are returned base64 encoded.
+func (e Executor) readMessages(ctx context.Context, conn *ws.Conn, match *regexp.Regexp) ([]string, []interface{}, bool, error) {
+	messages := []string{}
+	messagesJSON := []interface{}{}
+
+	deadline := time.Now().Add(time.Duration(e.Timeout) * time.Millisecond)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return messages, messagesJSON, false, err
+	}
+	defer conn.SetReadDeadline(time.Time{}) // nolint
+
+	for e.MessageLimit <= 0 || len(messages) < e.MessageLimit {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if match != nil {
+				return messages, messagesJSON, false, fmt.Errorf("no message matching %q after reading %d message(s): %v", e.Match, len(messages), err)
+			}
+			if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+				return messages, messagesJSON, false, fmt.Errorf("timeout after reading %d message(s)", len(messages))
+			}
+			return messages, messagesJSON, false, err
+		}
+
+		msg := string(data)
+		if messageType == ws.BinaryMessage {
+			msg = base64.StdEncoding.EncodeToString(data)
+		}
+		var msgJSON interface{}
+		if err := venom.JSONUnmarshal(data, &msgJSON); err != nil {
+			msgJSON = nil
+		}
+		venom.Debug(ctx, "message received: %s", msg)
+		messages = append(messages, msg)
+		messagesJSON = append(messagesJSON, msgJSON)
+
+		if match != nil && match.MatchString(msg) {
+			return messages, messagesJSON, true, nil
+		}
+	}
+
+	if match != nil {
+		return messages, messagesJSON, false, fmt.Errorf("no message matching %q in %d message(s)", e.Match, len(messages))
+	}
+	return messages, messagesJSON, false, nil
+}
@@ -0,0 +1,154 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ovh/venom"
+)
+
+// newEchoServer starts a websocket server echoing every message it receives,
+// the first message sent being the value of the X-Greeting header.
+func newEchoServer(t *testing.T) string {
+	upgrader := ws.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if greeting := r.Header.Get("X-Greeting"); greeting != "" {
+			if err := conn.WriteMessage(ws.TextMessage, []byte(greeting)); err != nil {
+				return
+			}
+		}
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestNew(t *testing.T) {
+	executor := New()
+	require.NotNil(t, executor)
+	_, ok := executor.(*Executor)
+	assert.True(t, ok, "New() should return an *Executor")
+}
+
+func TestExecutor_Run_MissingURL(t *testing.T) {
+	venom.InitTestLogger(t)
+	_, err := Executor{}.Run(context.Background(), venom.TestStep{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "url is mandatory")
+}
+
+func TestExecutor_Run_InvalidMessageType(t *testing.T) {
+	venom.InitTestLogger(t)
+	step := venom.TestStep{
+		"url":      newEchoServer(t),
+		"messages": []interface{}{map[string]interface{}{"type": "xml", "payload": "<a/>"}},
+	}
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, `type "xml" must be text, binary or json`)
+}
+
+func TestExecutor_Run_Echo(t *testing.T) {
+	venom.InitTestLogger(t)
+	step := venom.TestStep{
+		"url":     newEchoServer(t),
+		"headers": map[string]string{"X-Greeting": "hello"},
+		"messages": []interface{}{
+			map[string]interface{}{"payload": "foo"},
+			map[string]interface{}{"type": "json", "payload": map[string]interface{}{"key": "value"}},
+			map[string]interface{}{"type": "binary", "payload": "AAEC"},
+		},
+		"messageLimit": 4,
+		"timeout":      2000,
+	}
+
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Empty(t, result.Err)
+	assert.Equal(t, []string{"hello", "foo", `{"key":"value"}`, "AAEC"}, result.Messages)
+	require.Len(t, result.MessagesJSON, 4)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, result.MessagesJSON[2])
+}
+
+func TestExecutor_Run_Match(t *testing.T) {
+	venom.InitTestLogger(t)
+	step := venom.TestStep{
+		"url": newEchoServer(t),
+		"messages": []interface{}{
+			map[string]interface{}{"payload": "foo"},
+			map[string]interface{}{"payload": "bar"},
+			map[string]interface{}{"payload": "baz"},
+		},
+		"match":   "^ba",
+		"timeout": 2000,
+	}
+
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	result := res.(Result)
+	assert.Empty(t, result.Err)
+	assert.True(t, result.Matched)
+	assert.Equal(t, []string{"foo", "bar"}, result.Messages)
+}
+
+func TestExecutor_Run_Timeout(t *testing.T) {
+	venom.InitTestLogger(t)
+	step := venom.TestStep{
+		"url":          newEchoServer(t),
+		"messageLimit": 1,
+		"timeout":      100,
+	}
+
+	res, err := Executor{}.Run(context.Background(), step)
+	require.NoError(t, err)
+	assert.Contains(t, res.(Result).Err, "timeout after reading 0 message(s)")
+}
+
+func TestExecutor_Run_PersistentConnection(t *testing.T) {
+	venom.InitTestLogger(t)
+	var e Executor
+	ctx, err := e.Setup(context.Background(), venom.H{})
+	require.NoError(t, err)
+
+	url := newEchoServer(t)
+	_, err = e.Run(ctx, venom.TestStep{
+		"url":        url,
+		"connection": "client",
+		"messages":   []interface{}{map[string]interface{}{"payload": "first"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, getConnections(ctx).conns, 1)
+
+	// the echoed message is read by the next step, on the same connection
+	res, err := e.Run(ctx, venom.TestStep{
+		"connection":   "client",
+		"messageLimit": 1,
+		"close":        true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first"}, res.(Result).Messages)
+	assert.Empty(t, getConnections(ctx).conns)
+
+	require.NoError(t, e.TearDown(ctx))
+}
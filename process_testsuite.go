@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"sync"
 	"time"
 
 	"github.com/gosimple/slug"
@@ -56,6 +57,10 @@ func (v *Venom) runTestSuite(ctx context.Context, ts *TestSuite) error {
 	Info(ctx, "Starting testsuite")
 	defer Info(ctx, "Ending testsuite")
 
+	// the resources shared by the testcases are released once the testsuite has ended
+	ctx, end := WithTestSuiteEnd(ctx)
+	defer end()
+
 	totalSteps := 0
 	for _, tc := range ts.TestCases {
 		totalSteps += len(tc.RawTestSteps)
@@ -255,3 +260,38 @@ func (v *Venom) parseTestCases(ts *TestSuite) ([]string, []string, error) {
 
 	return vars, extractsVars, nil
 }
+
+// testSuiteEnd holds the functions registered with OnTestSuiteEnd
+type testSuiteEnd struct {
+	mutex sync.Mutex
+	funcs []func()
+}
+
+func (e *testSuiteEnd) run() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for i := len(e.funcs) - 1; i >= 0; i-- {
+		e.funcs[i]()
+	}
+	e.funcs = nil
+}
+
+// WithTestSuiteEnd returns a context in which OnTestSuiteEnd registers functions, and the function calling them
+func WithTestSuiteEnd(ctx context.Context) (context.Context, func()) {
+	end := &testSuiteEnd{}
+	return context.WithValue(ctx, ContextKey("testsuite.end"), end), end.run
+}
+
+// OnTestSuiteEnd registers a function called once the testsuite running the context has ended,
+// the functions are called in the reverse order of their registration.
+// It returns false if the context does not belong to a testsuite.
+func OnTestSuiteEnd(ctx context.Context, f func()) bool {
+	end, ok := ctx.Value(ContextKey("testsuite.end")).(*testSuiteEnd)
+	if !ok {
+		return false
+	}
+	end.mutex.Lock()
+	defer end.mutex.Unlock()
+	end.funcs = append(end.funcs, f)
+	return true
+}